@@ -0,0 +1,188 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"io"
+	"userapi/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller containing all API key request handlers
+type APIKeyController struct {
+	service APIKeyService
+}
+
+// Returns new APIKeyController instance
+func NewAPIKeyController(service APIKeyService) APIKeyController {
+	return APIKeyController{
+		service: service,
+	}
+}
+
+// IssueAPIKey godoc
+//
+//	@Summary		Issue new API key
+//	@Description	This endpoint issues a new API key with the scopes in request body.
+//	@Description	The plain key is returned only on this response, only its hash is stored.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		APIKeyRequest	true	"body"
+//	@Success		201		{object}	IssuedAPIKey
+//	@Failure		401
+//	@Failure		403		{object}	APIKeyResponse
+//	@Failure		400		{object}	APIKeyResponse
+//	@Failure		502		{object}	APIKeyResponse
+//	@Router			/api-keys [post]
+func (ctr APIKeyController) IssueAPIKey(c *gin.Context) {
+	var request APIKeyRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil {
+		c.JSON(400, INVALID_API_KEY_DATA)
+		return
+	}
+
+	principal, _ := identity.GetPrincipal(c)
+	issued, err := ctr.service.IssueAPIKey(request, principal.ID)
+	if err != nil {
+		if err.Error() == API_KEY_DATA_INVALID {
+			c.JSON(400, INVALID_API_KEY_DATA)
+			return
+		}
+		c.JSON(502, API_KEY_ISSUE_FAILED)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(201, issued)
+}
+
+// ListAPIKeys godoc
+//
+//	@Summary		List API keys
+//	@Description	This endpoint returns all API keys, including revoked and expired ones, without the keys themselves.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Success		200		{object}	APIKeyList
+//	@Failure		401
+//	@Failure		403		{object}	APIKeyResponse
+//	@Failure		502		{object}	APIKeyResponse
+//	@Router			/api-keys [get]
+func (ctr APIKeyController) ListAPIKeys(c *gin.Context) {
+	list, err := ctr.service.ListAPIKeys()
+	if err != nil {
+		c.JSON(502, API_KEY_LIST_FAILED)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// GetAPIKey godoc
+//
+//	@Summary		Return API key data
+//	@Description	This endpoint returns an API key by id, without the key itself.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"API key ID"
+//	@Success		200		{object}	APIKey
+//	@Failure		401
+//	@Failure		403		{object}	APIKeyResponse
+//	@Failure		400		{object}	APIKeyResponse
+//	@Failure		404		{object}	APIKeyResponse
+//	@Failure		502		{object}	APIKeyResponse
+//	@Router			/api-keys/{id} [get]
+func (ctr APIKeyController) GetAPIKey(c *gin.Context) {
+	key, err := ctr.service.GetAPIKey(c.Param("id"))
+	if err != nil {
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, API_KEY_GET_FAILED)
+		return
+	}
+
+	c.JSON(200, key)
+}
+
+// RevokeAPIKey godoc
+//
+//	@Summary		Revoke API key
+//	@Description	This endpoint revokes an API key by id, it stops authenticating requests right away.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"API key ID"
+//	@Success		200		{object}	APIKeyResponse
+//	@Failure		401
+//	@Failure		403		{object}	APIKeyResponse
+//	@Failure		400		{object}	APIKeyResponse
+//	@Failure		404		{object}	APIKeyResponse
+//	@Failure		502		{object}	APIKeyResponse
+//	@Router			/api-keys/{id} [delete]
+func (ctr APIKeyController) RevokeAPIKey(c *gin.Context) {
+	err := ctr.service.RevokeAPIKey(c.Param("id"))
+	if err != nil {
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, API_KEY_REVOKE_FAILED)
+		return
+	}
+
+	c.JSON(200, API_KEY_REVOKED)
+}
+
+// ExpireAPIKey godoc
+//
+//	@Summary		Expire API key
+//	@Description	This endpoint sets when an API key expires, it expires right away when expiresAt is not set.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string			true	"API key ID"
+//	@Param			request	body		ExpireRequest	false	"body"
+//	@Success		200		{object}	APIKeyResponse
+//	@Failure		401
+//	@Failure		403		{object}	APIKeyResponse
+//	@Failure		400		{object}	APIKeyResponse
+//	@Failure		404		{object}	APIKeyResponse
+//	@Failure		502		{object}	APIKeyResponse
+//	@Router			/api-keys/{id}/expiration [put]
+func (ctr APIKeyController) ExpireAPIKey(c *gin.Context) {
+	var request ExpireRequest
+
+	// The body is optional, without it the key expires right away
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil && err != io.EOF {
+		c.JSON(400, INVALID_EXPIRE_DATA)
+		return
+	}
+
+	err := ctr.service.ExpireAPIKey(c.Param("id"), request.ExpiresAt)
+	if err != nil {
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, API_KEY_EXPIRE_FAILED)
+		return
+	}
+
+	c.JSON(200, API_KEY_EXPIRED)
+}
+
+// Responds invalid id and not found errors, telling if the error was handled
+func (ctr APIKeyController) notFound(c *gin.Context, err error) bool {
+	switch err.Error() {
+	case API_KEY_ID_INVALID:
+		c.JSON(400, INVALID_API_KEY_ID)
+		return true
+	case API_KEY_NOT_EXISTS:
+		c.JSON(404, API_KEY_NOT_FOUND)
+		return true
+	}
+	return false
+}
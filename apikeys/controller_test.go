@@ -0,0 +1,259 @@
+package apikeys
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestIssueAPIKey(t *testing.T) {
+
+	const keyID string = "64260e1da4c0c814bda5734a"
+	createdAt := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAPIKeyService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "issue api key success",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					IssueAPIKey(APIKeyRequest{Name: "billing", Scopes: []string{"users:read"}}, "").
+					Return(&IssuedAPIKey{
+						APIKey: APIKey{ID: keyID, Name: "billing", Prefix: "uak_abcdefgh", Scopes: []string{"users:read"}, CreatedAt: createdAt},
+						Key:    "uak_abcdefghijk",
+					}, nil)
+			},
+			inputBody:        `{"name": "billing", "scopes": ["users:read"]}`,
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: fmt.Sprintf(`{"id":"%s","name":"billing","prefix":"uak_abcdefgh","scopes":["users:read"],"createdBy":"","createdAt":"2023-04-01T10:00:00Z","key":"uak_abcdefghijk"}`, keyID),
+		},
+		{
+			name:             "invalid body",
+			setupMock:        func(service *MockAPIKeyService) {},
+			inputBody:        `{`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid API Key Data","code":"INVALID_API_KEY_DATA"}`,
+		},
+		{
+			name: "invalid api key data",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Return(nil, &apiKeyServiceError{code: API_KEY_DATA_INVALID})
+			},
+			inputBody:        `{"name": "billing", "scopes": ["users:roles"]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid API Key Data","code":"INVALID_API_KEY_DATA"}`,
+		},
+		{
+			name: "issue failed",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Return(nil, &apiKeyServiceError{code: ISSUE_API_KEY_FAILED})
+			},
+			inputBody:        `{"name": "billing", "scopes": ["users:read"]}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"API Key Issue Failed","code":"API_KEY_ISSUE_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAPIKeyService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAPIKeyController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/api-keys", controller.IssueAPIKey)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/api-keys", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+
+	const keyID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAPIKeyService)
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "revoke success",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					RevokeAPIKey(keyID).
+					Return(nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"API Key Revoked","code":"API_KEY_REVOKED"}`,
+		},
+		{
+			name: "api key not found",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					RevokeAPIKey(gomock.Any()).
+					Return(&apiKeyServiceError{code: API_KEY_NOT_EXISTS})
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"API Key Not Found","code":"API_KEY_NOT_FOUND"}`,
+		},
+		{
+			name: "revoke failed",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					RevokeAPIKey(gomock.Any()).
+					Return(&apiKeyServiceError{code: REVOKE_API_KEY_FAILED})
+			},
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"API Key Revoke Failed","code":"API_KEY_REVOKE_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAPIKeyService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAPIKeyController(svc)
+			r := gin.Default()
+			r.DELETE("/api/v1/api-keys/:id", controller.RevokeAPIKey)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/api-keys/%s", keyID), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestExpireAPIKey(t *testing.T) {
+
+	const keyID string = "64260e1da4c0c814bda5734a"
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAPIKeyService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "expire now",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					ExpireAPIKey(keyID, nil).
+					Return(nil)
+			},
+			inputBody:        ``,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"API Key Expired","code":"API_KEY_EXPIRED"}`,
+		},
+		{
+			name: "expire at time",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					ExpireAPIKey(keyID, &expiresAt).
+					Return(nil)
+			},
+			inputBody:        `{"expiresAt": "2030-01-01T00:00:00Z"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"API Key Expired","code":"API_KEY_EXPIRED"}`,
+		},
+		{
+			name:             "invalid expire data",
+			setupMock:        func(service *MockAPIKeyService) {},
+			inputBody:        `{"expiresAt": "tomorrow"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Expire Data","code":"INVALID_EXPIRE_DATA"}`,
+		},
+		{
+			name: "invalid api key id",
+			setupMock: func(service *MockAPIKeyService) {
+				service.
+					EXPECT().
+					ExpireAPIKey(gomock.Any(), gomock.Any()).
+					Return(&apiKeyServiceError{code: API_KEY_ID_INVALID})
+			},
+			inputBody:        ``,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid API Key ID","code":"INVALID_API_KEY_ID"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAPIKeyService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAPIKeyController(svc)
+			r := gin.Default()
+			r.PUT("/api/v1/api-keys/:id/expiration", controller.ExpireAPIKey)
+
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/api-keys/%s/expiration", keyID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
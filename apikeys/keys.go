@@ -0,0 +1,29 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Prefix of every issued key, telling API keys apart from other secrets
+const KEY_PREFIX string = "uak_"
+
+// Length of the key start stored in clear to recognize keys on listing
+const prefixLength int = 12
+
+// Returns a new random API key
+func generateKey() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return KEY_PREFIX + base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Returns the hash stored in place of a key
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
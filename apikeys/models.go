@@ -0,0 +1,45 @@
+package apikeys
+
+import "time"
+
+// API key as stored, only the key hash is persisted.
+// Prefix holds the first characters of the key so it can be recognized on listing.
+type APIKey struct {
+	ID        string     `json:"id" bson:"_id,omitempty"`
+	Name      string     `json:"name" bson:"name"`
+	Prefix    string     `json:"prefix" bson:"prefix"`
+	KeyHash   string     `json:"-" bson:"keyHash"`
+	Scopes    []string   `json:"scopes" bson:"scopes"`
+	CreatedBy string     `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+}
+
+// Tells if the key can still authenticate requests at the given time
+func (k APIKey) active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type ExpireRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Issued API key, the only time the plain key is returned
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyList struct {
+	APIKeys []APIKey `json:"apiKeys"`
+}
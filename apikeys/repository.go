@@ -0,0 +1,143 @@
+package apikeys
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const apiKeyCollection string = "api_keys"
+const INVALID_OBJECT_ID string = "INVALID_OBJECT_ID"
+const DOCUMENT_NOT_FOUND string = "DOCUMENT_NOT_FOUND"
+
+type APIKeyRepository interface {
+	InsertAPIKey(key APIKey) (string, error)
+	FindAPIKeyByID(ID string) (*APIKey, error)
+	FindAPIKeyByHash(keyHash string) (*APIKey, error)
+	ListAPIKeys() ([]APIKey, error)
+	RevokeAPIKey(ID string) error
+	ExpireAPIKey(ID string, expiresAt time.Time) error
+	EnsureIndexes() error
+}
+
+type apiKeyRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewAPIKeyRepository(client *mongo.Client, database string) APIKeyRepository {
+	return &apiKeyRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *apiKeyRepository) InsertAPIKey(key APIKey) (string, error) {
+	key.ID = ""
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+	result, err := coll.InsertOne(context.Background(), key)
+	if err != nil {
+		return "", err
+	}
+	var objID primitive.ObjectID = result.InsertedID.(primitive.ObjectID)
+
+	return objID.Hex(), nil
+}
+
+func (repo *apiKeyRepository) FindAPIKeyByID(ID string) (*APIKey, error) {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+	return repo.findOne(bson.M{"_id": bson.M{"$eq": objID}})
+}
+
+func (repo *apiKeyRepository) FindAPIKeyByHash(keyHash string) (*APIKey, error) {
+	return repo.findOne(bson.M{"keyHash": bson.M{"$eq": keyHash}})
+}
+
+func (repo *apiKeyRepository) findOne(filter bson.M) (*APIKey, error) {
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+
+	var key APIKey
+	err := coll.FindOne(context.Background(), filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (repo *apiKeyRepository) ListAPIKeys() ([]APIKey, error) {
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := coll.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []APIKey{}
+	if err := cursor.All(context.Background(), &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revokes the key, revoking an already revoked key keeps its first revocation time
+func (repo *apiKeyRepository) RevokeAPIKey(ID string) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+	filter := bson.M{"_id": bson.M{"$eq": objID}}
+	update := bson.A{bson.M{"$set": bson.M{"revokedAt": bson.M{"$ifNull": bson.A{"$revokedAt", time.Now().UTC()}}}}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
+
+func (repo *apiKeyRepository) ExpireAPIKey(ID string, expiresAt time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+	filter := bson.M{"_id": bson.M{"$eq": objID}}
+	update := bson.M{"$set": bson.M{"expiresAt": expiresAt.UTC()}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
+
+// Creates the unique key hash index
+func (repo *apiKeyRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(apiKeyCollection)
+	_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "keyHash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
@@ -0,0 +1,61 @@
+package apikeys
+
+type APIKeyResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var INVALID_API_KEY_DATA APIKeyResponse = APIKeyResponse{
+	Message: "Invalid API Key Data",
+	Code:    "INVALID_API_KEY_DATA",
+}
+
+var INVALID_API_KEY_ID APIKeyResponse = APIKeyResponse{
+	Message: "Invalid API Key ID",
+	Code:    "INVALID_API_KEY_ID",
+}
+
+var API_KEY_NOT_FOUND APIKeyResponse = APIKeyResponse{
+	Message: "API Key Not Found",
+	Code:    "API_KEY_NOT_FOUND",
+}
+
+var API_KEY_ISSUE_FAILED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Issue Failed",
+	Code:    "API_KEY_ISSUE_FAILED",
+}
+
+var API_KEY_LIST_FAILED APIKeyResponse = APIKeyResponse{
+	Message: "API Key List Failed",
+	Code:    "API_KEY_LIST_FAILED",
+}
+
+var API_KEY_GET_FAILED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Get Failed",
+	Code:    "API_KEY_GET_FAILED",
+}
+
+var API_KEY_REVOKED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Revoked",
+	Code:    "API_KEY_REVOKED",
+}
+
+var API_KEY_REVOKE_FAILED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Revoke Failed",
+	Code:    "API_KEY_REVOKE_FAILED",
+}
+
+var INVALID_EXPIRE_DATA APIKeyResponse = APIKeyResponse{
+	Message: "Invalid Expire Data",
+	Code:    "INVALID_EXPIRE_DATA",
+}
+
+var API_KEY_EXPIRED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Expired",
+	Code:    "API_KEY_EXPIRED",
+}
+
+var API_KEY_EXPIRE_FAILED APIKeyResponse = APIKeyResponse{
+	Message: "API Key Expire Failed",
+	Code:    "API_KEY_EXPIRE_FAILED",
+}
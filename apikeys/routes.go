@@ -0,0 +1,31 @@
+// API keys module containing Controllers, Services e Repositories.
+// Module responsable for API keys of service-to-service callers
+package apikeys
+
+import (
+	"fmt"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Method to add routes in api (gin.RouterGroup), using config (config.Config)
+// and client (mongo.Client)
+func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client) {
+	var apiKeyRepository APIKeyRepository = NewAPIKeyRepository(client, config.Database)
+	var apiKeyService APIKeyService = NewAPIKeyService(apiKeyRepository)
+	var apiKeyController APIKeyController = NewAPIKeyController(apiKeyService)
+
+	if err := apiKeyRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+
+	manage := users.Authorize(users.PERMISSION_API_KEYS, false)
+	api.POST("/api-keys", manage, apiKeyController.IssueAPIKey)
+	api.GET("/api-keys", manage, apiKeyController.ListAPIKeys)
+	api.GET("/api-keys/:id", manage, apiKeyController.GetAPIKey)
+	api.DELETE("/api-keys/:id", manage, apiKeyController.RevokeAPIKey)
+	api.PUT("/api-keys/:id/expiration", manage, apiKeyController.ExpireAPIKey)
+}
@@ -0,0 +1,190 @@
+package apikeys
+
+import (
+	"fmt"
+	"time"
+	"userapi/users"
+)
+
+// Scopes an API key may be issued with
+var Scopes = []string{users.PERMISSION_READ, users.PERMISSION_WRITE, users.PERMISSION_DELETE}
+
+type APIKeyService interface {
+	/*
+		Method to issue a new API key, returning the plain key only once
+
+		Parameters
+
+		request: API key name, scopes and optional expiration.
+
+		createdBy: ID of the principal issuing the key.
+	*/
+	IssueAPIKey(request APIKeyRequest, createdBy string) (*IssuedAPIKey, error)
+	/*
+		Method to list all API keys, including revoked and expired ones
+	*/
+	ListAPIKeys() (*APIKeyList, error)
+	/*
+		Method to get API key by ID
+
+		Parameters
+
+		ID: API key ID.
+	*/
+	GetAPIKey(ID string) (*APIKey, error)
+	/*
+		Method to revoke API key, it can not authenticate requests anymore
+
+		Parameters
+
+		ID: API key ID.
+	*/
+	RevokeAPIKey(ID string) error
+	/*
+		Method to set API key expiration
+
+		Parameters
+
+		ID: API key ID.
+
+		expiresAt: Expiration time, the key expires right away when nil.
+	*/
+	ExpireAPIKey(ID string, expiresAt *time.Time) error
+	/*
+		Method to find the active API key matching the plain key
+
+		Parameters
+
+		key: Plain API key sent by the caller.
+	*/
+	Authenticate(key string) (*APIKey, error)
+}
+
+type apiKeyServiceError struct {
+	code string
+}
+
+func (e *apiKeyServiceError) Error() string {
+	return e.code
+}
+
+const ISSUE_API_KEY_FAILED string = "ISSUE_API_KEY_FAILED"
+const LIST_API_KEYS_FAILED string = "LIST_API_KEYS_FAILED"
+const GET_API_KEY_FAILED string = "GET_API_KEY_FAILED"
+const REVOKE_API_KEY_FAILED string = "REVOKE_API_KEY_FAILED"
+const EXPIRE_API_KEY_FAILED string = "EXPIRE_API_KEY_FAILED"
+const API_KEY_DATA_INVALID string = "API_KEY_DATA_INVALID"
+const API_KEY_ID_INVALID string = "API_KEY_ID_INVALID"
+const API_KEY_NOT_EXISTS string = "API_KEY_NOT_EXISTS"
+const API_KEY_INVALID string = "API_KEY_INVALID"
+const AUTHENTICATE_FAILED string = "AUTHENTICATE_FAILED"
+
+type apiKeyService struct {
+	repo APIKeyRepository
+}
+
+func NewAPIKeyService(repo APIKeyRepository) APIKeyService {
+	return &apiKeyService{
+		repo: repo,
+	}
+}
+
+func (svc *apiKeyService) IssueAPIKey(request APIKeyRequest, createdBy string) (*IssuedAPIKey, error) {
+	now := time.Now().UTC()
+	if !validateRequest(request, now) {
+		fmt.Println(fmt.Errorf("Invalid API key data"))
+		return nil, &apiKeyServiceError{code: API_KEY_DATA_INVALID}
+	}
+
+	key, err := generateKey()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on generateKey : %v", err))
+		return nil, &apiKeyServiceError{code: ISSUE_API_KEY_FAILED}
+	}
+
+	apiKey := APIKey{
+		Name:      request.Name,
+		Prefix:    key[:prefixLength],
+		KeyHash:   hashKey(key),
+		Scopes:    request.Scopes,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: request.ExpiresAt,
+	}
+
+	ID, err := svc.repo.InsertAPIKey(apiKey)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on InsertAPIKey : %v", err))
+		return nil, &apiKeyServiceError{code: ISSUE_API_KEY_FAILED}
+	}
+	apiKey.ID = ID
+
+	return &IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+func (svc *apiKeyService) ListAPIKeys() (*APIKeyList, error) {
+	keys, err := svc.repo.ListAPIKeys()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListAPIKeys : %v", err))
+		return nil, &apiKeyServiceError{code: LIST_API_KEYS_FAILED}
+	}
+	return &APIKeyList{APIKeys: keys}, nil
+}
+
+func (svc *apiKeyService) GetAPIKey(ID string) (*APIKey, error) {
+	key, err := svc.repo.FindAPIKeyByID(ID)
+	if err != nil {
+		return nil, svc.writeError("FindAPIKeyByID", err, GET_API_KEY_FAILED)
+	}
+	if key == nil {
+		fmt.Println(fmt.Errorf("API key not exists"))
+		return nil, &apiKeyServiceError{code: API_KEY_NOT_EXISTS}
+	}
+	return key, nil
+}
+
+func (svc *apiKeyService) RevokeAPIKey(ID string) error {
+	if err := svc.repo.RevokeAPIKey(ID); err != nil {
+		return svc.writeError("RevokeAPIKey", err, REVOKE_API_KEY_FAILED)
+	}
+	return nil
+}
+
+func (svc *apiKeyService) ExpireAPIKey(ID string, expiresAt *time.Time) error {
+	expiration := time.Now().UTC()
+	if expiresAt != nil {
+		expiration = *expiresAt
+	}
+
+	if err := svc.repo.ExpireAPIKey(ID, expiration); err != nil {
+		return svc.writeError("ExpireAPIKey", err, EXPIRE_API_KEY_FAILED)
+	}
+	return nil
+}
+
+func (svc *apiKeyService) Authenticate(key string) (*APIKey, error) {
+	apiKey, err := svc.repo.FindAPIKeyByHash(hashKey(key))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindAPIKeyByHash : %v", err))
+		return nil, &apiKeyServiceError{code: AUTHENTICATE_FAILED}
+	}
+
+	if apiKey == nil || !apiKey.active(time.Now()) {
+		fmt.Println(fmt.Errorf("Invalid API key"))
+		return nil, &apiKeyServiceError{code: API_KEY_INVALID}
+	}
+	return apiKey, nil
+}
+
+func (svc *apiKeyService) writeError(method string, err error, code string) error {
+	switch err.Error() {
+	case INVALID_OBJECT_ID:
+		fmt.Println(fmt.Errorf("Invalid API key id : %v", err))
+		return &apiKeyServiceError{code: API_KEY_ID_INVALID}
+	case DOCUMENT_NOT_FOUND:
+		fmt.Println(fmt.Errorf("API key not exists"))
+		return &apiKeyServiceError{code: API_KEY_NOT_EXISTS}
+	}
+	fmt.Println(fmt.Errorf("Error on %s : %v", method, err))
+	return &apiKeyServiceError{code: code}
+}
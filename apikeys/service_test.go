@@ -0,0 +1,236 @@
+package apikeys
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestServiceIssueAPIKey(t *testing.T) {
+
+	const keyID string = "64260e1da4c0c814bda5734a"
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		setupMock     func(repository *MockAPIKeyRepository)
+		inputParam    APIKeyRequest
+		expectedError error
+	}{
+		{
+			name: "issue api key success",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					InsertAPIKey(gomock.Any()).
+					DoAndReturn(func(key APIKey) (string, error) {
+						if key.KeyHash == "" || !strings.HasPrefix(key.Prefix, KEY_PREFIX) || key.CreatedBy != "admin" {
+							t.Errorf("Expecting hashed key created by admin , but returns %v", key)
+						}
+						return keyID, nil
+					})
+			},
+			inputParam:    APIKeyRequest{Name: "billing", Scopes: []string{"users:read"}},
+			expectedError: nil,
+		},
+		{
+			name:          "name required",
+			setupMock:     func(repository *MockAPIKeyRepository) {},
+			inputParam:    APIKeyRequest{Scopes: []string{"users:read"}},
+			expectedError: &apiKeyServiceError{code: API_KEY_DATA_INVALID},
+		},
+		{
+			name:          "unknown scope",
+			setupMock:     func(repository *MockAPIKeyRepository) {},
+			inputParam:    APIKeyRequest{Name: "billing", Scopes: []string{"users:roles"}},
+			expectedError: &apiKeyServiceError{code: API_KEY_DATA_INVALID},
+		},
+		{
+			name:          "expiration in the past",
+			setupMock:     func(repository *MockAPIKeyRepository) {},
+			inputParam:    APIKeyRequest{Name: "billing", Scopes: []string{"users:read"}, ExpiresAt: &past},
+			expectedError: &apiKeyServiceError{code: API_KEY_DATA_INVALID},
+		},
+		{
+			name: "insert failed",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					InsertAPIKey(gomock.Any()).
+					Return("", fmt.Errorf("Any Error"))
+			},
+			inputParam:    APIKeyRequest{Name: "billing", Scopes: []string{"users:read"}},
+			expectedError: &apiKeyServiceError{code: ISSUE_API_KEY_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockAPIKeyRepository(ctrl)
+			tc.setupMock(repo)
+
+			service := NewAPIKeyService(repo)
+
+			issued, err := service.IssueAPIKey(tc.inputParam, "admin")
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil && (issued.ID != keyID || hashKey(issued.Key) != issued.KeyHash) {
+				t.Errorf("Expecting issued key %s matching its hash , but returns %v", keyID, issued)
+			}
+		})
+	}
+}
+
+func TestServiceAuthenticate(t *testing.T) {
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		setupMock     func(repository *MockAPIKeyRepository)
+		expectedError error
+	}{
+		{
+			name: "active key",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					FindAPIKeyByHash(hashKey("uak_key")).
+					Return(&APIKey{ID: "key-1", ExpiresAt: &future}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "unknown key",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					FindAPIKeyByHash(gomock.Any()).
+					Return(nil, nil)
+			},
+			expectedError: &apiKeyServiceError{code: API_KEY_INVALID},
+		},
+		{
+			name: "revoked key",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					FindAPIKeyByHash(gomock.Any()).
+					Return(&APIKey{ID: "key-1", RevokedAt: &past}, nil)
+			},
+			expectedError: &apiKeyServiceError{code: API_KEY_INVALID},
+		},
+		{
+			name: "expired key",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					FindAPIKeyByHash(gomock.Any()).
+					Return(&APIKey{ID: "key-1", ExpiresAt: &past}, nil)
+			},
+			expectedError: &apiKeyServiceError{code: API_KEY_INVALID},
+		},
+		{
+			name: "find failed",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					FindAPIKeyByHash(gomock.Any()).
+					Return(nil, fmt.Errorf("Any Error"))
+			},
+			expectedError: &apiKeyServiceError{code: AUTHENTICATE_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockAPIKeyRepository(ctrl)
+			tc.setupMock(repo)
+
+			service := NewAPIKeyService(repo)
+
+			_, err := service.Authenticate("uak_key")
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceRevokeAPIKey(t *testing.T) {
+
+	const keyID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name          string
+		setupMock     func(repository *MockAPIKeyRepository)
+		expectedError error
+	}{
+		{
+			name: "revoke success",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					RevokeAPIKey(keyID).
+					Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "key not exists",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					RevokeAPIKey(gomock.Any()).
+					Return(fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			expectedError: &apiKeyServiceError{code: API_KEY_NOT_EXISTS},
+		},
+		{
+			name: "invalid id",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					RevokeAPIKey(gomock.Any()).
+					Return(fmt.Errorf(INVALID_OBJECT_ID))
+			},
+			expectedError: &apiKeyServiceError{code: API_KEY_ID_INVALID},
+		},
+		{
+			name: "revoke failed",
+			setupMock: func(repository *MockAPIKeyRepository) {
+				repository.
+					EXPECT().
+					RevokeAPIKey(gomock.Any()).
+					Return(fmt.Errorf("Any Error"))
+			},
+			expectedError: &apiKeyServiceError{code: REVOKE_API_KEY_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockAPIKeyRepository(ctrl)
+			tc.setupMock(repo)
+
+			service := NewAPIKeyService(repo)
+
+			err := service.RevokeAPIKey(keyID)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package apikeys
+
+import (
+	"strings"
+	"time"
+)
+
+// Tells if the request has a name, at least one known scope and, when set, a future expiration
+func validateRequest(request APIKeyRequest, now time.Time) bool {
+	if strings.TrimSpace(request.Name) == "" || len(request.Scopes) == 0 {
+		return false
+	}
+	for _, scope := range request.Scopes {
+		if !validScope(scope) {
+			return false
+		}
+	}
+	return request.ExpiresAt == nil || request.ExpiresAt.After(now)
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
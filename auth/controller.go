@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"encoding/json"
+	"userapi/lockout"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller containing all Auth request handlers
+type AuthController struct {
+	service AuthService
+}
+
+// Returns new AuthController instance
+func NewAuthController(service AuthService) AuthController {
+	return AuthController{
+		service: service,
+	}
+}
+
+// Login godoc
+//
+//	@Summary		Login
+//	@Description	This endpoint authenticates a user by email and password, returning an access token and a refresh token.
+//	@Description	Users with MFA enabled must also send a TOTP or recovery code.
+//	@Description	Failed logins delay the next ones progressively, locking the account after LOCKOUT_THRESHOLD failures.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		LoginRequest	true	"body"
+//	@Success		200		{object}	TokenResponse
+//	@Failure		400		{object}	AuthResponse
+//	@Failure		401		{object}	AuthResponse
+//	@Failure		403		{object}	AuthResponse
+//	@Failure		429		{object}	AuthResponse
+//	@Failure		502		{object}	AuthResponse
+//	@Router			/auth/login [post]
+func (ctr AuthController) Login(c *gin.Context) {
+	var request LoginRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.Email == "" || request.Password == "" {
+		c.JSON(400, INVALID_LOGIN_DATA)
+		return
+	}
+
+	response, err := ctr.service.Login(request)
+	if err != nil {
+		if err.Error() == CREDENTIALS_INVALID {
+			c.JSON(401, INVALID_CREDENTIALS)
+			return
+		}
+		if err.Error() == EMAIL_NOT_VERIFIED {
+			c.JSON(403, EMAIL_NOT_VERIFIED_RESPONSE)
+			return
+		}
+		if locked, ok := err.(*lockout.LockedError); ok {
+			c.Header("Retry-After", locked.RetryAfterSeconds())
+			c.JSON(429, ACCOUNT_LOCKED)
+			return
+		}
+		if err.Error() == MFA_REQUIRED {
+			c.JSON(401, MFA_REQUIRED_RESPONSE)
+			return
+		}
+		if err.Error() == MFA_CODE_INVALID {
+			c.JSON(401, INVALID_MFA_CODE)
+			return
+		}
+		c.JSON(502, LOGIN_FAILED_RESPONSE)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, response)
+}
+
+// Refresh godoc
+//
+//	@Summary		Refresh tokens
+//	@Description	This endpoint rotates a refresh token, returning a new access token and a new refresh token.
+//	@Description	A refresh token can only be used once, using it again revokes all tokens from the same login.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		RefreshRequest	true	"body"
+//	@Success		200		{object}	TokenResponse
+//	@Failure		400		{object}	AuthResponse
+//	@Failure		401		{object}	AuthResponse
+//	@Failure		502		{object}	AuthResponse
+//	@Router			/auth/refresh [post]
+func (ctr AuthController) Refresh(c *gin.Context) {
+	var request RefreshRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		c.JSON(400, INVALID_REFRESH_TOKEN)
+		return
+	}
+
+	response, err := ctr.service.Refresh(request.RefreshToken)
+	if err != nil {
+		if err.Error() == REFRESH_TOKEN_INVALID {
+			c.JSON(401, INVALID_REFRESH_TOKEN)
+			return
+		}
+		c.JSON(502, TOKEN_REFRESH_FAILED)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, response)
+}
+
+// Logout godoc
+//
+//	@Summary		Logout
+//	@Description	This endpoint revokes a refresh token and all tokens rotated from the same login.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		RefreshRequest	true	"body"
+//	@Success		200		{object}	AuthResponse
+//	@Failure		400		{object}	AuthResponse
+//	@Failure		502		{object}	AuthResponse
+//	@Router			/auth/logout [post]
+func (ctr AuthController) Logout(c *gin.Context) {
+	var request RefreshRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.RefreshToken == "" {
+		c.JSON(400, INVALID_REFRESH_TOKEN)
+		return
+	}
+
+	if err := ctr.service.Logout(request.RefreshToken); err != nil {
+		c.JSON(502, LOGOUT_FAILED_RESPONSE)
+		return
+	}
+
+	c.JSON(200, LOGGED_OUT)
+}
+
+// RequestPasswordReset godoc
+//
+//	@Summary		Request password reset
+//	@Description	This endpoint sends a single use password reset token to the user email.
+//	@Description	It accepts any email, so the response does not reveal registered emails.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		PasswordResetRequest	true	"body"
+//	@Success		202		{object}	AuthResponse
+//	@Failure		400		{object}	AuthResponse
+//	@Failure		502		{object}	AuthResponse
+//	@Router			/auth/password-reset [post]
+func (ctr AuthController) RequestPasswordReset(c *gin.Context) {
+	var request PasswordResetRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.Email == "" {
+		c.JSON(400, INVALID_RESET_DATA)
+		return
+	}
+
+	if err := ctr.service.RequestPasswordReset(request.Email); err != nil {
+		c.JSON(502, PASSWORD_RESET_FAILED)
+		return
+	}
+
+	c.JSON(202, PASSWORD_RESET_REQUESTED)
+}
+
+// ConfirmPasswordReset godoc
+//
+//	@Summary		Confirm password reset
+//	@Description	This endpoint sets a new password using a password reset token, closing all sessions of the user.
+//	@Description	The password must follow the password policy, otherwise the token can be used again.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		PasswordResetConfirm	true	"body"
+//	@Success		200		{object}	AuthResponse
+//	@Failure		400		{object}	AuthResponse
+//	@Failure		422		{object}	users.ValidationErrors
+//	@Failure		502		{object}	AuthResponse
+//	@Router			/auth/password-reset/confirm [post]
+func (ctr AuthController) ConfirmPasswordReset(c *gin.Context) {
+	var request PasswordResetConfirm
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.Token == "" || request.Password == "" {
+		c.JSON(400, INVALID_RESET_DATA)
+		return
+	}
+
+	if err := ctr.service.ConfirmPasswordReset(request); err != nil {
+		if validation, ok := err.(*users.ValidationErrors); ok {
+			c.JSON(422, validation)
+			return
+		}
+		if err.Error() == RESET_TOKEN_INVALID {
+			c.JSON(400, INVALID_RESET_TOKEN)
+			return
+		}
+		c.JSON(502, PASSWORD_RESET_FAILED)
+		return
+	}
+
+	c.JSON(200, PASSWORD_RESET)
+}
@@ -0,0 +1,321 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestLogin(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAuthService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "login success",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Login(LoginRequest{Email: "test@test.com", Password: "12345"}).
+					Return(&TokenResponse{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer", ExpiresIn: 900}, nil)
+			},
+			inputBody:        `{"email": "test@test.com", "password": "12345"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"accessToken":"access","refreshToken":"refresh","tokenType":"Bearer","expiresIn":900}`,
+		},
+		{
+			name:             "invalid login data",
+			setupMock:        func(service *MockAuthService) {},
+			inputBody:        `{"email": "test@test.com"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Login Data","code":"INVALID_LOGIN_DATA"}`,
+		},
+		{
+			name: "invalid credentials",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Login(gomock.Any()).
+					Return(nil, &authServiceError{code: CREDENTIALS_INVALID})
+			},
+			inputBody:        `{"email": "test@test.com", "password": "54321"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"message":"Invalid Credentials","code":"INVALID_CREDENTIALS"}`,
+		},
+		{
+			name: "login failed",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Login(gomock.Any()).
+					Return(nil, &authServiceError{code: LOGIN_FAILED})
+			},
+			inputBody:        `{"email": "test@test.com", "password": "12345"}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Login Failed","code":"LOGIN_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAuthService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAuthController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/auth/login", controller.Login)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAuthService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "refresh success",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Refresh("refresh").
+					Return(&TokenResponse{AccessToken: "access", RefreshToken: "new", TokenType: "Bearer", ExpiresIn: 900}, nil)
+			},
+			inputBody:        `{"refreshToken": "refresh"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"accessToken":"access","refreshToken":"new","tokenType":"Bearer","expiresIn":900}`,
+		},
+		{
+			name:             "missing refresh token",
+			setupMock:        func(service *MockAuthService) {},
+			inputBody:        `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Refresh Token","code":"INVALID_REFRESH_TOKEN"}`,
+		},
+		{
+			name: "invalid refresh token",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Refresh(gomock.Any()).
+					Return(nil, &authServiceError{code: REFRESH_TOKEN_INVALID})
+			},
+			inputBody:        `{"refreshToken": "refresh"}`,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedResponse: `{"message":"Invalid Refresh Token","code":"INVALID_REFRESH_TOKEN"}`,
+		},
+		{
+			name: "refresh failed",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Refresh(gomock.Any()).
+					Return(nil, &authServiceError{code: REFRESH_FAILED})
+			},
+			inputBody:        `{"refreshToken": "refresh"}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Token Refresh Failed","code":"TOKEN_REFRESH_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAuthService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAuthController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/auth/refresh", controller.Refresh)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAuthService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "logout success",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Logout("refresh").
+					Return(nil)
+			},
+			inputBody:        `{"refreshToken": "refresh"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Logged Out","code":"LOGGED_OUT"}`,
+		},
+		{
+			name: "logout failed",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					Logout(gomock.Any()).
+					Return(&authServiceError{code: LOGOUT_FAILED})
+			},
+			inputBody:        `{"refreshToken": "refresh"}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Logout Failed","code":"LOGOUT_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAuthService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAuthController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/auth/logout", controller.Logout)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/auth/logout", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockAuthService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "password reset",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					ConfirmPasswordReset(PasswordResetConfirm{Token: "reset", Password: "new-password"}).
+					Return(nil)
+			},
+			inputBody:        `{"token": "reset", "password": "new-password"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Password Reset","code":"PASSWORD_RESET"}`,
+		},
+		{
+			name:             "password required",
+			setupMock:        func(service *MockAuthService) {},
+			inputBody:        `{"token": "reset"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Reset Data","code":"INVALID_RESET_DATA"}`,
+		},
+		{
+			name: "invalid reset token",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					ConfirmPasswordReset(gomock.Any()).
+					Return(&authServiceError{code: RESET_TOKEN_INVALID})
+			},
+			inputBody:        `{"token": "used", "password": "new-password"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Reset Token","code":"INVALID_RESET_TOKEN"}`,
+		},
+		{
+			name: "password too short",
+			setupMock: func(service *MockAuthService) {
+				service.
+					EXPECT().
+					ConfirmPasswordReset(gomock.Any()).
+					Return(users.NewValidationErrors([]users.FieldError{*users.PASSWORD_TOO_SHORT}))
+			},
+			inputBody:        `{"token": "reset", "password": "short"}`,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedResponse: `{"message":"Invalid User Data","code":"VALIDATION_FAILED","errors":[{"field":"password","code":"PASSWORD_TOO_SHORT","message":"Password Too Short"}]}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockAuthService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewAuthController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/auth/password-reset/confirm", controller.ConfirmPasswordReset)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const BEARER_TOKEN_TYPE string = "Bearer"
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	// TOTP or recovery code, required when the user enabled MFA
+	Code string `json:"code,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+// Claims of access tokens issued on login and refresh
+type AccessClaims struct {
+	jwt.RegisteredClaims
+	Roles         []string `json:"roles,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+// Refresh token as stored, only the token hash is persisted.
+// All tokens rotated from the same login share the same family.
+type RefreshToken struct {
+	ID        string     `bson:"_id,omitempty"`
+	TokenHash string     `bson:"tokenHash"`
+	UserID    string     `bson:"userId"`
+	Family    string     `bson:"family"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirm struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Password reset token as stored, only the token hash is persisted
+type PasswordResetToken struct {
+	ID        string     `bson:"_id,omitempty"`
+	TokenHash string     `bson:"tokenHash"`
+	UserID    string     `bson:"userId"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	UsedAt    *time.Time `bson:"usedAt,omitempty"`
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const refreshTokenCollection string = "refresh_tokens"
+const passwordResetCollection string = "password_resets"
+
+type RefreshTokenRepository interface {
+	InsertRefreshToken(token RefreshToken) error
+	FindRefreshToken(tokenHash string) (*RefreshToken, error)
+	// Revokes the token if it is still active, returns false when it was already revoked
+	RevokeRefreshToken(tokenHash string) (bool, error)
+	RevokeFamily(family string) error
+	RevokeUserTokens(userID string) error
+	EnsureIndexes() error
+}
+
+type refreshTokenRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewRefreshTokenRepository(client *mongo.Client, database string) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *refreshTokenRepository) InsertRefreshToken(token RefreshToken) error {
+	token.ID = ""
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	_, err := coll.InsertOne(context.Background(), token)
+	return err
+}
+
+func (repo *refreshTokenRepository) FindRefreshToken(tokenHash string) (*RefreshToken, error) {
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	filter := bson.M{"tokenHash": bson.M{"$eq": tokenHash}}
+
+	var token RefreshToken
+	err := coll.FindOne(context.Background(), filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (repo *refreshTokenRepository) RevokeRefreshToken(tokenHash string) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	filter := bson.M{"tokenHash": bson.M{"$eq": tokenHash}, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (repo *refreshTokenRepository) RevokeFamily(family string) error {
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	filter := bson.M{"family": bson.M{"$eq": family}, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+
+	_, err := coll.UpdateMany(context.Background(), filter, update)
+	return err
+}
+
+func (repo *refreshTokenRepository) RevokeUserTokens(userID string) error {
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	filter := bson.M{"userId": bson.M{"$eq": userID}, "revokedAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now().UTC()}}
+
+	_, err := coll.UpdateMany(context.Background(), filter, update)
+	return err
+}
+
+// Creates the unique token hash index and the TTL index removing expired tokens
+func (repo *refreshTokenRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(refreshTokenCollection)
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "family", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+type PasswordResetRepository interface {
+	InsertResetToken(token PasswordResetToken) error
+	// Returns the token if it is unused and not expired, nil when it is not
+	FindResetToken(tokenHash string) (*PasswordResetToken, error)
+	// Marks the token as used if it is unused and not expired, returns nil when it is not
+	ConsumeResetToken(tokenHash string) (*PasswordResetToken, error)
+	EnsureIndexes() error
+}
+
+type passwordResetRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewPasswordResetRepository(client *mongo.Client, database string) PasswordResetRepository {
+	return &passwordResetRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *passwordResetRepository) InsertResetToken(token PasswordResetToken) error {
+	token.ID = ""
+	coll := repo.client.Database(repo.database).Collection(passwordResetCollection)
+	_, err := coll.InsertOne(context.Background(), token)
+	return err
+}
+
+func (repo *passwordResetRepository) FindResetToken(tokenHash string) (*PasswordResetToken, error) {
+	coll := repo.client.Database(repo.database).Collection(passwordResetCollection)
+	filter := bson.M{
+		"tokenHash": bson.M{"$eq": tokenHash},
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}
+
+	var token PasswordResetToken
+	err := coll.FindOne(context.Background(), filter).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (repo *passwordResetRepository) ConsumeResetToken(tokenHash string) (*PasswordResetToken, error) {
+	coll := repo.client.Database(repo.database).Collection(passwordResetCollection)
+	now := time.Now().UTC()
+	filter := bson.M{
+		"tokenHash": bson.M{"$eq": tokenHash},
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": now}}
+
+	var token PasswordResetToken
+	err := coll.FindOneAndUpdate(context.Background(), filter, update).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Creates the unique token hash index and the TTL index removing expired tokens
+func (repo *passwordResetRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(passwordResetCollection)
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+	"userapi/mailer"
+	"userapi/users"
+)
+
+func (svc *authService) RequestPasswordReset(email string) error {
+	user, err := svc.users.FindUserByEmail(email, users.Projection{{Key: "email", Value: 1}})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindUserByEmail : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	if user == nil {
+		fmt.Println(fmt.Errorf("Password reset requested for unknown email"))
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on randomToken : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	now := time.Now().UTC()
+	err = svc.resets.InsertResetToken(PasswordResetToken{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(svc.config.ResetTokenTTL),
+	})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on InsertResetToken : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	// Sent to the email stored for the user the token is issued for, not the one typed.
+	// A delivery failure is not returned, it would tell the email is registered
+	if err := svc.mailer.Send(svc.resetMessage(user.Email, token)); err != nil {
+		fmt.Println(fmt.Errorf("Error on Send : %v", err))
+	}
+	return nil
+}
+
+func (svc *authService) ConfirmPasswordReset(request PasswordResetConfirm) error {
+	token, err := svc.resets.FindResetToken(hashToken(request.Token))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindResetToken : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	if token == nil {
+		fmt.Println(fmt.Errorf("Invalid password reset token"))
+		return &authServiceError{code: RESET_TOKEN_INVALID}
+	}
+
+	// The password is validated before the token is consumed, so the token can be used again with another password
+	projection := users.Projection{{Key: "name", Value: 1}, {Key: "email", Value: 1}}
+	user, err := svc.users.FindUserByID(token.UserID, projection)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindUserByID : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	if user == nil {
+		fmt.Println(fmt.Errorf("User not exists"))
+		return &authServiceError{code: RESET_TOKEN_INVALID}
+	}
+
+	if validation := svc.passwords.Validate(request.Password, *user); validation != nil {
+		return users.NewValidationErrors([]users.FieldError{*validation})
+	}
+
+	token, err = svc.resets.ConsumeResetToken(token.TokenHash)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ConsumeResetToken : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	if token == nil {
+		fmt.Println(fmt.Errorf("Invalid password reset token"))
+		return &authServiceError{code: RESET_TOKEN_INVALID}
+	}
+
+	changes := users.UserChanges{Set: users.Projection{{Key: "password", Value: users.HashPassword(request.Password)}}}
+	// The token proves the user is the one resetting the password
+	event, err := svc.users.PatchUser(users.Actor{ID: token.UserID}, token.UserID, changes, nil)
+	if err != nil {
+		if err.Error() == users.DOCUMENT_NOT_FOUND {
+			fmt.Println(fmt.Errorf("User not exists"))
+			return &authServiceError{code: RESET_TOKEN_INVALID}
+		}
+		fmt.Println(fmt.Errorf("Error on PatchUser : %v", err))
+		return &authServiceError{code: RESET_FAILED}
+	}
+
+	// The reset is streamed as any other user change, the event written on the outbox
+	if svc.events != nil {
+		if err := svc.events.Publish(*event); err != nil {
+			fmt.Println(fmt.Errorf("Error on Publish : %v", err))
+		}
+	}
+
+	// Sessions opened with the old password are closed
+	if err := svc.tokens.RevokeUserTokens(token.UserID); err != nil {
+		fmt.Println(fmt.Errorf("Error on RevokeUserTokens : %v", err))
+	}
+	return nil
+}
+
+func (svc *authService) resetMessage(email string, token string) mailer.Message {
+	body := fmt.Sprintf("Use this token to reset your password: %s\n", token)
+	if svc.config.ResetURL != "" {
+		body = fmt.Sprintf("Open this link to reset your password: %s?token=%s\n", svc.config.ResetURL, token)
+	}
+	body += fmt.Sprintf("\nIt expires in %s and can be used only once. If you did not request it, ignore this email.", svc.config.ResetTokenTTL)
+
+	return mailer.Message{
+		To:      email,
+		Subject: "Password reset",
+		Body:    body,
+	}
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"userapi/lockout"
+	"userapi/mailer"
+	"userapi/mfa"
+	"userapi/users"
+
+	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestServiceRequestPasswordReset(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name          string
+		setupMock     func(users *users.MockUserRepository, resets *MockPasswordResetRepository, m *mailer.MockMailer)
+		expectedError error
+	}{
+		{
+			name: "reset token sent",
+			setupMock: func(userRepository *users.MockUserRepository, resets *MockPasswordResetRepository, m *mailer.MockMailer) {
+				var tokenHash string
+				userRepository.
+					EXPECT().
+					FindUserByEmail("Test@TEST.com", gomock.Any()).
+					Return(&users.User{ID: userID, Email: "test@test.com"}, nil)
+				resets.
+					EXPECT().
+					InsertResetToken(gomock.Any()).
+					DoAndReturn(func(token PasswordResetToken) error {
+						tokenHash = token.TokenHash
+						if token.UserID != userID || !token.ExpiresAt.After(token.CreatedAt) {
+							t.Errorf("Expecting expiring token of user %s , but returns %v", userID, token)
+						}
+						return nil
+					})
+				m.
+					EXPECT().
+					Send(gomock.Any()).
+					DoAndReturn(func(message mailer.Message) error {
+						token := strings.TrimSpace(strings.Split(strings.SplitN(message.Body, ": ", 2)[1], "\n")[0])
+						if message.To != "test@test.com" || hashToken(token) != tokenHash {
+							t.Errorf("Expecting mail with the stored token , but returns %v", message)
+						}
+						return nil
+					})
+			},
+			expectedError: nil,
+		},
+		{
+			name: "unknown email",
+			setupMock: func(userRepository *users.MockUserRepository, resets *MockPasswordResetRepository, m *mailer.MockMailer) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "mail failed",
+			setupMock: func(userRepository *users.MockUserRepository, resets *MockPasswordResetRepository, m *mailer.MockMailer) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID}, nil)
+				resets.
+					EXPECT().
+					InsertResetToken(gomock.Any()).
+					Return(nil)
+				m.
+					EXPECT().
+					Send(gomock.Any()).
+					Return(fmt.Errorf("Any Error"))
+			},
+			expectedError: nil,
+		},
+		{
+			name: "insert failed",
+			setupMock: func(userRepository *users.MockUserRepository, resets *MockPasswordResetRepository, m *mailer.MockMailer) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID}, nil)
+				resets.
+					EXPECT().
+					InsertResetToken(gomock.Any()).
+					Return(fmt.Errorf("Any Error"))
+			},
+			expectedError: &authServiceError{code: RESET_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			userRepository := users.NewMockUserRepository(ctrl)
+			resets := NewMockPasswordResetRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			tc.setupMock(userRepository, resets, m)
+
+			service := NewAuthService(userRepository, nil, NewMockRefreshTokenRepository(ctrl), resets, m, mfa.NewMockMFAService(ctrl), lockout.NewMockLockoutService(ctrl), testPasswords, testConfig)
+
+			err := service.RequestPasswordReset("Test@TEST.com")
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceConfirmPasswordReset(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	const resetToken string = "reset-token"
+
+	activeToken := PasswordResetToken{TokenHash: hashToken(resetToken), UserID: userID}
+	user := users.User{ID: userID, Name: "Test", Email: "test@test.com"}
+
+	tests := []struct {
+		name           string
+		setupMock      func(users *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository)
+		inputPassword  string
+		expectedError  error
+		expectedEvents int
+	}{
+		{
+			name: "password reset",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.EXPECT().FindResetToken(hashToken(resetToken)).Return(&activeToken, nil)
+				userRepository.EXPECT().FindUserByID(userID, gomock.Any()).Return(&user, nil)
+				resets.
+					EXPECT().
+					ConsumeResetToken(hashToken(resetToken)).
+					Return(&activeToken, nil)
+				userRepository.
+					EXPECT().
+					PatchUser(users.Actor{ID: userID}, userID, gomock.Any(), nil).
+					DoAndReturn(func(actor users.Actor, ID string, changes users.UserChanges, precondition users.Precondition) (*users.UserEvent, error) {
+						hash, _ := changes.Set[0].Value.(string)
+						if changes.Set[0].Key != "password" || bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) != nil {
+							t.Errorf("Expecting new password hash , but returns %v", changes)
+						}
+						return &users.UserEvent{Type: users.EVENT_USER_UPDATED, UserID: userID, Version: 2}, nil
+					})
+				tokens.
+					EXPECT().
+					RevokeUserTokens(userID).
+					Return(nil)
+			},
+			inputPassword:  "new-password",
+			expectedError:  nil,
+			expectedEvents: 1,
+		},
+		{
+			name: "password breaks policy",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.EXPECT().FindResetToken(gomock.Any()).Return(&activeToken, nil)
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&user, nil)
+			},
+			inputPassword: "password",
+			expectedError: users.NewValidationErrors([]users.FieldError{*users.PASSWORD_TOO_WEAK}),
+		},
+		{
+			name: "password equals email",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.EXPECT().FindResetToken(gomock.Any()).Return(&activeToken, nil)
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&user, nil)
+			},
+			inputPassword: "Test@Test.com",
+			expectedError: users.NewValidationErrors([]users.FieldError{*users.PASSWORD_MATCHES_USER}),
+		},
+		{
+			name: "used or expired token",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.
+					EXPECT().
+					FindResetToken(gomock.Any()).
+					Return(nil, nil)
+			},
+			inputPassword: "new-password",
+			expectedError: &authServiceError{code: RESET_TOKEN_INVALID},
+		},
+		{
+			name: "token used meanwhile",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.EXPECT().FindResetToken(gomock.Any()).Return(&activeToken, nil)
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&user, nil)
+				resets.
+					EXPECT().
+					ConsumeResetToken(gomock.Any()).
+					Return(nil, nil)
+			},
+			inputPassword: "new-password",
+			expectedError: &authServiceError{code: RESET_TOKEN_INVALID},
+		},
+		{
+			name: "user not exists",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.EXPECT().FindResetToken(gomock.Any()).Return(&activeToken, nil)
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+			inputPassword: "new-password",
+			expectedError: &authServiceError{code: RESET_TOKEN_INVALID},
+		},
+		{
+			name: "find token failed",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository, resets *MockPasswordResetRepository) {
+				resets.
+					EXPECT().
+					FindResetToken(gomock.Any()).
+					Return(nil, fmt.Errorf("Any Error"))
+			},
+			inputPassword: "new-password",
+			expectedError: &authServiceError{code: RESET_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			userRepository := users.NewMockUserRepository(ctrl)
+			tokens := NewMockRefreshTokenRepository(ctrl)
+			resets := NewMockPasswordResetRepository(ctrl)
+			events := users.NewMemoryPublisher(10)
+			tc.setupMock(userRepository, tokens, resets)
+
+			service := NewAuthService(userRepository, events, tokens, resets, mailer.NewMockMailer(ctrl), mfa.NewMockMFAService(ctrl), lockout.NewMockLockoutService(ctrl), testPasswords, testConfig)
+
+			err := service.ConfirmPasswordReset(PasswordResetConfirm{Token: resetToken, Password: tc.inputPassword})
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if published := events.Events(); len(published) != tc.expectedEvents {
+				t.Errorf("Expecting events %v , but returns %v", tc.expectedEvents, published)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+type AuthResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var INVALID_LOGIN_DATA AuthResponse = AuthResponse{
+	Message: "Invalid Login Data",
+	Code:    "INVALID_LOGIN_DATA",
+}
+
+var INVALID_CREDENTIALS AuthResponse = AuthResponse{
+	Message: "Invalid Credentials",
+	Code:    "INVALID_CREDENTIALS",
+}
+
+var LOGIN_FAILED_RESPONSE AuthResponse = AuthResponse{
+	Message: "Login Failed",
+	Code:    "LOGIN_FAILED",
+}
+
+var INVALID_REFRESH_TOKEN AuthResponse = AuthResponse{
+	Message: "Invalid Refresh Token",
+	Code:    "INVALID_REFRESH_TOKEN",
+}
+
+var TOKEN_REFRESH_FAILED AuthResponse = AuthResponse{
+	Message: "Token Refresh Failed",
+	Code:    "TOKEN_REFRESH_FAILED",
+}
+
+var LOGGED_OUT AuthResponse = AuthResponse{
+	Message: "Logged Out",
+	Code:    "LOGGED_OUT",
+}
+
+var LOGOUT_FAILED_RESPONSE AuthResponse = AuthResponse{
+	Message: "Logout Failed",
+	Code:    "LOGOUT_FAILED",
+}
+
+var INVALID_RESET_DATA AuthResponse = AuthResponse{
+	Message: "Invalid Reset Data",
+	Code:    "INVALID_RESET_DATA",
+}
+
+var INVALID_RESET_TOKEN AuthResponse = AuthResponse{
+	Message: "Invalid Reset Token",
+	Code:    "INVALID_RESET_TOKEN",
+}
+
+var PASSWORD_RESET_REQUESTED AuthResponse = AuthResponse{
+	Message: "Password Reset Requested",
+	Code:    "PASSWORD_RESET_REQUESTED",
+}
+
+var PASSWORD_RESET AuthResponse = AuthResponse{
+	Message: "Password Reset",
+	Code:    "PASSWORD_RESET",
+}
+
+var PASSWORD_RESET_FAILED AuthResponse = AuthResponse{
+	Message: "Password Reset Failed",
+	Code:    "PASSWORD_RESET_FAILED",
+}
+
+var EMAIL_NOT_VERIFIED_RESPONSE AuthResponse = AuthResponse{
+	Message: "Email Not Verified",
+	Code:    "EMAIL_NOT_VERIFIED",
+}
+
+var MFA_REQUIRED_RESPONSE AuthResponse = AuthResponse{
+	Message: "MFA Required",
+	Code:    "MFA_REQUIRED",
+}
+
+var INVALID_MFA_CODE AuthResponse = AuthResponse{
+	Message: "Invalid MFA Code",
+	Code:    "INVALID_MFA_CODE",
+}
+
+var ACCOUNT_LOCKED AuthResponse = AuthResponse{
+	Message: "Account Temporarily Locked",
+	Code:    "ACCOUNT_LOCKED",
+}
@@ -0,0 +1,40 @@
+// Auth module containing Controllers, Services e Repositories.
+// Module responsable for user authentication
+package auth
+
+import (
+	"fmt"
+	"userapi/config"
+	"userapi/lockout"
+	"userapi/mailer"
+	"userapi/mfa"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Method to add routes in api (gin.RouterGroup), using config (config.Config), client (mongo.Client),
+// passwords (users.PasswordPolicy) for reset passwords and events (users.Publisher) to publish password resets, may be nil
+func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client, passwords users.PasswordPolicy, events users.Publisher) {
+	var userRepository users.UserRepository = users.NewUserRepository(client, config.Database)
+	var refreshTokenRepository RefreshTokenRepository = NewRefreshTokenRepository(client, config.Database)
+	var passwordResetRepository PasswordResetRepository = NewPasswordResetRepository(client, config.Database)
+	var mfaService mfa.MFAService = mfa.NewMFAService(mfa.NewFactorRepository(client, config.Database), userRepository, config)
+	var lockoutService lockout.LockoutService = lockout.NewLockoutService(lockout.NewAttemptRepository(client, config.Database), userRepository, config)
+	var authService AuthService = NewAuthService(userRepository, events, refreshTokenRepository, passwordResetRepository, mailer.NewMailer(config.MailFile), mfaService, lockoutService, passwords, config)
+	var authController AuthController = NewAuthController(authService)
+
+	if err := refreshTokenRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	if err := passwordResetRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+
+	api.POST("/auth/login", authController.Login)
+	api.POST("/auth/refresh", authController.Refresh)
+	api.POST("/auth/logout", authController.Logout)
+	api.POST("/auth/password-reset", authController.RequestPasswordReset)
+	api.POST("/auth/password-reset/confirm", authController.ConfirmPasswordReset)
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"userapi/config"
+	"userapi/lockout"
+	"userapi/mailer"
+	"userapi/mfa"
+	"userapi/users"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AuthService interface {
+	/*
+		Method to authenticate user by email and password
+
+		Parameters
+
+		request: User email, password and the second factor code when MFA is enabled.
+	*/
+	Login(request LoginRequest) (*TokenResponse, error)
+	/*
+		Method to rotate refresh token issuing new tokens
+
+		Parameters
+
+		refreshToken: Refresh token issued on login or last refresh.
+	*/
+	Refresh(refreshToken string) (*TokenResponse, error)
+	/*
+		Method to revoke refresh token and all tokens rotated from the same login
+
+		Parameters
+
+		refreshToken: Refresh token issued on login or last refresh.
+	*/
+	Logout(refreshToken string) error
+	/*
+		Method to send a single use password reset token to the user email.
+		Unknown emails are ignored, so the response does not reveal registered emails.
+
+		Parameters
+
+		email: User email.
+	*/
+	RequestPasswordReset(email string) error
+	/*
+		Method to set a new password using a password reset token
+
+		Parameters
+
+		request: Password reset token and new password, following the password policy.
+	*/
+	ConfirmPasswordReset(request PasswordResetConfirm) error
+}
+
+type authServiceError struct {
+	code string
+}
+
+func (e *authServiceError) Error() string {
+	return e.code
+}
+
+const LOGIN_FAILED string = "LOGIN_FAILED"
+const CREDENTIALS_INVALID string = "CREDENTIALS_INVALID"
+const REFRESH_TOKEN_INVALID string = "REFRESH_TOKEN_INVALID"
+const REFRESH_FAILED string = "REFRESH_FAILED"
+const LOGOUT_FAILED string = "LOGOUT_FAILED"
+const EMAIL_NOT_VERIFIED string = "EMAIL_NOT_VERIFIED"
+const RESET_FAILED string = "RESET_FAILED"
+const RESET_TOKEN_INVALID string = "RESET_TOKEN_INVALID"
+const MFA_REQUIRED string = "MFA_REQUIRED"
+const MFA_CODE_INVALID string = "MFA_CODE_INVALID"
+
+var dummyHash []byte
+var dummyHashOnce sync.Once
+
+// Hash compared when the user does not exist, so response time does not reveal registered emails
+func getDummyHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), 8)
+	})
+	return dummyHash
+}
+
+type authService struct {
+	users     users.UserRepository
+	events    users.Publisher
+	tokens    RefreshTokenRepository
+	resets    PasswordResetRepository
+	mailer    mailer.Mailer
+	mfa       mfa.MFAService
+	lockout   lockout.LockoutService
+	passwords users.PasswordPolicy
+	config    config.Config
+}
+
+func NewAuthService(users users.UserRepository, events users.Publisher, tokens RefreshTokenRepository, resets PasswordResetRepository, mailer mailer.Mailer, mfa mfa.MFAService, lockout lockout.LockoutService, passwords users.PasswordPolicy, config config.Config) AuthService {
+	return &authService{
+		users:     users,
+		events:    events,
+		tokens:    tokens,
+		resets:    resets,
+		mailer:    mailer,
+		mfa:       mfa,
+		lockout:   lockout,
+		passwords: passwords,
+		config:    config,
+	}
+}
+
+func (svc *authService) Login(request LoginRequest) (*TokenResponse, error) {
+	key := lockout.LoginKey(request.Email)
+	if err := svc.lockout.Check(key); err != nil {
+		if err.Error() == lockout.ACCOUNT_LOCKED {
+			fmt.Println(fmt.Errorf("Account locked"))
+			return nil, err
+		}
+		return nil, &authServiceError{code: LOGIN_FAILED}
+	}
+
+	projection := users.Projection{{Key: "password", Value: 1}, {Key: "roles", Value: 1}, {Key: "emailVerified", Value: 1}}
+	user, err := svc.users.FindUserByEmail(request.Email, projection)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindUserByEmail : %v", err))
+		return nil, &authServiceError{code: LOGIN_FAILED}
+	}
+
+	if user == nil {
+		bcrypt.CompareHashAndPassword(getDummyHash(), []byte(request.Password))
+		fmt.Println(fmt.Errorf("Invalid credentials"))
+		svc.lockout.Fail(key)
+		return nil, &authServiceError{code: CREDENTIALS_INVALID}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.Password)); err != nil {
+		fmt.Println(fmt.Errorf("Invalid credentials"))
+		svc.lockout.Fail(key)
+		return nil, &authServiceError{code: CREDENTIALS_INVALID}
+	}
+
+	if !user.EmailVerified && users.RequiresVerifiedEmail(svc.config.RequireVerifiedEmail, users.VERIFIED_FOR_LOGIN) {
+		fmt.Println(fmt.Errorf("Email not verified"))
+		return nil, &authServiceError{code: EMAIL_NOT_VERIFIED}
+	}
+
+	if err := svc.mfa.Check(user.ID, request.Code); err != nil {
+		switch err.Error() {
+		case mfa.MFA_REQUIRED:
+			return nil, &authServiceError{code: MFA_REQUIRED}
+		case mfa.MFA_CODE_INVALID:
+			svc.lockout.Fail(key)
+			return nil, &authServiceError{code: MFA_CODE_INVALID}
+		}
+		fmt.Println(fmt.Errorf("Error on Check : %v", err))
+		return nil, &authServiceError{code: LOGIN_FAILED}
+	}
+	svc.lockout.Reset(key)
+
+	family, err := randomToken(16)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on randomToken : %v", err))
+		return nil, &authServiceError{code: LOGIN_FAILED}
+	}
+
+	response, err := svc.issueTokens(*user, family)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on issueTokens : %v", err))
+		return nil, &authServiceError{code: LOGIN_FAILED}
+	}
+	return response, nil
+}
+
+func (svc *authService) Refresh(refreshToken string) (*TokenResponse, error) {
+	tokenHash := hashToken(refreshToken)
+	token, err := svc.tokens.FindRefreshToken(tokenHash)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindRefreshToken : %v", err))
+		return nil, &authServiceError{code: REFRESH_FAILED}
+	}
+
+	if token == nil || time.Now().After(token.ExpiresAt) {
+		fmt.Println(fmt.Errorf("Invalid refresh token"))
+		return nil, &authServiceError{code: REFRESH_TOKEN_INVALID}
+	}
+
+	revoked, err := svc.tokens.RevokeRefreshToken(tokenHash)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on RevokeRefreshToken : %v", err))
+		return nil, &authServiceError{code: REFRESH_FAILED}
+	}
+
+	if !revoked {
+		// A rotated token was used again, it may be stolen so the whole family is revoked
+		fmt.Println(fmt.Errorf("Refresh token reused, revoking family %s", token.Family))
+		if err := svc.tokens.RevokeFamily(token.Family); err != nil {
+			fmt.Println(fmt.Errorf("Error on RevokeFamily : %v", err))
+		}
+		return nil, &authServiceError{code: REFRESH_TOKEN_INVALID}
+	}
+
+	// Roles and email verification are read again so their changes apply from the next refresh
+	user, err := svc.users.FindUserByID(token.UserID, users.Projection{{Key: "roles", Value: 1}, {Key: "emailVerified", Value: 1}})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindUserByID : %v", err))
+		return nil, &authServiceError{code: REFRESH_FAILED}
+	}
+
+	if user == nil {
+		fmt.Println(fmt.Errorf("User not exists"))
+		return nil, &authServiceError{code: REFRESH_TOKEN_INVALID}
+	}
+
+	response, err := svc.issueTokens(*user, token.Family)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on issueTokens : %v", err))
+		return nil, &authServiceError{code: REFRESH_FAILED}
+	}
+	return response, nil
+}
+
+func (svc *authService) Logout(refreshToken string) error {
+	token, err := svc.tokens.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindRefreshToken : %v", err))
+		return &authServiceError{code: LOGOUT_FAILED}
+	}
+
+	if token == nil {
+		// Logout is idempotent, an unknown token has nothing to revoke
+		return nil
+	}
+
+	if err := svc.tokens.RevokeFamily(token.Family); err != nil {
+		fmt.Println(fmt.Errorf("Error on RevokeFamily : %v", err))
+		return &authServiceError{code: LOGOUT_FAILED}
+	}
+	return nil
+}
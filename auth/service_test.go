@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/lockout"
+	"userapi/mailer"
+	"userapi/mfa"
+	"userapi/users"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testConfig config.Config = config.Config{
+	RequireVerifiedEmail: []string{"login"},
+	JWTSecret:            "test-secret-with-at-least-32-characters",
+	JWTIssuer:            "userapi",
+	AccessTokenTTL:       15 * time.Minute,
+	RefreshTokenTTL:      time.Hour,
+	ResetTokenTTL:        time.Hour,
+}
+
+var testPasswords users.PasswordPolicy = users.PasswordPolicy{MinLength: 8, Classes: 2}
+
+func TestServiceLogin(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	hash, _ := bcrypt.GenerateFromPassword([]byte("12345"), 8)
+
+	tests := []struct {
+		name          string
+		setupMock     func(users *users.MockUserRepository, tokens *MockRefreshTokenRepository)
+		inputParam    LoginRequest
+		mfaError      error
+		lockoutError  error
+		failures      int
+		expectedError error
+	}{
+		{
+			name: "login success",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail("test@test.com", gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash), EmailVerified: true}, nil)
+				tokens.
+					EXPECT().
+					InsertRefreshToken(gomock.Any()).
+					Return(nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			expectedError: nil,
+		},
+		{
+			name: "mfa code required",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash), EmailVerified: true}, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			mfaError:      fmt.Errorf(mfa.MFA_REQUIRED),
+			expectedError: &authServiceError{code: MFA_REQUIRED},
+		},
+		{
+			name: "invalid mfa code",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash), EmailVerified: true}, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345", Code: "000000"},
+			mfaError:      fmt.Errorf(mfa.MFA_CODE_INVALID),
+			failures:      1,
+			expectedError: &authServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "mfa check failed",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash), EmailVerified: true}, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345", Code: "123456"},
+			mfaError:      fmt.Errorf(mfa.MFA_FAILED),
+			expectedError: &authServiceError{code: LOGIN_FAILED},
+		},
+		{
+			name: "wrong password",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash)}, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "54321"},
+			failures:      1,
+			expectedError: &authServiceError{code: CREDENTIALS_INVALID},
+		},
+		{
+			name: "email not verified",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash)}, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			expectedError: &authServiceError{code: EMAIL_NOT_VERIFIED},
+		},
+		{
+			name:          "account locked",
+			setupMock:     func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			lockoutError:  &lockout.LockedError{RetryAfter: time.Minute},
+			expectedError: &lockout.LockedError{},
+		},
+		{
+			name: "user not exists",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			failures:      1,
+			expectedError: &authServiceError{code: CREDENTIALS_INVALID},
+		},
+		{
+			name: "find user failed",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any Error"))
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			expectedError: &authServiceError{code: LOGIN_FAILED},
+		},
+		{
+			name: "insert refresh token failed",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				userRepository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(&users.User{ID: userID, Password: string(hash), EmailVerified: true}, nil)
+				tokens.
+					EXPECT().
+					InsertRefreshToken(gomock.Any()).
+					Return(fmt.Errorf("Any Error"))
+			},
+			inputParam:    LoginRequest{Email: "test@test.com", Password: "12345"},
+			expectedError: &authServiceError{code: LOGIN_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			userRepository := users.NewMockUserRepository(ctrl)
+			tokens := NewMockRefreshTokenRepository(ctrl)
+			mfaService := mfa.NewMockMFAService(ctrl)
+			tc.setupMock(userRepository, tokens)
+			mfaService.EXPECT().Check(userID, tc.inputParam.Code).Return(tc.mfaError).AnyTimes()
+			lockoutService := lockout.NewMockLockoutService(ctrl)
+			lockoutService.EXPECT().Check(lockout.LoginKey(tc.inputParam.Email)).Return(tc.lockoutError)
+			lockoutService.EXPECT().Fail(lockout.LoginKey(tc.inputParam.Email)).Return(nil).Times(tc.failures)
+			lockoutService.EXPECT().Reset(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewAuthService(userRepository, nil, tokens, NewMockPasswordResetRepository(ctrl), mailer.NewMockMailer(ctrl), mfaService, lockoutService, testPasswords, testConfig)
+
+			result, err := service.Login(tc.inputParam)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil {
+				var claims AccessClaims
+				_, err := jwt.ParseWithClaims(result.AccessToken, &claims, func(token *jwt.Token) (interface{}, error) {
+					return []byte(testConfig.JWTSecret), nil
+				})
+				if err != nil || claims.Subject != userID {
+					t.Errorf("Expecting access token for %s , but returns %v", userID, err)
+				}
+				if result.RefreshToken == "" || result.TokenType != BEARER_TOKEN_TYPE {
+					t.Errorf("Expecting refresh token , but returns %v", result)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceRefresh(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	const refreshToken string = "refresh-token"
+
+	activeToken := RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserID:    userID,
+		Family:    "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expiredToken := activeToken
+	expiredToken.ExpiresAt = time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		setupMock     func(users *users.MockUserRepository, tokens *MockRefreshTokenRepository)
+		expectedError error
+	}{
+		{
+			name: "refresh success",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(hashToken(refreshToken)).Return(&activeToken, nil)
+				tokens.EXPECT().RevokeRefreshToken(hashToken(refreshToken)).Return(true, nil)
+				userRepository.EXPECT().FindUserByID(userID, gomock.Any()).Return(&users.User{ID: userID}, nil)
+				tokens.
+					EXPECT().
+					InsertRefreshToken(gomock.Any()).
+					DoAndReturn(func(token RefreshToken) error {
+						if token.Family != "family" || token.UserID != userID || token.TokenHash == hashToken(refreshToken) {
+							t.Errorf("Expecting new token in the same family , but returns %v", token)
+						}
+						return nil
+					})
+			},
+			expectedError: nil,
+		},
+		{
+			name: "unknown token",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(nil, nil)
+			},
+			expectedError: &authServiceError{code: REFRESH_TOKEN_INVALID},
+		},
+		{
+			name: "expired token",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(&expiredToken, nil)
+			},
+			expectedError: &authServiceError{code: REFRESH_TOKEN_INVALID},
+		},
+		{
+			name: "reused token revokes family",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(&activeToken, nil)
+				tokens.EXPECT().RevokeRefreshToken(gomock.Any()).Return(false, nil)
+				tokens.EXPECT().RevokeFamily("family").Return(nil)
+			},
+			expectedError: &authServiceError{code: REFRESH_TOKEN_INVALID},
+		},
+		{
+			name: "user deleted",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(&activeToken, nil)
+				tokens.EXPECT().RevokeRefreshToken(gomock.Any()).Return(true, nil)
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+			expectedError: &authServiceError{code: REFRESH_TOKEN_INVALID},
+		},
+		{
+			name: "find token failed",
+			setupMock: func(userRepository *users.MockUserRepository, tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(nil, fmt.Errorf("Any Error"))
+			},
+			expectedError: &authServiceError{code: REFRESH_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			userRepository := users.NewMockUserRepository(ctrl)
+			tokens := NewMockRefreshTokenRepository(ctrl)
+			tc.setupMock(userRepository, tokens)
+
+			service := NewAuthService(userRepository, nil, tokens, NewMockPasswordResetRepository(ctrl), mailer.NewMockMailer(ctrl), mfa.NewMockMFAService(ctrl), lockout.NewMockLockoutService(ctrl), testPasswords, testConfig)
+
+			_, err := service.Refresh(refreshToken)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceLogout(t *testing.T) {
+
+	const refreshToken string = "refresh-token"
+
+	tests := []struct {
+		name          string
+		setupMock     func(tokens *MockRefreshTokenRepository)
+		expectedError error
+	}{
+		{
+			name: "logout success",
+			setupMock: func(tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(hashToken(refreshToken)).Return(&RefreshToken{Family: "family"}, nil)
+				tokens.EXPECT().RevokeFamily("family").Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "unknown token",
+			setupMock: func(tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(nil, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "revoke failed",
+			setupMock: func(tokens *MockRefreshTokenRepository) {
+				tokens.EXPECT().FindRefreshToken(gomock.Any()).Return(&RefreshToken{Family: "family"}, nil)
+				tokens.EXPECT().RevokeFamily(gomock.Any()).Return(fmt.Errorf("Any Error"))
+			},
+			expectedError: &authServiceError{code: LOGOUT_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			tokens := NewMockRefreshTokenRepository(ctrl)
+			tc.setupMock(tokens)
+
+			service := NewAuthService(users.NewMockUserRepository(ctrl), nil, tokens, NewMockPasswordResetRepository(ctrl), mailer.NewMockMailer(ctrl), mfa.NewMockMFAService(ctrl), lockout.NewMockLockoutService(ctrl), testPasswords, testConfig)
+
+			err := service.Logout(refreshToken)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+	"userapi/users"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Returns a random URL safe token with n random bytes
+func randomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Returns the hash stored in place of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (svc *authService) signAccessToken(user users.User, now time.Time) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.ID,
+			Issuer:    svc.config.JWTIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(svc.config.AccessTokenTTL)),
+		},
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(svc.config.JWTSecret))
+}
+
+// Issues a new access token and a new refresh token in the refresh token family
+func (svc *authService) issueTokens(user users.User, family string) (*TokenResponse, error) {
+	now := time.Now().UTC()
+
+	accessToken, err := svc.signAccessToken(user, now)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.tokens.InsertRefreshToken(RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserID:    user.ID,
+		Family:    family,
+		CreatedAt: now,
+		ExpiresAt: now.Add(svc.config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    BEARER_TOKEN_TYPE,
+		ExpiresIn:    int64(svc.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
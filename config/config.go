@@ -5,30 +5,117 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Port            int
-	DBURI           string
-	Database        string
-	RateLimit       int
-	RateLimitTokens int
-	ApiUser         string
-	ApiPass         string
-	ApiHost         string
+	Port                 int
+	DBURI                string
+	Database             string
+	RateLimit            int
+	RateLimitTokens      int
+	ApiUser              string
+	ApiPass              string
+	ApiRoles             []string
+	CredentialsFile      string
+	DevMode              bool
+	ApiHost              string
+	PageSize             int
+	MaxPageSize          int
+	JWTSecret            string
+	JWTIssuer            string
+	JWKSFile             string
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	ResetTokenTTL        time.Duration
+	ResetURL             string
+	MailFile             string
+	VerificationTokenTTL time.Duration
+	VerificationURL      string
+	RequireVerifiedEmail []string
+	MFAEncryptionKey     string
+	MFAIssuer            string
+	LockoutThreshold     int
+	LockoutDuration      time.Duration
+	LockoutBackoff       time.Duration
+	PasswordMinLength    int
+	PasswordMaxLength    int
+	PasswordClasses      int
+	PasswordBannedFile   string
+	RejectLegacyAge      bool
+	MigrationLockTTL     time.Duration
+	DeletedRetention     time.Duration
+	PurgeInterval        time.Duration
+	WebhookTimeout       time.Duration
+	WebhookMaxAttempts   int
+	WebhookBackoff       time.Duration
+	WebhookPollInterval  time.Duration
+	EventPublishers      []string
+	OutboxPollInterval   time.Duration
+	OutboxRetention      time.Duration
+	EventHistory         int
+	ChangeStreams        bool
+	AllowStandalone      bool
 }
 
+// Publishers of user events selectable on EVENT_PUBLISHERS
+const PUBLISHER_WEBHOOK string = "webhook"
+const PUBLISHER_STDOUT string = "stdout"
+const PUBLISHER_MEMORY string = "memory"
+
+var Publishers = []string{PUBLISHER_WEBHOOK, PUBLISHER_STDOUT, PUBLISHER_MEMORY}
+
 func NewConfig() Config {
 	var port int = getIntValue("PORT", 3000)
 	return Config{
-		Port:            port,
-		DBURI:           os.Getenv("MONGODB_URI"),
-		Database:        os.Getenv("MONGODB_DATABASE"),
-		RateLimit:       getIntValue(os.Getenv("RATE_LIMIT"), 1),
-		RateLimitTokens: getIntValue(os.Getenv("RATE_LIMIT_TOKENS"), 5),
-		ApiUser:         getStringValue("API_USER", "apiuser"),
-		ApiPass:         getStringValue("API_PASS", "apipass"),
-		ApiHost:         getStringValue("API_HOST", fmt.Sprintf("localhost:%d", port)),
+		Port:                 port,
+		DBURI:                os.Getenv("MONGODB_URI"),
+		Database:             os.Getenv("MONGODB_DATABASE"),
+		RateLimit:            getIntValue(os.Getenv("RATE_LIMIT"), 1),
+		RateLimitTokens:      getIntValue(os.Getenv("RATE_LIMIT_TOKENS"), 5),
+		ApiUser:              getStringValue("API_USER", DEFAULT_API_USER),
+		ApiPass:              getStringValue("API_PASS", DEFAULT_API_PASS),
+		ApiRoles:             getListValue("API_ROLES", []string{"admin"}),
+		CredentialsFile:      os.Getenv("CREDENTIALS_FILE"),
+		DevMode:              getBoolValue("DEV_MODE", false),
+		ApiHost:              getStringValue("API_HOST", fmt.Sprintf("localhost:%d", port)),
+		PageSize:             getIntValue("PAGE_SIZE", 20),
+		MaxPageSize:          getIntValue("MAX_PAGE_SIZE", 100),
+		JWTSecret:            os.Getenv("JWT_SECRET"),
+		JWTIssuer:            getStringValue("JWT_ISSUER", "userapi"),
+		JWKSFile:             os.Getenv("JWKS_FILE"),
+		AccessTokenTTL:       getDurationValue("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      getDurationValue("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		ResetTokenTTL:        getDurationValue("RESET_TOKEN_TTL", time.Hour),
+		ResetURL:             os.Getenv("RESET_URL"),
+		MailFile:             os.Getenv("MAIL_FILE"),
+		VerificationTokenTTL: getDurationValue("VERIFICATION_TOKEN_TTL", 48*time.Hour),
+		VerificationURL:      os.Getenv("VERIFICATION_URL"),
+		RequireVerifiedEmail: getListValue("REQUIRE_VERIFIED_EMAIL", []string{}),
+		MFAEncryptionKey:     os.Getenv("MFA_ENCRYPTION_KEY"),
+		MFAIssuer:            getStringValue("MFA_ISSUER", "User API"),
+		LockoutThreshold:     getIntValue("LOCKOUT_THRESHOLD", 5),
+		LockoutDuration:      getDurationValue("LOCKOUT_DURATION", 15*time.Minute),
+		LockoutBackoff:       getDurationValue("LOCKOUT_BACKOFF", time.Second),
+		PasswordMinLength:    getIntValue("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:    getIntValue("PASSWORD_MAX_LENGTH", BCRYPT_MAX_PASSWORD_BYTES),
+		PasswordClasses:      getIntValue("PASSWORD_CLASSES", 3),
+		PasswordBannedFile:   os.Getenv("PASSWORD_BANNED_FILE"),
+		RejectLegacyAge:      getBoolValue("REJECT_LEGACY_AGE", false),
+		MigrationLockTTL:     getDurationValue("MIGRATION_LOCK_TTL", 30*time.Minute),
+		DeletedRetention:     getDurationValue("DELETED_RETENTION", 30*24*time.Hour),
+		PurgeInterval:        getDurationValue("PURGE_INTERVAL", time.Hour),
+		WebhookTimeout:       getDurationValue("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:   getIntValue("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoff:       getDurationValue("WEBHOOK_BACKOFF", 30*time.Second),
+		WebhookPollInterval:  getDurationValue("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+		EventPublishers:      getListValue("EVENT_PUBLISHERS", []string{PUBLISHER_WEBHOOK}),
+		OutboxPollInterval:   getDurationValue("OUTBOX_POLL_INTERVAL", time.Second),
+		OutboxRetention:      getDurationValue("OUTBOX_RETENTION", 24*time.Hour),
+		EventHistory:         getIntValue("EVENT_HISTORY", 1000),
+		ChangeStreams:        getBoolValue("CHANGE_STREAMS", false),
+		AllowStandalone:      getBoolValue("ALLOW_STANDALONE_MONGODB", false),
 	}
 }
 
@@ -42,6 +129,73 @@ func (c *Config) Validate() {
 		fmt.Println("Invalid MONGODB_DATABASE environment variable")
 		os.Exit(0)
 	}
+
+	if len(c.JWTSecret) < 32 {
+		fmt.Println("Invalid JWT_SECRET environment variable, it must have at least 32 characters")
+		os.Exit(0)
+	}
+
+	if len(c.MFAEncryptionKey) < 32 {
+		fmt.Println("Invalid MFA_ENCRYPTION_KEY environment variable, it must have at least 32 characters")
+		os.Exit(0)
+	}
+
+	if c.LockoutThreshold <= 0 || c.LockoutDuration <= 0 {
+		fmt.Println("Invalid LOCKOUT_THRESHOLD or LOCKOUT_DURATION environment variable, they must be positive")
+		os.Exit(0)
+	}
+
+	if c.PasswordMinLength < 1 || c.PasswordMaxLength > BCRYPT_MAX_PASSWORD_BYTES || c.PasswordMinLength > c.PasswordMaxLength {
+		fmt.Printf("Invalid PASSWORD_MIN_LENGTH or PASSWORD_MAX_LENGTH environment variable, they must be between 1 and %d \n", BCRYPT_MAX_PASSWORD_BYTES)
+		os.Exit(0)
+	}
+
+	if c.DeletedRetention <= 0 || c.PurgeInterval <= 0 {
+		fmt.Println("Invalid DELETED_RETENTION or PURGE_INTERVAL environment variable, they must be positive")
+		os.Exit(0)
+	}
+
+	if c.WebhookTimeout <= 0 || c.WebhookMaxAttempts <= 0 || c.WebhookBackoff <= 0 || c.WebhookPollInterval <= 0 {
+		fmt.Println("Invalid WEBHOOK_TIMEOUT, WEBHOOK_MAX_ATTEMPTS, WEBHOOK_BACKOFF or WEBHOOK_POLL_INTERVAL environment variable, they must be positive")
+		os.Exit(0)
+	}
+
+	if c.OutboxPollInterval <= 0 || c.OutboxRetention <= 0 {
+		fmt.Println("Invalid OUTBOX_POLL_INTERVAL or OUTBOX_RETENTION environment variable, they must be positive")
+		os.Exit(0)
+	}
+
+	if c.EventHistory <= 0 {
+		fmt.Println("Invalid EVENT_HISTORY environment variable, it must be positive")
+		os.Exit(0)
+	}
+
+	for _, publisher := range c.EventPublishers {
+		if !contains(Publishers, publisher) {
+			fmt.Printf("Invalid EVENT_PUBLISHERS environment variable, %s is not one of %s \n", publisher, strings.Join(Publishers, ", "))
+			os.Exit(0)
+		}
+	}
+
+	if c.PasswordClasses < 0 || c.PasswordClasses > 4 {
+		fmt.Println("Invalid PASSWORD_CLASSES environment variable, it must be between 0 and 4")
+		os.Exit(0)
+	}
+
+	if _, err := c.LoadBannedPasswords(); err != nil {
+		fmt.Println(fmt.Errorf("Invalid PASSWORD_BANNED_FILE environment variable : %v", err))
+		os.Exit(0)
+	}
+
+	if c.CredentialsFile == "" && !c.DevMode && (c.ApiUser == DEFAULT_API_USER || c.ApiPass == DEFAULT_API_PASS) {
+		fmt.Println("Refusing to start with default API_USER/API_PASS, set them, set CREDENTIALS_FILE or set DEV_MODE=true")
+		os.Exit(0)
+	}
+
+	if _, err := c.LoadAccounts(); err != nil {
+		fmt.Println(fmt.Errorf("Invalid CREDENTIALS_FILE environment variable : %v", err))
+		os.Exit(0)
+	}
 }
 
 func getIntValue(envName string, defaultValue int) int {
@@ -59,3 +213,42 @@ func getStringValue(envName string, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getBoolValue(envName string, defaultValue bool) bool {
+	valueStr := os.Getenv(envName)
+	if valueBool, err := strconv.ParseBool(valueStr); err == nil {
+		return valueBool
+	}
+	return defaultValue
+}
+
+func getDurationValue(envName string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(envName)
+	if valueDuration, err := time.ParseDuration(valueStr); err == nil {
+		return valueDuration
+	}
+	return defaultValue
+}
+
+func getListValue(envName string, defaultValue []string) []string {
+	valueStr := os.Getenv(envName)
+	if valueStr == "" {
+		return defaultValue
+	}
+	values := make([]string, 0)
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const DEFAULT_API_USER string = "apiuser"
+const DEFAULT_API_PASS string = "apipass"
+
+// Basic auth account, the password is kept as a bcrypt hash
+type Account struct {
+	User         string   `yaml:"user"`
+	PasswordHash string   `yaml:"password"`
+	Roles        []string `yaml:"roles"`
+}
+
+// Basic auth accounts by user
+type Accounts map[string]Account
+
+type credentialsFile struct {
+	Accounts []Account `yaml:"accounts"`
+}
+
+/*
+Returns the Basic auth accounts.
+
+When CREDENTIALS_FILE is set, accounts are read from the file, otherwise
+the single API_USER/API_PASS account is returned. Accounts without roles
+hold the roles on API_ROLES.
+*/
+func (c Config) LoadAccounts() (Accounts, error) {
+	if c.CredentialsFile == "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(c.ApiPass), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		return Accounts{c.ApiUser: {User: c.ApiUser, PasswordHash: string(hash), Roles: c.ApiRoles}}, nil
+	}
+
+	content, err := os.ReadFile(c.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+	switch strings.ToLower(filepath.Ext(c.CredentialsFile)) {
+	case ".yaml", ".yml":
+		accounts, err = parseYAMLCredentials(content)
+	default:
+		accounts, err = parseHtpasswd(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s : %v", c.CredentialsFile, err)
+	}
+
+	result := make(Accounts, len(accounts))
+	for _, account := range accounts {
+		if account.User == "" {
+			return nil, fmt.Errorf("invalid credentials file %s : account without user", c.CredentialsFile)
+		}
+		if _, err := bcrypt.Cost([]byte(account.PasswordHash)); err != nil {
+			return nil, fmt.Errorf("invalid credentials file %s : password of %s is not a bcrypt hash", c.CredentialsFile, account.User)
+		}
+		if _, exists := result[account.User]; exists {
+			return nil, fmt.Errorf("invalid credentials file %s : duplicated user %s", c.CredentialsFile, account.User)
+		}
+		if len(account.Roles) == 0 {
+			account.Roles = c.ApiRoles
+		}
+		result[account.User] = account
+	}
+	return result, nil
+}
+
+// Parses YAML credentials, a list of accounts with user, bcrypt password hash and roles
+func parseYAMLCredentials(content []byte) ([]Account, error) {
+	var file credentialsFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	return file.Accounts, nil
+}
+
+// Parses htpasswd credentials, one `user:hash` per line, as written by `htpasswd -B`
+func parseHtpasswd(content []byte) ([]Account, error) {
+	accounts := make([]Account, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		user, hash, ok := strings.Cut(text, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d is not user:hash", line)
+		}
+		accounts = append(accounts, Account{User: user, PasswordHash: hash})
+	}
+	return accounts, scanner.Err()
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoadAccounts(t *testing.T) {
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	tests := []struct {
+		name             string
+		fileName         string
+		content          string
+		expectedAccounts string
+		expectedError    bool
+	}{
+		{
+			name:             "htpasswd file",
+			fileName:         "htpasswd",
+			content:          fmt.Sprintf("# service accounts\nbilling:%s\n\nreports:%s\n", hash, hash),
+			expectedAccounts: "billing:[admin] reports:[admin]",
+		},
+		{
+			name:     "yaml file",
+			fileName: "credentials.yaml",
+			content: fmt.Sprintf(`accounts:
+  - user: billing
+    password: "%s"
+    roles: [support]
+  - user: reports
+    password: "%s"
+`, hash, hash),
+			expectedAccounts: "billing:[support] reports:[admin]",
+		},
+		{
+			name:          "plaintext password",
+			fileName:      "htpasswd",
+			content:       "billing:secret\n",
+			expectedError: true,
+		},
+		{
+			name:          "malformed line",
+			fileName:      "htpasswd",
+			content:       "billing\n",
+			expectedError: true,
+		},
+		{
+			name:          "duplicated user",
+			fileName:      "htpasswd",
+			content:       fmt.Sprintf("billing:%s\nbilling:%s\n", hash, hash),
+			expectedError: true,
+		},
+		{
+			name:          "invalid yaml",
+			fileName:      "credentials.yml",
+			content:       "accounts: {",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			file := filepath.Join(tu.TempDir(), tc.fileName)
+			if err := os.WriteFile(file, []byte(tc.content), 0600); err != nil {
+				tu.Fatalf("Error writing credentials file : %v", err)
+			}
+
+			c := Config{CredentialsFile: file, ApiRoles: []string{"admin"}}
+			accounts, err := c.LoadAccounts()
+
+			if (err != nil) != tc.expectedError {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil {
+				result := fmt.Sprintf("billing:%v reports:%v", accounts["billing"].Roles, accounts["reports"].Roles)
+				if result != tc.expectedAccounts || len(accounts) != 2 {
+					t.Errorf("Expecting accounts %s , but returns %s", tc.expectedAccounts, result)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAccountsFromEnv(t *testing.T) {
+	c := Config{ApiUser: "apiuser", ApiPass: "changed", ApiRoles: []string{"admin"}}
+
+	accounts, err := c.LoadAccounts()
+	if err != nil {
+		t.Fatalf("Error on LoadAccounts : %v", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(accounts["apiuser"].PasswordHash), []byte("changed")); err != nil {
+		t.Errorf("Expecting API_PASS hashed , but returns %v", err)
+	}
+}
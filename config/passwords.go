@@ -0,0 +1,42 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Passwords longer than this are truncated by bcrypt, so they are refused
+const BCRYPT_MAX_PASSWORD_BYTES int = 72
+
+/*
+Returns the banned passwords of PASSWORD_BANNED_FILE, lower cased.
+
+The file has one password per line, blank lines and lines starting
+with # are ignored. Returns no passwords when the file is not set.
+*/
+func (c Config) LoadBannedPasswords() ([]string, error) {
+	if c.PasswordBannedFile == "" {
+		return []string{}, nil
+	}
+
+	file, err := os.Open(c.PasswordBannedFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	passwords := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, strings.ToLower(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return passwords, nil
+}
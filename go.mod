@@ -3,7 +3,9 @@ module userapi
 go 1.18
 
 require (
+	github.com/evanphx/json-patch/v5 v5.6.0
 	github.com/gin-gonic/gin v1.9.0
+	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/golang/mock v1.6.0
 	github.com/swaggo/files v1.0.1
 	github.com/swaggo/gin-swagger v1.6.0
@@ -11,6 +13,7 @@ require (
 	go.mongodb.org/mongo-driver v1.11.3
 	golang.org/x/crypto v0.7.0
 	golang.org/x/time v0.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -55,5 +58,4 @@ require (
 	golang.org/x/tools v0.7.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
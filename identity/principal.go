@@ -0,0 +1,36 @@
+// This package carries the authenticated principal and the request ID through request context
+package identity
+
+import "github.com/gin-gonic/gin"
+
+const principalKey string = "principal"
+
+const BASIC_METHOD string = "basic"
+const BEARER_METHOD string = "bearer"
+const API_KEY_METHOD string = "apikey"
+
+// Authenticated caller of a request
+type Principal struct {
+	ID     string
+	Method string
+	Roles  []string
+	// Permissions granted to API keys, in place of roles
+	Scopes []string
+	// Tells if the user email is verified, only known for bearer tokens
+	EmailVerified bool
+}
+
+// Puts the authenticated principal in request context
+func SetPrincipal(c *gin.Context, principal Principal) {
+	c.Set(principalKey, principal)
+}
+
+// Returns the authenticated principal from request context, if any
+func GetPrincipal(c *gin.Context) (Principal, bool) {
+	value, exists := c.Get(principalKey)
+	if !exists {
+		return Principal{}, false
+	}
+	principal, ok := value.(Principal)
+	return principal, ok
+}
@@ -0,0 +1,18 @@
+package identity
+
+import "github.com/gin-gonic/gin"
+
+const requestIDKey string = "requestID"
+
+// Header carrying the request ID, received from callers or generated, and returned on responses
+const REQUEST_ID_HEADER string = "X-Request-ID"
+
+// Puts the request ID in request context
+func SetRequestID(c *gin.Context, requestID string) {
+	c.Set(requestIDKey, requestID)
+}
+
+// Returns the request ID from request context, empty when there is none
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
@@ -0,0 +1,49 @@
+package lockout
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// Controller containing all lockout request handlers
+type LockoutController struct {
+	service LockoutService
+}
+
+// Returns new LockoutController instance
+func NewLockoutController(service LockoutService) LockoutController {
+	return LockoutController{
+		service: service,
+	}
+}
+
+// UnlockUser godoc
+//
+//	@Summary		Unlock user login
+//	@Description	This endpoint forgets the failed logins of a user, unlocking its login right away.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Success		200		{object}	LockoutResponse
+//	@Failure		401
+//	@Failure		403		{object}	LockoutResponse
+//	@Failure		400		{object}	LockoutResponse
+//	@Failure		404		{object}	LockoutResponse
+//	@Failure		502		{object}	LockoutResponse
+//	@Router			/users/{id}/unlock [post]
+func (ctr LockoutController) UnlockUser(c *gin.Context) {
+	err := ctr.service.UnlockUser(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case USER_ID_INVALID:
+			c.JSON(400, INVALID_USER_ID)
+		case USER_NOT_EXISTS:
+			c.JSON(404, USER_NOT_FOUND)
+		default:
+			c.JSON(502, USER_UNLOCK_FAILED)
+		}
+		return
+	}
+
+	c.JSON(200, USER_UNLOCKED)
+}
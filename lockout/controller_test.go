@@ -0,0 +1,75 @@
+package lockout
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestUnlockUserController(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockLockoutService)
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "unlock success",
+			setupMock: func(service *MockLockoutService) {
+				service.EXPECT().UnlockUser(userID).Return(nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"User Unlocked","code":"USER_UNLOCKED"}`,
+		},
+		{
+			name: "user not found",
+			setupMock: func(service *MockLockoutService) {
+				service.EXPECT().UnlockUser(gomock.Any()).Return(&lockoutServiceError{code: USER_NOT_EXISTS})
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Not Found","code":"USER_NOT_FOUND"}`,
+		},
+		{
+			name: "unlock failed",
+			setupMock: func(service *MockLockoutService) {
+				service.EXPECT().UnlockUser(gomock.Any()).Return(&lockoutServiceError{code: LOCKOUT_FAILED})
+			},
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"User Unlock Failed","code":"USER_UNLOCK_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockLockoutService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewLockoutController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/unlock", controller.UnlockUser)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/unlock", userID), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./lockout/repository.go
+
+// Package lockout is a generated GoMock package.
+package lockout
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockAttemptRepository is a mock of AttemptRepository interface.
+type MockAttemptRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAttemptRepositoryMockRecorder
+}
+
+// MockAttemptRepositoryMockRecorder is the mock recorder for MockAttemptRepository.
+type MockAttemptRepositoryMockRecorder struct {
+	mock *MockAttemptRepository
+}
+
+// NewMockAttemptRepository creates a new mock instance.
+func NewMockAttemptRepository(ctrl *gomock.Controller) *MockAttemptRepository {
+	mock := &MockAttemptRepository{ctrl: ctrl}
+	mock.recorder = &MockAttemptRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAttemptRepository) EXPECT() *MockAttemptRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AddFailure mocks base method.
+func (m *MockAttemptRepository) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddFailure", key, now, window)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddFailure indicates an expected call of AddFailure.
+func (mr *MockAttemptRepositoryMockRecorder) AddFailure(key, now, window interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFailure", reflect.TypeOf((*MockAttemptRepository)(nil).AddFailure), key, now, window)
+}
+
+// DeleteAttempts mocks base method.
+func (m *MockAttemptRepository) DeleteAttempts(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAttempts", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAttempts indicates an expected call of DeleteAttempts.
+func (mr *MockAttemptRepositoryMockRecorder) DeleteAttempts(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAttempts", reflect.TypeOf((*MockAttemptRepository)(nil).DeleteAttempts), key)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockAttemptRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockAttemptRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockAttemptRepository)(nil).EnsureIndexes))
+}
+
+// FindAttempts mocks base method.
+func (m *MockAttemptRepository) FindAttempts(key string) (*Attempts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAttempts", key)
+	ret0, _ := ret[0].(*Attempts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindAttempts indicates an expected call of FindAttempts.
+func (mr *MockAttemptRepositoryMockRecorder) FindAttempts(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAttempts", reflect.TypeOf((*MockAttemptRepository)(nil).FindAttempts), key)
+}
+
+// Lock mocks base method.
+func (m *MockAttemptRepository) Lock(key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockAttemptRepositoryMockRecorder) Lock(key, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockAttemptRepository)(nil).Lock), key, until)
+}
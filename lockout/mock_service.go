@@ -0,0 +1,90 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./lockout/service.go
+
+// Package lockout is a generated GoMock package.
+package lockout
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLockoutService is a mock of LockoutService interface.
+type MockLockoutService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockoutServiceMockRecorder
+}
+
+// MockLockoutServiceMockRecorder is the mock recorder for MockLockoutService.
+type MockLockoutServiceMockRecorder struct {
+	mock *MockLockoutService
+}
+
+// NewMockLockoutService creates a new mock instance.
+func NewMockLockoutService(ctrl *gomock.Controller) *MockLockoutService {
+	mock := &MockLockoutService{ctrl: ctrl}
+	mock.recorder = &MockLockoutServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLockoutService) EXPECT() *MockLockoutServiceMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockLockoutService) Check(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockLockoutServiceMockRecorder) Check(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockLockoutService)(nil).Check), key)
+}
+
+// Fail mocks base method.
+func (m *MockLockoutService) Fail(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLockoutServiceMockRecorder) Fail(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLockoutService)(nil).Fail), key)
+}
+
+// Reset mocks base method.
+func (m *MockLockoutService) Reset(key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reset", key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLockoutServiceMockRecorder) Reset(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLockoutService)(nil).Reset), key)
+}
+
+// UnlockUser mocks base method.
+func (m *MockLockoutService) UnlockUser(userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockLockoutServiceMockRecorder) UnlockUser(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockLockoutService)(nil).UnlockUser), userID)
+}
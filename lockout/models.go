@@ -0,0 +1,30 @@
+package lockout
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Failed attempts of an account, the account is locked while LockedUntil is after now
+type Attempts struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"lastFailure"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
+
+// Returned while the account is locked, telling when it may be tried again
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return ACCOUNT_LOCKED
+}
+
+// Returns the value of the Retry-After header, in whole seconds rounded up
+func (e *LockedError) RetryAfterSeconds() string {
+	return fmt.Sprint(int64(math.Ceil(e.RetryAfter.Seconds())))
+}
@@ -0,0 +1,190 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const attemptCollection string = "login_attempts"
+
+type AttemptRepository interface {
+	FindAttempts(key string) (*Attempts, error)
+	// Counts a failure at now, restarting the count when the last failure is older than window.
+	// Returns the failures counted.
+	AddFailure(key string, now time.Time, window time.Duration) (int, error)
+	// Locks the account until the given time
+	Lock(key string, until time.Time) error
+	DeleteAttempts(key string) error
+	EnsureIndexes() error
+}
+
+type attemptRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+// Returns the AttemptRepository shared by all instances of the API, used for user logins
+func NewAttemptRepository(client *mongo.Client, database string) AttemptRepository {
+	return &attemptRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *attemptRepository) FindAttempts(key string) (*Attempts, error) {
+	coll := repo.client.Database(repo.database).Collection(attemptCollection)
+	filter := bson.M{"_id": bson.M{"$eq": key}}
+
+	var attempts Attempts
+	err := coll.FindOne(context.Background(), filter).Decode(&attempts)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func (repo *attemptRepository) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	coll := repo.client.Database(repo.database).Collection(attemptCollection)
+	filter := bson.M{"_id": bson.M{"$eq": key}}
+
+	// Pipeline update, so the count restarts atomically when the last failure is out of the window
+	recent := bson.D{{Key: "$gte", Value: bson.A{"$lastFailure", now.Add(-window)}}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "failures", Value: bson.D{{Key: "$cond", Value: bson.A{recent, bson.D{{Key: "$add", Value: bson.A{"$failures", 1}}}, 1}}}},
+		{Key: "lastFailure", Value: now},
+		{Key: "expiresAt", Value: bson.D{{Key: "$max", Value: bson.A{"$expiresAt", now.Add(window)}}}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempts Attempts
+	if err := coll.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&attempts); err != nil {
+		return 0, err
+	}
+	return attempts.Failures, nil
+}
+
+func (repo *attemptRepository) Lock(key string, until time.Time) error {
+	coll := repo.client.Database(repo.database).Collection(attemptCollection)
+	filter := bson.M{"_id": bson.M{"$eq": key}}
+	update := bson.M{"$max": bson.M{"lockedUntil": until, "expiresAt": until}}
+
+	_, err := coll.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+func (repo *attemptRepository) DeleteAttempts(key string) error {
+	coll := repo.client.Database(repo.database).Collection(attemptCollection)
+	_, err := coll.DeleteOne(context.Background(), bson.M{"_id": bson.M{"$eq": key}})
+	return err
+}
+
+func (repo *attemptRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(attemptCollection)
+	_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+type memoryAttemptRepository struct {
+	mutex    sync.Mutex
+	attempts map[string]*Attempts
+}
+
+// Returns an AttemptRepository kept in memory of this instance, used for Basic auth accounts
+func NewMemoryAttemptRepository() AttemptRepository {
+	return &memoryAttemptRepository{attempts: make(map[string]*Attempts)}
+}
+
+func (repo *memoryAttemptRepository) FindAttempts(key string) (*Attempts, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	attempts := repo.find(key, time.Now())
+	if attempts == nil {
+		return nil, nil
+	}
+	found := *attempts
+	return &found, nil
+}
+
+func (repo *memoryAttemptRepository) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	attempts := repo.find(key, now)
+	if attempts == nil {
+		attempts = &Attempts{Key: key}
+		repo.attempts[key] = attempts
+	}
+	if attempts.LastFailure.Before(now.Add(-window)) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	if expiresAt := now.Add(window); expiresAt.After(attempts.ExpiresAt) {
+		attempts.ExpiresAt = expiresAt
+	}
+	return attempts.Failures, nil
+}
+
+func (repo *memoryAttemptRepository) Lock(key string, until time.Time) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	if attempts, exists := repo.attempts[key]; exists {
+		if until.After(attempts.LockedUntil) {
+			attempts.LockedUntil = until
+		}
+		if until.After(attempts.ExpiresAt) {
+			attempts.ExpiresAt = until
+		}
+	}
+	return nil
+}
+
+func (repo *memoryAttemptRepository) DeleteAttempts(key string) error {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	delete(repo.attempts, key)
+	return nil
+}
+
+func (repo *memoryAttemptRepository) EnsureIndexes() error {
+	return nil
+}
+
+// Returns the attempts of key, dropping them once expired as the TTL index does. Must hold mutex.
+func (repo *memoryAttemptRepository) find(key string, now time.Time) *Attempts {
+	attempts, exists := repo.attempts[key]
+	if !exists {
+		return nil
+	}
+	if !attempts.ExpiresAt.After(now) {
+		delete(repo.attempts, key)
+		return nil
+	}
+	return attempts
+}
+
+// Drops the attempts expired at now, of keys not looked up since they expired
+func (repo *memoryAttemptRepository) sweep(now time.Time) {
+	repo.mutex.Lock()
+	defer repo.mutex.Unlock()
+
+	for key, attempts := range repo.attempts {
+		if !attempts.ExpiresAt.After(now) {
+			delete(repo.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package lockout
+
+type LockoutResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var INVALID_USER_ID LockoutResponse = LockoutResponse{
+	Message: "Invalid User ID",
+	Code:    "INVALID_USER_ID",
+}
+
+var USER_NOT_FOUND LockoutResponse = LockoutResponse{
+	Message: "User Not Found",
+	Code:    "USER_NOT_FOUND",
+}
+
+var USER_UNLOCKED LockoutResponse = LockoutResponse{
+	Message: "User Unlocked",
+	Code:    "USER_UNLOCKED",
+}
+
+var USER_UNLOCK_FAILED LockoutResponse = LockoutResponse{
+	Message: "User Unlock Failed",
+	Code:    "USER_UNLOCK_FAILED",
+}
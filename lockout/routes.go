@@ -0,0 +1,34 @@
+// Lockout module containing Controllers, Services e Repositories.
+// Module responsable for throttling and locking accounts after failed attempts
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Method to add routes in api (gin.RouterGroup), using config (config.Config)
+// and client (mongo.Client)
+func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client) {
+	var attemptRepository AttemptRepository = NewAttemptRepository(client, config.Database)
+	var userRepository users.UserRepository = users.NewUserRepository(client, config.Database)
+	var lockoutService LockoutService = NewLockoutService(attemptRepository, userRepository, config)
+	var lockoutController LockoutController = NewLockoutController(lockoutService)
+
+	if err := attemptRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+
+	api.POST("/users/:id/unlock", users.Authorize(users.PERMISSION_UNLOCK, false), lockoutController.UnlockUser)
+}
+
+// Method to start dropping expired attempts of repository (AttemptRepository) in background,
+// using config (config.Config), until ctx is done. Attempts expire after LOCKOUT_DURATION at least.
+func StartSweeper(ctx context.Context, config config.Config, repository AttemptRepository) {
+	go RunSweeper(ctx, repository, config.LockoutDuration)
+}
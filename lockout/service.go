@@ -0,0 +1,163 @@
+package lockout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"userapi/config"
+	"userapi/users"
+)
+
+type LockoutService interface {
+	/*
+		Method to check if an account may be tried
+
+		Parameters
+
+		key: Account key, see LoginKey and BasicKey.
+
+		Returns a LockedError while the account is locked.
+	*/
+	Check(key string) error
+	/*
+		Method to count a failed attempt, delaying the next attempts progressively
+		and locking the account once LOCKOUT_THRESHOLD failures are counted
+
+		Parameters
+
+		key: Account key, see LoginKey and BasicKey.
+	*/
+	Fail(key string) error
+	/*
+		Method to forget the failed attempts of an account, after a successful attempt
+
+		Parameters
+
+		key: Account key, see LoginKey and BasicKey.
+	*/
+	Reset(key string) error
+	/*
+		Method to unlock the login of a user
+
+		Parameters
+
+		userID: User ID to find user data.
+	*/
+	UnlockUser(userID string) error
+}
+
+type lockoutServiceError struct {
+	code string
+}
+
+func (e *lockoutServiceError) Error() string {
+	return e.code
+}
+
+const ACCOUNT_LOCKED string = "ACCOUNT_LOCKED"
+const LOCKOUT_FAILED string = "LOCKOUT_FAILED"
+const USER_ID_INVALID string = "USER_ID_INVALID"
+const USER_NOT_EXISTS string = "USER_NOT_EXISTS"
+
+// Returns the key counting failed logins of the email, also for unknown emails,
+// so locked accounts do not reveal registered emails
+func LoginKey(email string) string {
+	return "login:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// Returns the key counting failed Basic auth attempts of the user
+func BasicKey(user string) string {
+	return "basic:" + user
+}
+
+type lockoutService struct {
+	attempts AttemptRepository
+	users    users.UserRepository
+	config   config.Config
+}
+
+// Returns a LockoutService counting attempts on the repository. The user repository is only used by
+// UnlockUser and may be nil when users are not unlocked through the service.
+func NewLockoutService(attempts AttemptRepository, users users.UserRepository, config config.Config) LockoutService {
+	return &lockoutService{
+		attempts: attempts,
+		users:    users,
+		config:   config,
+	}
+}
+
+func (svc *lockoutService) Check(key string) error {
+	attempts, err := svc.attempts.FindAttempts(key)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindAttempts : %v", err))
+		return &lockoutServiceError{code: LOCKOUT_FAILED}
+	}
+
+	if attempts != nil {
+		if wait := time.Until(attempts.LockedUntil); wait > 0 {
+			return &LockedError{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+func (svc *lockoutService) Fail(key string) error {
+	now := time.Now().UTC()
+	failures, err := svc.attempts.AddFailure(key, now, svc.config.LockoutDuration)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on AddFailure : %v", err))
+		return &lockoutServiceError{code: LOCKOUT_FAILED}
+	}
+
+	if wait := svc.delay(failures); wait > 0 {
+		if err := svc.attempts.Lock(key, now.Add(wait)); err != nil {
+			fmt.Println(fmt.Errorf("Error on Lock : %v", err))
+			return &lockoutServiceError{code: LOCKOUT_FAILED}
+		}
+	}
+	return nil
+}
+
+func (svc *lockoutService) Reset(key string) error {
+	if err := svc.attempts.DeleteAttempts(key); err != nil {
+		fmt.Println(fmt.Errorf("Error on DeleteAttempts : %v", err))
+		return &lockoutServiceError{code: LOCKOUT_FAILED}
+	}
+	return nil
+}
+
+func (svc *lockoutService) UnlockUser(userID string) error {
+	user, err := svc.users.FindUserByID(userID, users.Projection{{Key: "email", Value: 1}})
+	if err != nil {
+		if err.Error() == users.INVALID_OBJECT_ID {
+			fmt.Println(fmt.Errorf("Invalid user id : %v", err))
+			return &lockoutServiceError{code: USER_ID_INVALID}
+		}
+		fmt.Println(fmt.Errorf("Error on FindUserByID : %v", err))
+		return &lockoutServiceError{code: LOCKOUT_FAILED}
+	}
+
+	if user == nil {
+		fmt.Println(fmt.Errorf("User not exists"))
+		return &lockoutServiceError{code: USER_NOT_EXISTS}
+	}
+
+	return svc.Reset(LoginKey(user.Email))
+}
+
+// Returns how long the account is locked after the failures: LOCKOUT_BACKOFF doubling on
+// each failure, then LOCKOUT_DURATION once LOCKOUT_THRESHOLD failures are counted
+func (svc *lockoutService) delay(failures int) time.Duration {
+	if failures >= svc.config.LockoutThreshold {
+		return svc.config.LockoutDuration
+	}
+
+	wait := svc.config.LockoutBackoff
+	for i := 1; i < failures && wait < svc.config.LockoutDuration; i++ {
+		wait *= 2
+	}
+	if wait > svc.config.LockoutDuration {
+		wait = svc.config.LockoutDuration
+	}
+	return wait
+}
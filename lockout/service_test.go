@@ -0,0 +1,217 @@
+package lockout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/golang/mock/gomock"
+)
+
+var testConfig config.Config = config.Config{
+	LockoutThreshold: 5,
+	LockoutDuration:  15 * time.Minute,
+	LockoutBackoff:   time.Second,
+}
+
+const key string = "login:test@test.com"
+
+func TestCheck(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		setupMock     func(attempts *MockAttemptRepository)
+		expectedError error
+	}{
+		{
+			name: "no failures",
+			setupMock: func(attempts *MockAttemptRepository) {
+				attempts.EXPECT().FindAttempts(key).Return(nil, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "lock expired",
+			setupMock: func(attempts *MockAttemptRepository) {
+				attempts.EXPECT().FindAttempts(key).Return(&Attempts{Key: key, Failures: 5, LockedUntil: time.Now().Add(-time.Second)}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "locked",
+			setupMock: func(attempts *MockAttemptRepository) {
+				attempts.EXPECT().FindAttempts(key).Return(&Attempts{Key: key, Failures: 5, LockedUntil: time.Now().Add(time.Minute)}, nil)
+			},
+			expectedError: &LockedError{},
+		},
+		{
+			name: "find failed",
+			setupMock: func(attempts *MockAttemptRepository) {
+				attempts.EXPECT().FindAttempts(key).Return(nil, fmt.Errorf("Any Error"))
+			},
+			expectedError: &lockoutServiceError{code: LOCKOUT_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			attempts := NewMockAttemptRepository(ctrl)
+			tc.setupMock(attempts)
+
+			service := NewLockoutService(attempts, nil, testConfig)
+
+			err := service.Check(key)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestFail(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		failures      int
+		expectedDelay time.Duration
+	}{
+		{name: "first failure", failures: 1, expectedDelay: time.Second},
+		{name: "second failure", failures: 2, expectedDelay: 2 * time.Second},
+		{name: "fourth failure", failures: 4, expectedDelay: 8 * time.Second},
+		{name: "threshold reached", failures: 5, expectedDelay: 15 * time.Minute},
+		{name: "after threshold", failures: 9, expectedDelay: 15 * time.Minute},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			attempts := NewMockAttemptRepository(ctrl)
+			attempts.EXPECT().AddFailure(key, gomock.Any(), testConfig.LockoutDuration).Return(tc.failures, nil)
+			attempts.
+				EXPECT().
+				Lock(key, gomock.Any()).
+				DoAndReturn(func(key string, until time.Time) error {
+					if delay := time.Until(until); delay > tc.expectedDelay || delay < tc.expectedDelay-time.Second {
+						t.Errorf("Expecting lock for %v , but returns %v", tc.expectedDelay, delay)
+					}
+					return nil
+				})
+
+			service := NewLockoutService(attempts, nil, testConfig)
+
+			if err := service.Fail(key); err != nil {
+				t.Errorf("Expecting error nil , but returns %v", err)
+			}
+		})
+	}
+}
+
+func TestFailWithMemoryRepository(t *testing.T) {
+
+	config := config.Config{LockoutThreshold: 2, LockoutDuration: time.Minute}
+	service := NewLockoutService(NewMemoryAttemptRepository(), nil, config)
+
+	service.Fail(key)
+	if err := service.Check(key); err != nil {
+		t.Errorf("Expecting error nil before threshold , but returns %v", err)
+	}
+
+	service.Fail(key)
+	err := service.Check(key)
+	if locked, ok := err.(*LockedError); !ok || locked.RetryAfterSeconds() != "60" {
+		t.Errorf("Expecting account locked for 60 seconds , but returns %v", err)
+	}
+
+	service.Reset(key)
+	if err := service.Check(key); err != nil {
+		t.Errorf("Expecting error nil after reset , but returns %v", err)
+	}
+}
+
+func TestMemoryRepositoryExpiresAttempts(t *testing.T) {
+
+	now := time.Now()
+	repository := NewMemoryAttemptRepository().(*memoryAttemptRepository)
+
+	repository.AddFailure("expired", now.Add(-2*time.Minute), time.Minute)
+	repository.AddFailure("swept", now.Add(-2*time.Minute), time.Minute)
+	repository.AddFailure(key, now, time.Minute)
+
+	if attempts, _ := repository.FindAttempts("expired"); attempts != nil {
+		t.Errorf("Expecting expired attempts nil , but returns %v", attempts)
+	}
+	if _, exists := repository.attempts["expired"]; exists {
+		t.Errorf("Expecting expired attempts dropped on lookup , but returns them kept")
+	}
+
+	repository.sweep(now)
+	if _, exists := repository.attempts["swept"]; exists {
+		t.Errorf("Expecting expired attempts dropped on sweep , but returns them kept")
+	}
+	if attempts, _ := repository.FindAttempts(key); attempts == nil || attempts.Failures != 1 {
+		t.Errorf("Expecting 1 failure kept , but returns %v", attempts)
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name          string
+		setupMock     func(attempts *MockAttemptRepository, userRepository *users.MockUserRepository)
+		expectedError error
+	}{
+		{
+			name: "unlock success",
+			setupMock: func(attempts *MockAttemptRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(userID, gomock.Any()).Return(&users.User{ID: userID, Email: "Test@Test.com"}, nil)
+				attempts.EXPECT().DeleteAttempts(key).Return(nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "user not exists",
+			setupMock: func(attempts *MockAttemptRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+			expectedError: &lockoutServiceError{code: USER_NOT_EXISTS},
+		},
+		{
+			name: "invalid user id",
+			setupMock: func(attempts *MockAttemptRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf(users.INVALID_OBJECT_ID))
+			},
+			expectedError: &lockoutServiceError{code: USER_ID_INVALID},
+		},
+		{
+			name: "delete failed",
+			setupMock: func(attempts *MockAttemptRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&users.User{ID: userID, Email: "test@test.com"}, nil)
+				attempts.EXPECT().DeleteAttempts(gomock.Any()).Return(fmt.Errorf("Any Error"))
+			},
+			expectedError: &lockoutServiceError{code: LOCKOUT_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			attempts := NewMockAttemptRepository(ctrl)
+			userRepository := users.NewMockUserRepository(ctrl)
+			tc.setupMock(attempts, userRepository)
+
+			service := NewLockoutService(attempts, userRepository, testConfig)
+
+			err := service.UnlockUser(userID)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package lockout
+
+import (
+	"context"
+	"time"
+)
+
+/*
+Drops the expired attempts of repository on every interval, until ctx is done.
+
+Attempts kept in MongoDB are expired by the TTL index, only attempts kept in
+memory are swept, so guessing unknown accounts does not grow them forever.
+*/
+func RunSweeper(ctx context.Context, repository AttemptRepository, interval time.Duration) {
+	memory, ok := repository.(*memoryAttemptRepository)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			memory.sweep(now)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// This package delivers emails sent by the API
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	/*
+		Method to deliver an email message
+
+		Parameters
+
+		message: Recipient, subject and plain text body.
+	*/
+	Send(message Message) error
+}
+
+// Returns the mailer for local use, appending messages to the file when set
+// or printing them to stdout otherwise
+func NewMailer(file string) Mailer {
+	if file != "" {
+		return &fileMailer{path: file}
+	}
+	return &writerMailer{writer: os.Stdout}
+}
+
+type writerMailer struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (m *writerMailer) Send(message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return writeMessage(m.writer, message)
+}
+
+type fileMailer struct {
+	mutex sync.Mutex
+	path  string
+}
+
+func (m *fileMailer) Send(message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeMessage(file, message)
+}
+
+func writeMessage(writer io.Writer, message Message) error {
+	_, err := fmt.Fprintf(writer, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), message.To, message.Subject, message.Body)
+	return err
+}
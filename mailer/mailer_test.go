@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mail.log")
+	m := NewMailer(file)
+
+	for _, to := range []string{"first@test.com", "second@test.com"} {
+		if err := m.Send(Message{To: to, Subject: "Password reset", Body: "token"}); err != nil {
+			t.Fatalf("Error on Send : %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Error reading mail file : %v", err)
+	}
+
+	for _, expected := range []string{"To: first@test.com\nSubject: Password reset\n\ntoken", "To: second@test.com"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expecting mail file containing %q , but returns %s", expected, content)
+		}
+	}
+}
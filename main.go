@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -24,22 +25,48 @@ import (
 //	@schemes	http https
 
 // @securityDefinitions.basic	BasicAuth
+
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
 func main() {
 	c := config.NewConfig()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(c, os.Args[2:])
+		return
+	}
+
 	s := server.NewServer(c)
 
 	docs.SwaggerInfo.Host = c.ApiHost
 
 	c.Validate()
 
+	// The server failing to start, or to serve, stops the process
+	go func() {
+		if err := s.Run(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("FATAL SERVER RUN:", err)
+		}
+	}()
+
+	// Reload credentials file on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	go func() {
-		if err := s.Run(); err != nil {
-			fmt.Println(fmt.Errorf("%v", err))
+		for range reload {
+			if err := s.Reload(); err != nil {
+				fmt.Println(fmt.Errorf("Error on Reload : %v", err))
+			}
 		}
 	}()
 
 	// Graceful Shutdown
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, os.Kill, syscall.SIGTERM)
 	<-quit
 	fmt.Println("Shutdown Server ...")
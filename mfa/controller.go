@@ -0,0 +1,151 @@
+package mfa
+
+import (
+	"encoding/json"
+	"io"
+	"userapi/identity"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller containing all MFA request handlers
+type MFAController struct {
+	service MFAService
+}
+
+// Returns new MFAController instance
+func NewMFAController(service MFAService) MFAController {
+	return MFAController{
+		service: service,
+	}
+}
+
+// EnrollMFA godoc
+//
+//	@Summary		Start TOTP enrolment
+//	@Description	This endpoint generates a TOTP secret for the logged in user, along with its otpauth:// URI to add it to an authenticator app.
+//	@Description	The factor is only required on login after being confirmed with a first code.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"User ID"
+//	@Success		201		{object}	Enrollment
+//	@Failure		401
+//	@Failure		403		{object}	MFAResponse
+//	@Failure		404		{object}	MFAResponse
+//	@Failure		409		{object}	MFAResponse
+//	@Failure		502		{object}	MFAResponse
+//	@Router			/users/{id}/mfa [post]
+func (ctr MFAController) EnrollMFA(c *gin.Context) {
+	enrollment, err := ctr.service.Enroll(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case USER_NOT_EXISTS:
+			c.JSON(404, USER_NOT_FOUND)
+		case MFA_ALREADY_ENABLED:
+			c.JSON(409, MFA_ENABLED)
+		default:
+			c.JSON(502, MFA_ENROLL_FAILED)
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(201, enrollment)
+}
+
+// ConfirmMFA godoc
+//
+//	@Summary		Confirm TOTP enrolment
+//	@Description	This endpoint enables the enrolled TOTP factor with a first code, returning one time recovery codes.
+//	@Description	The recovery codes are returned only on this response, only their hashes are stored.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string		true	"User ID"
+//	@Param			request	body		CodeRequest	true	"body"
+//	@Success		200		{object}	RecoveryCodes
+//	@Failure		401
+//	@Failure		403		{object}	MFAResponse
+//	@Failure		400		{object}	MFAResponse
+//	@Failure		404		{object}	MFAResponse
+//	@Failure		409		{object}	MFAResponse
+//	@Failure		502		{object}	MFAResponse
+//	@Router			/users/{id}/mfa/confirm [post]
+func (ctr MFAController) ConfirmMFA(c *gin.Context) {
+	var request CodeRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.Code == "" {
+		c.JSON(400, INVALID_MFA_DATA)
+		return
+	}
+
+	codes, err := ctr.service.Confirm(c.Param("id"), request.Code)
+	if err != nil {
+		switch err.Error() {
+		case MFA_CODE_INVALID:
+			c.JSON(400, INVALID_MFA_CODE)
+		case MFA_NOT_ENROLLED:
+			c.JSON(404, MFA_NOT_FOUND)
+		case MFA_ALREADY_ENABLED:
+			c.JSON(409, MFA_ENABLED)
+		default:
+			c.JSON(502, MFA_CONFIRM_FAILED)
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, codes)
+}
+
+// DisableMFA godoc
+//
+//	@Summary		Disable TOTP
+//	@Description	This endpoint removes the TOTP factor of the user. Users disabling their own factor must send a TOTP or recovery code,
+//	@Description	admins resetting the factor of another user do not.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string		true	"User ID"
+//	@Param			request	body		CodeRequest	false	"body"
+//	@Success		200		{object}	MFAResponse
+//	@Failure		401
+//	@Failure		403		{object}	MFAResponse
+//	@Failure		400		{object}	MFAResponse
+//	@Failure		404		{object}	MFAResponse
+//	@Failure		502		{object}	MFAResponse
+//	@Router			/users/{id}/mfa [delete]
+func (ctr MFAController) DisableMFA(c *gin.Context) {
+	var request CodeRequest
+
+	// The body is optional for admins resetting the factor
+	if err := json.NewDecoder(c.Request.Body).Decode(&request); err != nil && err != io.EOF {
+		c.JSON(400, INVALID_MFA_DATA)
+		return
+	}
+
+	principal, _ := identity.GetPrincipal(c)
+	requireCode := !users.IsGranted(principal, users.PERMISSION_MFA_RESET)
+	if requireCode && request.Code == "" {
+		c.JSON(400, INVALID_MFA_DATA)
+		return
+	}
+
+	err := ctr.service.Disable(c.Param("id"), request.Code, requireCode)
+	if err != nil {
+		switch err.Error() {
+		case MFA_CODE_INVALID:
+			c.JSON(400, INVALID_MFA_CODE)
+		case MFA_NOT_ENROLLED:
+			c.JSON(404, MFA_NOT_FOUND)
+		default:
+			c.JSON(502, MFA_DISABLE_FAILED)
+		}
+		return
+	}
+
+	c.JSON(200, MFA_DISABLED)
+}
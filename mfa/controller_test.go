@@ -0,0 +1,172 @@
+package mfa
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"userapi/identity"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestEnrollMFA(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockMFAService)
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "enroll success",
+			setupMock: func(service *MockMFAService) {
+				service.
+					EXPECT().
+					Enroll(userID).
+					Return(&Enrollment{Secret: "GEZDGNBV", URI: "otpauth://totp/User%20API:test@test.com?secret=GEZDGNBV"}, nil)
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: `{"secret":"GEZDGNBV","uri":"otpauth://totp/User%20API:test@test.com?secret=GEZDGNBV"}`,
+		},
+		{
+			name: "already enabled",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Enroll(gomock.Any()).Return(nil, &mfaServiceError{code: MFA_ALREADY_ENABLED})
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"message":"MFA Already Enabled","code":"MFA_ENABLED"}`,
+		},
+		{
+			name: "enroll failed",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Enroll(gomock.Any()).Return(nil, &mfaServiceError{code: MFA_FAILED})
+			},
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"MFA Enroll Failed","code":"MFA_ENROLL_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockMFAService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewMFAController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/mfa", controller.EnrollMFA)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/mfa", userID), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+
+	self := identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{users.ROLE_SELF}}
+	admin := identity.Principal{ID: "apiuser", Method: identity.BASIC_METHOD, Roles: []string{users.ROLE_ADMIN}}
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockMFAService)
+		principal        identity.Principal
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "user disables with code",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Disable(userID, "123456", true).Return(nil)
+			},
+			principal:        self,
+			inputBody:        `{"code": "123456"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"MFA Disabled","code":"MFA_DISABLED"}`,
+		},
+		{
+			name:             "user disables without code",
+			setupMock:        func(service *MockMFAService) {},
+			principal:        self,
+			inputBody:        ``,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid MFA Data","code":"INVALID_MFA_DATA"}`,
+		},
+		{
+			name: "admin resets without code",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Disable(userID, "", false).Return(nil)
+			},
+			principal:        admin,
+			inputBody:        ``,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"MFA Disabled","code":"MFA_DISABLED"}`,
+		},
+		{
+			name: "invalid code",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Disable(gomock.Any(), gomock.Any(), gomock.Any()).Return(&mfaServiceError{code: MFA_CODE_INVALID})
+			},
+			principal:        self,
+			inputBody:        `{"code": "000000"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid MFA Code","code":"INVALID_MFA_CODE"}`,
+		},
+		{
+			name: "not enrolled",
+			setupMock: func(service *MockMFAService) {
+				service.EXPECT().Disable(gomock.Any(), gomock.Any(), gomock.Any()).Return(&mfaServiceError{code: MFA_NOT_ENROLLED})
+			},
+			principal:        admin,
+			inputBody:        ``,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"MFA Not Enrolled","code":"MFA_NOT_FOUND"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockMFAService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewMFAController(svc)
+			r := gin.Default()
+			r.DELETE("/api/v1/users/:id/mfa", func(c *gin.Context) {
+				identity.SetPrincipal(c, tc.principal)
+			}, controller.DisableMFA)
+
+			req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/users/%s/mfa", userID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
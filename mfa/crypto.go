@@ -0,0 +1,41 @@
+package mfa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var errInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Returns the AES-256-GCM cipher protecting TOTP secrets, keyed by MFA_ENCRYPTION_KEY
+func newCipher(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypts the secret bound to the user, so it can not be moved to another user
+func encryptSecret(aead cipher.AEAD, secret []byte, userID string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, secret, []byte(userID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(aead cipher.AEAD, encrypted string, userID string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, errInvalidCiphertext
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(userID))
+}
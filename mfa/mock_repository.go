@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./mfa/repository.go
+
+// Package mfa is a generated GoMock package.
+package mfa
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockFactorRepository is a mock of FactorRepository interface.
+type MockFactorRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFactorRepositoryMockRecorder
+}
+
+// MockFactorRepositoryMockRecorder is the mock recorder for MockFactorRepository.
+type MockFactorRepositoryMockRecorder struct {
+	mock *MockFactorRepository
+}
+
+// NewMockFactorRepository creates a new mock instance.
+func NewMockFactorRepository(ctrl *gomock.Controller) *MockFactorRepository {
+	mock := &MockFactorRepository{ctrl: ctrl}
+	mock.recorder = &MockFactorRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFactorRepository) EXPECT() *MockFactorRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteFactor mocks base method.
+func (m *MockFactorRepository) DeleteFactor(userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFactor", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFactor indicates an expected call of DeleteFactor.
+func (mr *MockFactorRepositoryMockRecorder) DeleteFactor(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFactor", reflect.TypeOf((*MockFactorRepository)(nil).DeleteFactor), userID)
+}
+
+// EnableFactor mocks base method.
+func (m *MockFactorRepository) EnableFactor(userID string, step int64, recoveryCodes []RecoveryCode) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableFactor", userID, step, recoveryCodes)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableFactor indicates an expected call of EnableFactor.
+func (mr *MockFactorRepositoryMockRecorder) EnableFactor(userID, step, recoveryCodes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableFactor", reflect.TypeOf((*MockFactorRepository)(nil).EnableFactor), userID, step, recoveryCodes)
+}
+
+// FindFactor mocks base method.
+func (m *MockFactorRepository) FindFactor(userID string) (*Factor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindFactor", userID)
+	ret0, _ := ret[0].(*Factor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindFactor indicates an expected call of FindFactor.
+func (mr *MockFactorRepositoryMockRecorder) FindFactor(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindFactor", reflect.TypeOf((*MockFactorRepository)(nil).FindFactor), userID)
+}
+
+// SavePendingFactor mocks base method.
+func (m *MockFactorRepository) SavePendingFactor(factor Factor) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavePendingFactor", factor)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SavePendingFactor indicates an expected call of SavePendingFactor.
+func (mr *MockFactorRepositoryMockRecorder) SavePendingFactor(factor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavePendingFactor", reflect.TypeOf((*MockFactorRepository)(nil).SavePendingFactor), factor)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockFactorRepository) UseRecoveryCode(userID string, index int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", userID, index)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockFactorRepositoryMockRecorder) UseRecoveryCode(userID, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockFactorRepository)(nil).UseRecoveryCode), userID, index)
+}
+
+// UseStep mocks base method.
+func (m *MockFactorRepository) UseStep(userID string, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseStep", userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseStep indicates an expected call of UseStep.
+func (mr *MockFactorRepositoryMockRecorder) UseStep(userID, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseStep", reflect.TypeOf((*MockFactorRepository)(nil).UseStep), userID, step)
+}
@@ -0,0 +1,35 @@
+package mfa
+
+import "time"
+
+// TOTP factor of a user, pending until confirmed with a first code
+type Factor struct {
+	UserID        string         `bson:"_id"`
+	Secret        string         `bson:"secret"`
+	Enabled       bool           `bson:"enabled"`
+	CreatedAt     time.Time      `bson:"createdAt"`
+	EnabledAt     *time.Time     `bson:"enabledAt,omitempty"`
+	LastStep      int64          `bson:"lastStep"`
+	RecoveryCodes []RecoveryCode `bson:"recoveryCodes,omitempty"`
+}
+
+// One time recovery code, only its bcrypt hash is stored
+type RecoveryCode struct {
+	Hash   string     `bson:"hash"`
+	UsedAt *time.Time `bson:"usedAt,omitempty"`
+}
+
+// Secret shown once on enrolment, to be added to an authenticator app
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Recovery codes shown once on confirmation, each one replaces a TOTP code once
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
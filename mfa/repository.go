@@ -0,0 +1,125 @@
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const factorCollection string = "mfa_factors"
+const DOCUMENT_NOT_FOUND string = "DOCUMENT_NOT_FOUND"
+
+type FactorRepository interface {
+	FindFactor(userID string) (*Factor, error)
+	// Replaces the pending factor of the user, returns false when an enabled factor exists
+	SavePendingFactor(factor Factor) (bool, error)
+	// Enables the pending factor, returns false when it is not pending anymore
+	EnableFactor(userID string, step int64, recoveryCodes []RecoveryCode) (bool, error)
+	// Records the step of an accepted code, returns false when the step or a later one was used
+	UseStep(userID string, step int64) (bool, error)
+	// Marks the recovery code as used, returns false when it was already used
+	UseRecoveryCode(userID string, index int) (bool, error)
+	DeleteFactor(userID string) error
+}
+
+type factorRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewFactorRepository(client *mongo.Client, database string) FactorRepository {
+	return &factorRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *factorRepository) FindFactor(userID string) (*Factor, error) {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	filter := bson.M{"_id": bson.M{"$eq": userID}}
+
+	var factor Factor
+	err := coll.FindOne(context.Background(), filter).Decode(&factor)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &factor, nil
+}
+
+func (repo *factorRepository) SavePendingFactor(factor Factor) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	factor.Enabled = false
+	filter := bson.M{"_id": bson.M{"$eq": factor.UserID}, "enabled": bson.M{"$eq": false}}
+	opts := options.Replace().SetUpsert(true)
+
+	_, err := coll.ReplaceOne(context.Background(), filter, factor, opts)
+	if err != nil {
+		// The upsert conflicts with the enabled factor of the user
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *factorRepository) EnableFactor(userID string, step int64, recoveryCodes []RecoveryCode) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	filter := bson.M{"_id": bson.M{"$eq": userID}, "enabled": bson.M{"$eq": false}}
+	update := bson.M{"$set": bson.M{
+		"enabled":       true,
+		"enabledAt":     time.Now().UTC(),
+		"lastStep":      step,
+		"recoveryCodes": recoveryCodes,
+	}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (repo *factorRepository) UseStep(userID string, step int64) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	filter := bson.M{"_id": bson.M{"$eq": userID}, "lastStep": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"lastStep": step}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (repo *factorRepository) UseRecoveryCode(userID string, index int) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	field := fmt.Sprintf("recoveryCodes.%d.usedAt", index)
+	filter := bson.M{"_id": bson.M{"$eq": userID}, field: bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{field: time.Now().UTC()}}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (repo *factorRepository) DeleteFactor(userID string) error {
+	coll := repo.client.Database(repo.database).Collection(factorCollection)
+	result, err := coll.DeleteOne(context.Background(), bson.M{"_id": bson.M{"$eq": userID}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
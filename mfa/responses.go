@@ -0,0 +1,51 @@
+package mfa
+
+type MFAResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var INVALID_MFA_DATA MFAResponse = MFAResponse{
+	Message: "Invalid MFA Data",
+	Code:    "INVALID_MFA_DATA",
+}
+
+var INVALID_MFA_CODE MFAResponse = MFAResponse{
+	Message: "Invalid MFA Code",
+	Code:    "INVALID_MFA_CODE",
+}
+
+var MFA_ENABLED MFAResponse = MFAResponse{
+	Message: "MFA Already Enabled",
+	Code:    "MFA_ENABLED",
+}
+
+var MFA_NOT_FOUND MFAResponse = MFAResponse{
+	Message: "MFA Not Enrolled",
+	Code:    "MFA_NOT_FOUND",
+}
+
+var USER_NOT_FOUND MFAResponse = MFAResponse{
+	Message: "User Not Found",
+	Code:    "USER_NOT_FOUND",
+}
+
+var MFA_ENROLL_FAILED MFAResponse = MFAResponse{
+	Message: "MFA Enroll Failed",
+	Code:    "MFA_ENROLL_FAILED",
+}
+
+var MFA_CONFIRM_FAILED MFAResponse = MFAResponse{
+	Message: "MFA Confirm Failed",
+	Code:    "MFA_CONFIRM_FAILED",
+}
+
+var MFA_DISABLED MFAResponse = MFAResponse{
+	Message: "MFA Disabled",
+	Code:    "MFA_DISABLED",
+}
+
+var MFA_DISABLE_FAILED MFAResponse = MFAResponse{
+	Message: "MFA Disable Failed",
+	Code:    "MFA_DISABLE_FAILED",
+}
@@ -0,0 +1,24 @@
+// MFA module containing Controllers, Services e Repositories.
+// Module responsable for the TOTP second factor of users
+package mfa
+
+import (
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Method to add routes in api (gin.RouterGroup), using config (config.Config)
+// and client (mongo.Client)
+func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client) {
+	var factorRepository FactorRepository = NewFactorRepository(client, config.Database)
+	var userRepository users.UserRepository = users.NewUserRepository(client, config.Database)
+	var mfaService MFAService = NewMFAService(factorRepository, userRepository, config)
+	var mfaController MFAController = NewMFAController(mfaService)
+
+	api.POST("/users/:id/mfa", users.AuthorizeSelf(), mfaController.EnrollMFA)
+	api.POST("/users/:id/mfa/confirm", users.AuthorizeSelf(), mfaController.ConfirmMFA)
+	api.DELETE("/users/:id/mfa", users.AuthorizeSelf(users.PERMISSION_MFA_RESET), mfaController.DisableMFA)
+}
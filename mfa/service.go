@@ -0,0 +1,293 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+	"userapi/config"
+	"userapi/users"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Number of recovery codes issued when enrolment is confirmed
+const recoveryCodeCount int = 10
+
+type MFAService interface {
+	/*
+		Method to start TOTP enrolment, replacing any pending enrolment
+
+		Parameters
+
+		userID: User ID enrolling.
+
+		Returns the secret and the otpauth:// provisioning URI.
+	*/
+	Enroll(userID string) (*Enrollment, error)
+	/*
+		Method to enable the pending TOTP factor with a first code
+
+		Parameters
+
+		userID: User ID enrolling.
+		code: TOTP code generated from the enrolled secret.
+
+		Returns the one time recovery codes.
+	*/
+	Confirm(userID string, code string) (*RecoveryCodes, error)
+	/*
+		Method to remove the TOTP factor of the user
+
+		Parameters
+
+		userID: User ID enrolled.
+		code: TOTP or recovery code, only checked when requireCode is true.
+		requireCode: Tells if the code must be checked.
+	*/
+	Disable(userID string, code string, requireCode bool) error
+	/*
+		Method to check the second factor of a login
+
+		Parameters
+
+		userID: User ID logging in.
+		code: TOTP or recovery code, ignored when the user has no TOTP factor enabled.
+	*/
+	Check(userID string, code string) error
+}
+
+type mfaServiceError struct {
+	code string
+}
+
+func (e *mfaServiceError) Error() string {
+	return e.code
+}
+
+const MFA_FAILED string = "MFA_FAILED"
+const MFA_ALREADY_ENABLED string = "MFA_ALREADY_ENABLED"
+const MFA_NOT_ENROLLED string = "MFA_NOT_ENROLLED"
+const MFA_REQUIRED string = "MFA_REQUIRED"
+const MFA_CODE_INVALID string = "MFA_CODE_INVALID"
+const USER_NOT_EXISTS string = "USER_NOT_EXISTS"
+
+type mfaService struct {
+	factors FactorRepository
+	users   users.UserRepository
+	config  config.Config
+}
+
+func NewMFAService(factors FactorRepository, users users.UserRepository, config config.Config) MFAService {
+	return &mfaService{
+		factors: factors,
+		users:   users,
+		config:  config,
+	}
+}
+
+func (svc *mfaService) Enroll(userID string) (*Enrollment, error) {
+	user, err := svc.users.FindUserByID(userID, users.Projection{{Key: "email", Value: 1}})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindUserByID : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+	if user == nil {
+		fmt.Println(fmt.Errorf("User not exists"))
+		return nil, &mfaServiceError{code: USER_NOT_EXISTS}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on generateSecret : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+
+	aead, err := newCipher(svc.config.MFAEncryptionKey)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on newCipher : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+
+	encrypted, err := encryptSecret(aead, secret, userID)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on encryptSecret : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+
+	saved, err := svc.factors.SavePendingFactor(Factor{UserID: userID, Secret: encrypted, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on SavePendingFactor : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+	if !saved {
+		fmt.Println(fmt.Errorf("MFA already enabled"))
+		return nil, &mfaServiceError{code: MFA_ALREADY_ENABLED}
+	}
+
+	return &Enrollment{
+		Secret: secretEncoding.EncodeToString(secret),
+		URI:    provisioningURI(svc.config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+func (svc *mfaService) Confirm(userID string, code string) (*RecoveryCodes, error) {
+	factor, secret, err := svc.findFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor.Enabled {
+		fmt.Println(fmt.Errorf("MFA already enabled"))
+		return nil, &mfaServiceError{code: MFA_ALREADY_ENABLED}
+	}
+
+	step, ok := matchStep(secret, code, time.Now())
+	if !ok {
+		fmt.Println(fmt.Errorf("Invalid MFA code"))
+		return nil, &mfaServiceError{code: MFA_CODE_INVALID}
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on generateRecoveryCodes : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+
+	enabled, err := svc.factors.EnableFactor(userID, step, hashes)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on EnableFactor : %v", err))
+		return nil, &mfaServiceError{code: MFA_FAILED}
+	}
+	if !enabled {
+		fmt.Println(fmt.Errorf("MFA already enabled"))
+		return nil, &mfaServiceError{code: MFA_ALREADY_ENABLED}
+	}
+	return &RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+func (svc *mfaService) Disable(userID string, code string, requireCode bool) error {
+	if requireCode {
+		factor, secret, err := svc.findFactor(userID)
+		if err != nil {
+			return err
+		}
+		if err := svc.verify(factor, secret, code); err != nil {
+			return err
+		}
+	}
+
+	if err := svc.factors.DeleteFactor(userID); err != nil {
+		if err.Error() == DOCUMENT_NOT_FOUND {
+			fmt.Println(fmt.Errorf("MFA not enrolled"))
+			return &mfaServiceError{code: MFA_NOT_ENROLLED}
+		}
+		fmt.Println(fmt.Errorf("Error on DeleteFactor : %v", err))
+		return &mfaServiceError{code: MFA_FAILED}
+	}
+	return nil
+}
+
+func (svc *mfaService) Check(userID string, code string) error {
+	factor, secret, err := svc.findFactor(userID)
+	if err != nil {
+		if err.Error() == MFA_NOT_ENROLLED {
+			return nil
+		}
+		return err
+	}
+	if !factor.Enabled {
+		return nil
+	}
+
+	if code == "" {
+		fmt.Println(fmt.Errorf("MFA code required"))
+		return &mfaServiceError{code: MFA_REQUIRED}
+	}
+	return svc.verify(factor, secret, code)
+}
+
+// Returns the factor of the user with its decrypted secret
+func (svc *mfaService) findFactor(userID string) (*Factor, []byte, error) {
+	factor, err := svc.factors.FindFactor(userID)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on FindFactor : %v", err))
+		return nil, nil, &mfaServiceError{code: MFA_FAILED}
+	}
+	if factor == nil {
+		return nil, nil, &mfaServiceError{code: MFA_NOT_ENROLLED}
+	}
+
+	aead, err := newCipher(svc.config.MFAEncryptionKey)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on newCipher : %v", err))
+		return nil, nil, &mfaServiceError{code: MFA_FAILED}
+	}
+
+	secret, err := decryptSecret(aead, factor.Secret, userID)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on decryptSecret : %v", err))
+		return nil, nil, &mfaServiceError{code: MFA_FAILED}
+	}
+	return factor, secret, nil
+}
+
+// Accepts a TOTP code not used before or an unused recovery code of an enabled factor
+func (svc *mfaService) verify(factor *Factor, secret []byte, code string) error {
+	if !factor.Enabled {
+		return &mfaServiceError{code: MFA_NOT_ENROLLED}
+	}
+
+	if step, ok := matchStep(secret, code, time.Now()); ok {
+		used, err := svc.factors.UseStep(factor.UserID, step)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on UseStep : %v", err))
+			return &mfaServiceError{code: MFA_FAILED}
+		}
+		if used {
+			return nil
+		}
+		fmt.Println(fmt.Errorf("MFA code replayed"))
+		return &mfaServiceError{code: MFA_CODE_INVALID}
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	for i, recoveryCode := range factor.RecoveryCodes {
+		if recoveryCode.UsedAt != nil || bcrypt.CompareHashAndPassword([]byte(recoveryCode.Hash), []byte(normalized)) != nil {
+			continue
+		}
+		used, err := svc.factors.UseRecoveryCode(factor.UserID, i)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on UseRecoveryCode : %v", err))
+			return &mfaServiceError{code: MFA_FAILED}
+		}
+		if used {
+			return nil
+		}
+	}
+
+	fmt.Println(fmt.Errorf("Invalid MFA code"))
+	return &mfaServiceError{code: MFA_CODE_INVALID}
+}
+
+var recoveryCodeEncoding = secretEncoding
+
+// Returns the recovery codes, formatted as xxxxx-xxxxx, along with their hashes to store
+func generateRecoveryCodes() ([]string, []RecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, 7)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(bytes)[:10])
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, RecoveryCode{Hash: users.HashPassword(code)})
+	}
+	return codes, hashes, nil
+}
+
+// Recovery codes are accepted in any case, with or without the dash
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,346 @@
+package mfa
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/golang/mock/gomock"
+)
+
+var testConfig config.Config = config.Config{
+	MFAEncryptionKey: "test-key-with-at-least-32-characters",
+	MFAIssuer:        "User API",
+}
+
+const userID string = "64260e1da4c0c814bda5734a"
+
+var testSecret []byte = []byte("12345678901234567890")
+
+// Returns a factor of the test user holding testSecret encrypted
+func testFactor(t *testing.T, enabled bool, recoveryCodes ...RecoveryCode) *Factor {
+	aead, _ := newCipher(testConfig.MFAEncryptionKey)
+	encrypted, err := encryptSecret(aead, testSecret, userID)
+	if err != nil {
+		t.Fatalf("Error on encryptSecret : %v", err)
+	}
+	return &Factor{UserID: userID, Secret: encrypted, Enabled: enabled, RecoveryCodes: recoveryCodes}
+}
+
+func currentCode() string {
+	return hotp(testSecret, uint64(timeStep(time.Now())))
+}
+
+func TestEnroll(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		setupMock     func(factors *MockFactorRepository, userRepository *users.MockUserRepository)
+		expectedError error
+	}{
+		{
+			name: "enroll success",
+			setupMock: func(factors *MockFactorRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(userID, gomock.Any()).Return(&users.User{ID: userID, Email: "test@test.com"}, nil)
+				factors.
+					EXPECT().
+					SavePendingFactor(gomock.Any()).
+					DoAndReturn(func(factor Factor) (bool, error) {
+						if factor.UserID != userID || factor.Enabled || factor.Secret == "" {
+							t.Errorf("Expecting pending factor with encrypted secret , but returns %v", factor)
+						}
+						return true, nil
+					})
+			},
+			expectedError: nil,
+		},
+		{
+			name: "already enabled",
+			setupMock: func(factors *MockFactorRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&users.User{ID: userID, Email: "test@test.com"}, nil)
+				factors.EXPECT().SavePendingFactor(gomock.Any()).Return(false, nil)
+			},
+			expectedError: &mfaServiceError{code: MFA_ALREADY_ENABLED},
+		},
+		{
+			name: "user not exists",
+			setupMock: func(factors *MockFactorRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+			expectedError: &mfaServiceError{code: USER_NOT_EXISTS},
+		},
+		{
+			name: "save failed",
+			setupMock: func(factors *MockFactorRepository, userRepository *users.MockUserRepository) {
+				userRepository.EXPECT().FindUserByID(gomock.Any(), gomock.Any()).Return(&users.User{ID: userID, Email: "test@test.com"}, nil)
+				factors.EXPECT().SavePendingFactor(gomock.Any()).Return(false, fmt.Errorf("Any Error"))
+			},
+			expectedError: &mfaServiceError{code: MFA_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			factors := NewMockFactorRepository(ctrl)
+			userRepository := users.NewMockUserRepository(ctrl)
+			tc.setupMock(factors, userRepository)
+
+			service := NewMFAService(factors, userRepository, testConfig)
+
+			result, err := service.Enroll(userID)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil && (result.Secret == "" || result.URI == "") {
+				t.Errorf("Expecting secret and URI , but returns %v", result)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		setupMock     func(factors *MockFactorRepository)
+		inputCode     string
+		expectedError error
+	}{
+		{
+			name: "confirm success",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, false), nil)
+				factors.
+					EXPECT().
+					EnableFactor(userID, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(userID string, step int64, codes []RecoveryCode) (bool, error) {
+						if len(codes) != recoveryCodeCount {
+							t.Errorf("Expecting %d recovery codes , but returns %d", recoveryCodeCount, len(codes))
+						}
+						return true, nil
+					})
+			},
+			inputCode:     currentCode(),
+			expectedError: nil,
+		},
+		{
+			name: "invalid code",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(gomock.Any()).Return(testFactor(t, false), nil)
+			},
+			inputCode:     "abcdef",
+			expectedError: &mfaServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "not enrolled",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(gomock.Any()).Return(nil, nil)
+			},
+			inputCode:     currentCode(),
+			expectedError: &mfaServiceError{code: MFA_NOT_ENROLLED},
+		},
+		{
+			name: "already enabled",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(gomock.Any()).Return(testFactor(t, true), nil)
+			},
+			inputCode:     currentCode(),
+			expectedError: &mfaServiceError{code: MFA_ALREADY_ENABLED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			factors := NewMockFactorRepository(ctrl)
+			tc.setupMock(factors)
+
+			service := NewMFAService(factors, users.NewMockUserRepository(ctrl), testConfig)
+
+			result, err := service.Confirm(userID, tc.inputCode)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil && len(result.RecoveryCodes) != recoveryCodeCount {
+				t.Errorf("Expecting %d recovery codes , but returns %v", recoveryCodeCount, result)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+
+	usedAt := time.Now()
+	recoveryCode := RecoveryCode{Hash: users.HashPassword("abcde12345")}
+	usedRecoveryCode := RecoveryCode{Hash: users.HashPassword("abcde12345"), UsedAt: &usedAt}
+
+	tests := []struct {
+		name          string
+		setupMock     func(factors *MockFactorRepository)
+		inputCode     string
+		expectedError error
+	}{
+		{
+			name: "not enrolled",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(nil, nil)
+			},
+			inputCode:     "",
+			expectedError: nil,
+		},
+		{
+			name: "enrolment pending",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, false), nil)
+			},
+			inputCode:     "",
+			expectedError: nil,
+		},
+		{
+			name: "code required",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true), nil)
+			},
+			inputCode:     "",
+			expectedError: &mfaServiceError{code: MFA_REQUIRED},
+		},
+		{
+			name: "totp code accepted",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true), nil)
+				factors.EXPECT().UseStep(userID, gomock.Any()).Return(true, nil)
+			},
+			inputCode:     currentCode(),
+			expectedError: nil,
+		},
+		{
+			name: "totp code replayed",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true), nil)
+				factors.EXPECT().UseStep(userID, gomock.Any()).Return(false, nil)
+			},
+			inputCode:     currentCode(),
+			expectedError: &mfaServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "recovery code accepted",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true, recoveryCode), nil)
+				factors.EXPECT().UseRecoveryCode(userID, 0).Return(true, nil)
+			},
+			inputCode:     "ABCDE-12345",
+			expectedError: nil,
+		},
+		{
+			name: "recovery code used",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true, usedRecoveryCode), nil)
+			},
+			inputCode:     "abcde-12345",
+			expectedError: &mfaServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "wrong code",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true, recoveryCode), nil)
+			},
+			inputCode:     "00000-00000",
+			expectedError: &mfaServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "find factor failed",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(nil, fmt.Errorf("Any Error"))
+			},
+			inputCode:     currentCode(),
+			expectedError: &mfaServiceError{code: MFA_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			factors := NewMockFactorRepository(ctrl)
+			tc.setupMock(factors)
+
+			service := NewMFAService(factors, users.NewMockUserRepository(ctrl), testConfig)
+
+			err := service.Check(userID, tc.inputCode)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestDisable(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		setupMock     func(factors *MockFactorRepository)
+		inputCode     string
+		requireCode   bool
+		expectedError error
+	}{
+		{
+			name: "disable with code",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true), nil)
+				factors.EXPECT().UseStep(userID, gomock.Any()).Return(true, nil)
+				factors.EXPECT().DeleteFactor(userID).Return(nil)
+			},
+			inputCode:     currentCode(),
+			requireCode:   true,
+			expectedError: nil,
+		},
+		{
+			name: "wrong code",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().FindFactor(userID).Return(testFactor(t, true), nil)
+			},
+			inputCode:     "abcdef",
+			requireCode:   true,
+			expectedError: &mfaServiceError{code: MFA_CODE_INVALID},
+		},
+		{
+			name: "reset without code",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().DeleteFactor(userID).Return(nil)
+			},
+			requireCode:   false,
+			expectedError: nil,
+		},
+		{
+			name: "not enrolled",
+			setupMock: func(factors *MockFactorRepository) {
+				factors.EXPECT().DeleteFactor(userID).Return(fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			requireCode:   false,
+			expectedError: &mfaServiceError{code: MFA_NOT_ENROLLED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			factors := NewMockFactorRepository(ctrl)
+			tc.setupMock(factors)
+
+			service := NewMFAService(factors, users.NewMockUserRepository(ctrl), testConfig)
+
+			err := service.Disable(userID, tc.inputCode, tc.requireCode)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// TOTP parameters of RFC 6238, the defaults understood by authenticator apps
+const totpDigits int = 6
+const totpPeriod int64 = 30
+
+// Steps accepted before and after the current one, tolerating clock drift
+const totpSkew int64 = 1
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Returns a new random TOTP secret of 160 bits, as recommended by RFC 4226
+func generateSecret() ([]byte, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Returns the time step of RFC 6238 at the given time
+func timeStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// Returns the HOTP code of RFC 4226 for the counter
+func hotp(secret []byte, counter uint64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// Returns the step matching the code around the given time, or false when none matches
+func matchStep(secret []byte, code string, now time.Time) (int64, bool) {
+	current := timeStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if hmac.Equal([]byte(hotp(secret, uint64(step))), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the otpauth:// URI read by authenticator apps from a QR code
+func provisioningURI(issuer string, account string, secret []byte) string {
+	values := url.Values{}
+	values.Set("secret", secretEncoding.EncodeToString(secret))
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, values.Encode())
+}
@@ -0,0 +1,80 @@
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestHOTP(t *testing.T) {
+
+	// Test vectors of RFC 6238, appendix B, with SHA1 and 6 digits
+	secret := []byte("12345678901234567890")
+
+	tests := []struct {
+		name         string
+		time         int64
+		expectedCode string
+	}{
+		{name: "time 59", time: 59, expectedCode: "287082"},
+		{name: "time 1111111109", time: 1111111109, expectedCode: "081804"},
+		{name: "time 1111111111", time: 1111111111, expectedCode: "050471"},
+		{name: "time 1234567890", time: 1234567890, expectedCode: "005924"},
+		{name: "time 2000000000", time: 2000000000, expectedCode: "279037"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			code := hotp(secret, uint64(timeStep(time.Unix(tc.time, 0))))
+			if code != tc.expectedCode {
+				t.Errorf("Expecting code %s , but returns %s", tc.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestMatchStep(t *testing.T) {
+
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := timeStep(now)
+
+	tests := []struct {
+		name          string
+		code          string
+		expectedStep  int64
+		expectedMatch bool
+	}{
+		{name: "current step", code: hotp(secret, uint64(current)), expectedStep: current, expectedMatch: true},
+		{name: "previous step", code: hotp(secret, uint64(current-1)), expectedStep: current - 1, expectedMatch: true},
+		{name: "next step", code: hotp(secret, uint64(current+1)), expectedStep: current + 1, expectedMatch: true},
+		{name: "step out of skew", code: hotp(secret, uint64(current-2)), expectedMatch: false},
+		{name: "wrong code", code: "12345", expectedMatch: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			step, ok := matchStep(secret, tc.code, now)
+			if ok != tc.expectedMatch || step != tc.expectedStep {
+				t.Errorf("Expecting step %d %v , but returns %d %v", tc.expectedStep, tc.expectedMatch, step, ok)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(provisioningURI("User API", "test@test.com", []byte("12345678901234567890")))
+	if err != nil {
+		t.Fatalf("Expecting valid URI , but returns %v", err)
+	}
+
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/User API:test@test.com" {
+		t.Errorf("Expecting otpauth://totp/User API:test@test.com , but returns %s", uri)
+	}
+	if secret := uri.Query().Get("secret"); secret != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("Expecting base32 secret , but returns %s", secret)
+	}
+	if issuer := uri.Query().Get("issuer"); issuer != "User API" {
+		t.Errorf("Expecting issuer User API , but returns %s", issuer)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"userapi/config"
+	"userapi/migrations"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const migrateUsage string = "Usage: userapi migrate up [N] | down [N] | status"
+
+/*
+Runs the migrate command.
+
+up applies the pending migrations, or the next N, down reverts the latest
+migration, or the latest N, and status lists the migrations.
+*/
+func migrate(c config.Config, args []string) {
+	if len(args) == 0 || len(args) > 2 {
+		exitUsage()
+	}
+
+	steps := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			exitUsage()
+		}
+		steps = n
+	}
+
+	if c.MigrationLockTTL <= 0 {
+		log.Fatal("Invalid MIGRATION_LOCK_TTL environment variable, it must be positive")
+	}
+
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(c.DBURI))
+	if err != nil {
+		log.Fatal("FATAL DATABASE CONNECTION:", err)
+	}
+	defer client.Disconnect(context.TODO())
+
+	repo := migrations.NewMigrationRepository(client, c.Database)
+	service := migrations.NewMigrationService(repo, client.Database(c.Database), migrations.Migrations(), c)
+
+	switch args[0] {
+	case "up":
+		done, err := service.Up(steps)
+		printMigrations("Applied", done, err)
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		done, err := service.Down(steps)
+		printMigrations("Reverted", done, err)
+	case "status":
+		if len(args) != 1 {
+			exitUsage()
+		}
+		statuses, err := service.Status()
+		exitOnError(err)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-45s %s\n", status.Version, status.Description, state)
+		}
+	default:
+		exitUsage()
+	}
+}
+
+// Lists the migrations run, which are kept when a later migration fails
+func printMigrations(action string, done []migrations.Migration, err error) {
+	for _, migration := range done {
+		fmt.Printf("%s %d  %s\n", action, migration.Version, migration.Description)
+	}
+	if len(done) == 0 && err == nil {
+		fmt.Println("No migrations to run")
+	}
+	exitOnError(err)
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Println(fmt.Errorf("Migrate failed : %v", err))
+		os.Exit(1)
+	}
+}
+
+func exitUsage() {
+	fmt.Println(migrateUsage)
+	os.Exit(2)
+}
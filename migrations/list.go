@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"userapi/users"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Code returned by MongoDB when creating a collection that exists
+const NAMESPACE_EXISTS int = 48
+
+/*
+Returns the migrations of the API schema.
+
+New migrations are appended with the next version and never changed once
+released, since environments only run the migrations they did not record.
+*/
+func Migrations() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "Create users collection",
+			Up:          createUsersCollection,
+			// Reverting would drop the users
+			Down: nil,
+		},
+		{
+			Version:     2,
+			Description: "Index users by birth date",
+			Up:          createBirthDateIndex,
+			Down:        dropBirthDateIndex,
+		},
+		{
+			Version:     3,
+			Description: "Convert legacy ages into birth dates",
+			Up:          convertLegacyAges,
+			// Converted ages are computed from the birth dates, which stay valid
+			Down: func(db *mongo.Database) error { return nil },
+		},
+		{
+			Version:     4,
+			Description: "Normalize user emails",
+			Up:          normalizeEmails,
+			// Emails are compared ignoring case, so normalized emails stay valid
+			Down: func(db *mongo.Database) error { return nil },
+		},
+		{
+			Version:     5,
+			Description: "Index users by unique email ignoring case",
+			Up:          func(db *mongo.Database) error { return users.CreateEmailIndex(db.Client(), db.Name()) },
+			Down:        func(db *mongo.Database) error { return users.DropEmailIndex(db.Client(), db.Name()) },
+		},
+		{
+			Version:     6,
+			Description: "Index users by deletion time",
+			Up:          createDeletedAtIndex,
+			Down:        dropDeletedAtIndex,
+		},
+		{
+			Version:     7,
+			Description: "Leave deleted users out of the unique email index",
+			Up:          func(db *mongo.Database) error { return users.ExcludeDeletedFromEmailIndex(db.Client(), db.Name()) },
+			Down:        func(db *mongo.Database) error { return users.IncludeDeletedInEmailIndex(db.Client(), db.Name()) },
+		},
+	}
+}
+
+func createUsersCollection(db *mongo.Database) error {
+	err := db.CreateCollection(context.Background(), "users")
+	if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Code == int32(NAMESPACE_EXISTS) {
+		return nil
+	}
+	return err
+}
+
+func createBirthDateIndex(db *mongo.Database) error {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "birthDate", Value: 1}},
+		Options: options.Index().SetName("birthDate_1"),
+	}
+	_, err := db.Collection("users").Indexes().CreateOne(context.Background(), index)
+	return err
+}
+
+func dropBirthDateIndex(db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().DropOne(context.Background(), "birthDate_1")
+	return err
+}
+
+// Serves both listing users not deleted and purging users deleted long ago
+func createDeletedAtIndex(db *mongo.Database) error {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName("deletedAt_1"),
+	}
+	_, err := db.Collection("users").Indexes().CreateOne(context.Background(), index)
+	return err
+}
+
+func dropDeletedAtIndex(db *mongo.Database) error {
+	_, err := db.Collection("users").Indexes().DropOne(context.Background(), "deletedAt_1")
+	return err
+}
+
+// Users whose age could not be converted are listed and keep their legacy age
+func convertLegacyAges(db *mongo.Database) error {
+	report, err := users.MigrateBirthDates(db.Client(), db.Name())
+	if report != nil {
+		fmt.Printf("Converted %d users\n", report.Converted)
+		for _, failure := range report.Failed {
+			fmt.Printf("Could not convert user %s with age %q\n", failure.ID, failure.Age)
+		}
+	}
+	return err
+}
+
+// Fails while users share an email ignoring case, listing them to be resolved before running it again
+func normalizeEmails(db *mongo.Database) error {
+	report, err := users.NormalizeEmails(db.Client(), db.Name())
+	if report != nil {
+		fmt.Printf("Normalized %d emails\n", report.Normalized)
+		for _, conflict := range report.Conflicts {
+			fmt.Printf("User %s with email %q conflicts with user %s\n", conflict.ID, conflict.Email, conflict.ConflictID)
+		}
+	}
+	if err == nil && len(report.Conflicts) > 0 {
+		return fmt.Errorf("%d users share an email ignoring case", len(report.Conflicts))
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./migrations/repository.go
+
+// Package migrations is a generated GoMock package.
+package migrations
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockMigrationRepository is a mock of MigrationRepository interface.
+type MockMigrationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockMigrationRepositoryMockRecorder
+}
+
+// MockMigrationRepositoryMockRecorder is the mock recorder for MockMigrationRepository.
+type MockMigrationRepositoryMockRecorder struct {
+	mock *MockMigrationRepository
+}
+
+// NewMockMigrationRepository creates a new mock instance.
+func NewMockMigrationRepository(ctrl *gomock.Controller) *MockMigrationRepository {
+	mock := &MockMigrationRepository{ctrl: ctrl}
+	mock.recorder = &MockMigrationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMigrationRepository) EXPECT() *MockMigrationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// AcquireLock mocks base method.
+func (m *MockMigrationRepository) AcquireLock(owner string, now, until time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireLock", owner, now, until)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireLock indicates an expected call of AcquireLock.
+func (mr *MockMigrationRepositoryMockRecorder) AcquireLock(owner, now, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireLock", reflect.TypeOf((*MockMigrationRepository)(nil).AcquireLock), owner, now, until)
+}
+
+// DeleteApplied mocks base method.
+func (m *MockMigrationRepository) DeleteApplied(version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApplied", version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApplied indicates an expected call of DeleteApplied.
+func (mr *MockMigrationRepositoryMockRecorder) DeleteApplied(version interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApplied", reflect.TypeOf((*MockMigrationRepository)(nil).DeleteApplied), version)
+}
+
+// InsertApplied mocks base method.
+func (m *MockMigrationRepository) InsertApplied(migration AppliedMigration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertApplied", migration)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertApplied indicates an expected call of InsertApplied.
+func (mr *MockMigrationRepositoryMockRecorder) InsertApplied(migration interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertApplied", reflect.TypeOf((*MockMigrationRepository)(nil).InsertApplied), migration)
+}
+
+// ListApplied mocks base method.
+func (m *MockMigrationRepository) ListApplied() ([]AppliedMigration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListApplied")
+	ret0, _ := ret[0].([]AppliedMigration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListApplied indicates an expected call of ListApplied.
+func (mr *MockMigrationRepositoryMockRecorder) ListApplied() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListApplied", reflect.TypeOf((*MockMigrationRepository)(nil).ListApplied))
+}
+
+// ReleaseLock mocks base method.
+func (m *MockMigrationRepository) ReleaseLock(owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLock", owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLock indicates an expected call of ReleaseLock.
+func (mr *MockMigrationRepositoryMockRecorder) ReleaseLock(owner interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLock", reflect.TypeOf((*MockMigrationRepository)(nil).ReleaseLock), owner)
+}
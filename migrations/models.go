@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Versioned schema change, migrations are applied in version order and reverted in reverse order
+type Migration struct {
+	Version     int64
+	Description string
+	Up          func(db *mongo.Database) error
+	// Reverts Up, nil when the migration can not be reverted
+	Down func(db *mongo.Database) error
+}
+
+// Migration recorded as applied
+type AppliedMigration struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"appliedAt"`
+}
+
+// State of a migration, applied migrations unknown to this build are listed as well
+type MigrationStatus struct {
+	Version     int64
+	Description string
+	Applied     bool
+	AppliedAt   *time.Time
+}
+
+// Lock held by the process running migrations, taken over by others once expired
+type MigrationLock struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	LockedAt  time.Time `bson:"lockedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
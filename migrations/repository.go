@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const migrationCollection string = "schema_migrations"
+const lockCollection string = "schema_migrations_lock"
+const lockID string = "migrations"
+
+type MigrationRepository interface {
+	// Returns the applied migrations in version order
+	ListApplied() ([]AppliedMigration, error)
+	InsertApplied(migration AppliedMigration) error
+	DeleteApplied(version int64) error
+	// Takes the lock for owner until the given time, returns false while another owner holds it
+	AcquireLock(owner string, now time.Time, until time.Time) (bool, error)
+	ReleaseLock(owner string) error
+}
+
+type migrationRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewMigrationRepository(client *mongo.Client, database string) MigrationRepository {
+	return &migrationRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *migrationRepository) ListApplied() ([]AppliedMigration, error) {
+	coll := repo.client.Database(repo.database).Collection(migrationCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := coll.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]AppliedMigration, 0)
+	if err := cursor.All(context.Background(), &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (repo *migrationRepository) InsertApplied(migration AppliedMigration) error {
+	coll := repo.client.Database(repo.database).Collection(migrationCollection)
+	_, err := coll.InsertOne(context.Background(), migration)
+	return err
+}
+
+func (repo *migrationRepository) DeleteApplied(version int64) error {
+	coll := repo.client.Database(repo.database).Collection(migrationCollection)
+	_, err := coll.DeleteOne(context.Background(), bson.M{"_id": bson.M{"$eq": version}})
+	return err
+}
+
+func (repo *migrationRepository) AcquireLock(owner string, now time.Time, until time.Time) (bool, error) {
+	coll := repo.client.Database(repo.database).Collection(lockCollection)
+
+	// The upsert only matches a lock of the same owner or an expired one,
+	// otherwise it inserts a second lock and fails on the duplicate id
+	filter := bson.M{
+		"_id": lockID,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "lockedAt": now, "expiresAt": until}}
+	opts := options.Update().SetUpsert(true)
+
+	_, err := coll.UpdateOne(context.Background(), filter, update, opts)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (repo *migrationRepository) ReleaseLock(owner string) error {
+	coll := repo.client.Database(repo.database).Collection(lockCollection)
+	_, err := coll.DeleteOne(context.Background(), bson.M{"_id": lockID, "owner": owner})
+	return err
+}
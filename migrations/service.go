@@ -0,0 +1,223 @@
+// Migrations module containing Services e Repositories.
+// Module responsable for versioned schema changes, run by the migrate command
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+	"userapi/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MigrationService interface {
+	/*
+		Method to apply pending migrations in version order
+
+		Parameters
+
+		steps: Number of migrations to apply, all pending migrations when zero.
+
+		Returns the migrations applied, also when a migration fails.
+	*/
+	Up(steps int) ([]Migration, error)
+	/*
+		Method to revert applied migrations, the latest first
+
+		Parameters
+
+		steps: Number of migrations to revert.
+
+		Returns the migrations reverted, also when a migration fails.
+	*/
+	Down(steps int) ([]Migration, error)
+	/*
+		Method to list known migrations along with the applied ones, in version order
+	*/
+	Status() ([]MigrationStatus, error)
+}
+
+type migrationServiceError struct {
+	code string
+}
+
+func (e *migrationServiceError) Error() string {
+	return e.code
+}
+
+const MIGRATION_LOCKED string = "MIGRATION_LOCKED"
+const MIGRATION_FAILED string = "MIGRATION_FAILED"
+const MIGRATION_IRREVERSIBLE string = "MIGRATION_IRREVERSIBLE"
+const MIGRATION_UNKNOWN string = "MIGRATION_UNKNOWN"
+
+type migrationService struct {
+	repo       MigrationRepository
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+	config     config.Config
+}
+
+// Returns a MigrationService running the migrations on db, recording them on the repository
+func NewMigrationService(repo MigrationRepository, db *mongo.Database, migrations []Migration, config config.Config) MigrationService {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	hostname, _ := os.Hostname()
+	return &migrationService{
+		repo:       repo,
+		db:         db,
+		migrations: sorted,
+		owner:      fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+		config:     config,
+	}
+}
+
+func (svc *migrationService) Up(steps int) ([]Migration, error) {
+	if err := svc.lock(); err != nil {
+		return nil, err
+	}
+	defer svc.unlock()
+
+	applied, err := svc.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range svc.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		if err := migration.Up(svc.db); err != nil {
+			fmt.Println(fmt.Errorf("Error on migration %d up : %v", migration.Version, err))
+			return done, &migrationServiceError{code: MIGRATION_FAILED}
+		}
+
+		record := AppliedMigration{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now().UTC()}
+		if err := svc.repo.InsertApplied(record); err != nil {
+			fmt.Println(fmt.Errorf("Error on InsertApplied : %v", err))
+			return done, &migrationServiceError{code: MIGRATION_FAILED}
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (svc *migrationService) Down(steps int) ([]Migration, error) {
+	if err := svc.lock(); err != nil {
+		return nil, err
+	}
+	defer svc.unlock()
+
+	applied, err := svc.repo.ListApplied()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListApplied : %v", err))
+		return nil, &migrationServiceError{code: MIGRATION_FAILED}
+	}
+
+	done := make([]Migration, 0)
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, ok := svc.find(applied[i].Version)
+		if !ok {
+			fmt.Println(fmt.Errorf("Migration %d is not known by this build", applied[i].Version))
+			return done, &migrationServiceError{code: MIGRATION_UNKNOWN}
+		}
+		if migration.Down == nil {
+			fmt.Println(fmt.Errorf("Migration %d can not be reverted", migration.Version))
+			return done, &migrationServiceError{code: MIGRATION_IRREVERSIBLE}
+		}
+
+		if err := migration.Down(svc.db); err != nil {
+			fmt.Println(fmt.Errorf("Error on migration %d down : %v", migration.Version, err))
+			return done, &migrationServiceError{code: MIGRATION_FAILED}
+		}
+
+		if err := svc.repo.DeleteApplied(migration.Version); err != nil {
+			fmt.Println(fmt.Errorf("Error on DeleteApplied : %v", err))
+			return done, &migrationServiceError{code: MIGRATION_FAILED}
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (svc *migrationService) Status() ([]MigrationStatus, error) {
+	applied, err := svc.repo.ListApplied()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListApplied : %v", err))
+		return nil, &migrationServiceError{code: MIGRATION_FAILED}
+	}
+
+	statuses := make([]MigrationStatus, 0, len(svc.migrations))
+	recorded := make(map[int64]AppliedMigration)
+	for _, a := range applied {
+		recorded[a.Version] = a
+	}
+	for _, migration := range svc.migrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if a, ok := recorded[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &a.AppliedAt
+			delete(recorded, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range recorded {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Description: a.Description, Applied: true, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (svc *migrationService) find(version int64) (Migration, bool) {
+	for _, migration := range svc.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (svc *migrationService) appliedVersions() (map[int64]bool, error) {
+	applied, err := svc.repo.ListApplied()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListApplied : %v", err))
+		return nil, &migrationServiceError{code: MIGRATION_FAILED}
+	}
+
+	versions := make(map[int64]bool)
+	for _, a := range applied {
+		versions[a.Version] = true
+	}
+	return versions, nil
+}
+
+// Takes the lock so migrations do not run concurrently, it expires after MIGRATION_LOCK_TTL
+// in case the process dies while holding it
+func (svc *migrationService) lock() error {
+	now := time.Now()
+	acquired, err := svc.repo.AcquireLock(svc.owner, now, now.Add(svc.config.MigrationLockTTL))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on AcquireLock : %v", err))
+		return &migrationServiceError{code: MIGRATION_FAILED}
+	}
+	if !acquired {
+		fmt.Println(fmt.Errorf("Migrations are being run by another process"))
+		return &migrationServiceError{code: MIGRATION_LOCKED}
+	}
+	return nil
+}
+
+func (svc *migrationService) unlock() {
+	if err := svc.repo.ReleaseLock(svc.owner); err != nil {
+		fmt.Println(fmt.Errorf("Error on ReleaseLock : %v", err))
+	}
+}
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"userapi/config"
+
+	"github.com/golang/mock/gomock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var testConfig config.Config = config.Config{
+	MigrationLockTTL: time.Minute,
+}
+
+// Returns migrations appending their runs to the journal, the third one fails when failing is set
+func testMigrations(journal *[]string, failing bool) []Migration {
+	step := func(name string, fail bool) func(db *mongo.Database) error {
+		return func(db *mongo.Database) error {
+			if fail {
+				return fmt.Errorf("Any Error")
+			}
+			*journal = append(*journal, name)
+			return nil
+		}
+	}
+	return []Migration{
+		{Version: 3, Description: "third", Up: step("up 3", failing), Down: step("down 3", false)},
+		{Version: 1, Description: "first", Up: step("up 1", false), Down: nil},
+		{Version: 2, Description: "second", Up: step("up 2", false), Down: step("down 2", false)},
+	}
+}
+
+func applied(versions ...int64) []AppliedMigration {
+	migrations := make([]AppliedMigration, 0, len(versions))
+	for _, v := range versions {
+		migrations = append(migrations, AppliedMigration{Version: v, Description: fmt.Sprint(v)})
+	}
+	return migrations
+}
+
+func TestUp(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		setupMock       func(repo *MockMigrationRepository)
+		inputSteps      int
+		failing         bool
+		expectedJournal []string
+		expectedError   error
+	}{
+		{
+			name: "apply pending in version order",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(1), nil)
+				repo.EXPECT().InsertApplied(gomock.Any()).Return(nil).Times(2)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			expectedJournal: []string{"up 2", "up 3"},
+			expectedError:   nil,
+		},
+		{
+			name: "apply next steps",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(), nil)
+				repo.EXPECT().InsertApplied(gomock.Any()).Return(nil).Times(2)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			inputSteps:      2,
+			expectedJournal: []string{"up 1", "up 2"},
+			expectedError:   nil,
+		},
+		{
+			name: "stop on failed migration",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(1), nil)
+				repo.EXPECT().InsertApplied(gomock.Any()).Return(nil).Times(1)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			failing:         true,
+			expectedJournal: []string{"up 2"},
+			expectedError:   &migrationServiceError{code: MIGRATION_FAILED},
+		},
+		{
+			name: "locked by another process",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(false, nil)
+			},
+			expectedJournal: []string{},
+			expectedError:   &migrationServiceError{code: MIGRATION_LOCKED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockMigrationRepository(ctrl)
+			tc.setupMock(repo)
+
+			journal := []string{}
+			service := NewMigrationService(repo, nil, testMigrations(&journal, tc.failing), testConfig)
+
+			_, err := service.Up(tc.inputSteps)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(journal, tc.expectedJournal) {
+				t.Errorf("Expecting migrations %v , but runs %v", tc.expectedJournal, journal)
+			}
+		})
+	}
+}
+
+func TestDown(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		setupMock       func(repo *MockMigrationRepository)
+		inputSteps      int
+		expectedJournal []string
+		expectedError   error
+	}{
+		{
+			name: "revert latest",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(1, 2, 3), nil)
+				repo.EXPECT().DeleteApplied(int64(3)).Return(nil)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			inputSteps:      1,
+			expectedJournal: []string{"down 3"},
+			expectedError:   nil,
+		},
+		{
+			name: "stop on irreversible migration",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(1, 2), nil)
+				repo.EXPECT().DeleteApplied(int64(2)).Return(nil)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			inputSteps:      3,
+			expectedJournal: []string{"down 2"},
+			expectedError:   &migrationServiceError{code: MIGRATION_IRREVERSIBLE},
+		},
+		{
+			name: "migration unknown by this build",
+			setupMock: func(repo *MockMigrationRepository) {
+				repo.EXPECT().AcquireLock(gomock.Any(), gomock.Any(), gomock.Any()).Return(true, nil)
+				repo.EXPECT().ListApplied().Return(applied(1, 2, 3, 4), nil)
+				repo.EXPECT().ReleaseLock(gomock.Any()).Return(nil)
+			},
+			inputSteps:      1,
+			expectedJournal: []string{},
+			expectedError:   &migrationServiceError{code: MIGRATION_UNKNOWN},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockMigrationRepository(ctrl)
+			tc.setupMock(repo)
+
+			journal := []string{}
+			service := NewMigrationService(repo, nil, testMigrations(&journal, false), testConfig)
+
+			_, err := service.Down(tc.inputSteps)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(journal, tc.expectedJournal) {
+				t.Errorf("Expecting migrations %v , but runs %v", tc.expectedJournal, journal)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := NewMockMigrationRepository(ctrl)
+	repo.EXPECT().ListApplied().Return(applied(1, 4), nil)
+
+	journal := []string{}
+	service := NewMigrationService(repo, nil, testMigrations(&journal, false), testConfig)
+
+	statuses, err := service.Status()
+	if err != nil {
+		t.Fatalf("Expecting error %v , but returns %v", nil, err)
+	}
+
+	versions := make([]int64, 0)
+	pending := make([]int64, 0)
+	for _, status := range statuses {
+		versions = append(versions, status.Version)
+		if !status.Applied {
+			pending = append(pending, status.Version)
+		}
+	}
+	if !reflect.DeepEqual(versions, []int64{1, 2, 3, 4}) || !reflect.DeepEqual(pending, []int64{2, 3}) {
+		t.Errorf("Expecting versions [1 2 3 4] with [2 3] pending , but returns %v with %v pending", versions, pending)
+	}
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"crypto"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"userapi/apikeys"
+	"userapi/auth"
+	"userapi/config"
+	"userapi/identity"
+	"userapi/lockout"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const authRealm string = "userapi"
+
+// Error codes from RFC 6750
+const INVALID_REQUEST string = "invalid_request"
+const INVALID_TOKEN string = "invalid_token"
+
+// Authentication failure, returned as WWW-Authenticate challenge
+type AuthError struct {
+	Status      int
+	Code        string
+	Description string
+	// Seconds until the credentials may be tried again, when locked
+	RetryAfter string
+}
+
+// Authenticates requests using credentials of an Authorization header scheme
+type Authenticator interface {
+	// Authorization scheme handled, e.g. Basic or Bearer
+	Scheme() string
+	// Returns the principal authenticated by the credentials following the scheme
+	Authenticate(credentials string) (*identity.Principal, *AuthError)
+}
+
+// Authenticates requests using a key sent in its own header, e.g. X-API-Key
+type KeyAuthenticator interface {
+	// Header carrying the key
+	Header() string
+	// Returns the principal authenticated by the key
+	Authenticate(key string) (*identity.Principal, *AuthError)
+}
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var UNAUTHORIZED ErrorResponse = ErrorResponse{
+	Message: "Unauthorized",
+	Code:    "UNAUTHORIZED",
+}
+
+var INVALID_AUTHORIZATION ErrorResponse = ErrorResponse{
+	Message: "Invalid Authorization Header",
+	Code:    "INVALID_AUTHORIZATION",
+}
+
+var ACCOUNT_LOCKED ErrorResponse = ErrorResponse{
+	Message: "Account Temporarily Locked",
+	Code:    "ACCOUNT_LOCKED",
+}
+
+var TOO_MANY_REQUESTS ErrorResponse = ErrorResponse{
+	Message: "Too Many Requests",
+	Code:    "TOO_MANY_REQUESTS",
+}
+
+// Middleware accepting the key authenticator when its header is sent, otherwise any of the
+// authenticators, chosen by the Authorization header scheme.
+// The authenticated principal is put in request context.
+func authMiddleware(keys KeyAuthenticator, authenticators ...Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if keys != nil {
+			if key := ctx.GetHeader(keys.Header()); key != "" {
+				principal, authErr := keys.Authenticate(key)
+				if authErr != nil {
+					challenge(ctx, authenticators, nil, authErr)
+					return
+				}
+
+				identity.SetPrincipal(ctx, *principal)
+				ctx.Set(gin.AuthUserKey, principal.ID)
+				return
+			}
+		}
+
+		scheme, credentials, _ := strings.Cut(ctx.GetHeader("Authorization"), " ")
+
+		for _, authenticator := range authenticators {
+			if !strings.EqualFold(authenticator.Scheme(), scheme) {
+				continue
+			}
+
+			principal, authErr := authenticator.Authenticate(strings.TrimSpace(credentials))
+			if authErr != nil {
+				challenge(ctx, authenticators, authenticator, authErr)
+				return
+			}
+
+			identity.SetPrincipal(ctx, *principal)
+			ctx.Set(gin.AuthUserKey, principal.ID)
+			return
+		}
+
+		challenge(ctx, authenticators, nil, &AuthError{Status: http.StatusUnauthorized})
+	}
+}
+
+// Aborts the request with one challenge per authenticator, the failed one carrying the error
+func challenge(ctx *gin.Context, authenticators []Authenticator, failed Authenticator, authErr *AuthError) {
+	for _, authenticator := range authenticators {
+		value := fmt.Sprintf(`%s realm="%s"`, authenticator.Scheme(), authRealm)
+		if authenticator == failed && authErr.Code != "" {
+			value += fmt.Sprintf(`, error="%s"`, authErr.Code)
+			if authErr.Description != "" {
+				value += fmt.Sprintf(`, error_description="%s"`, authErr.Description)
+			}
+		}
+		ctx.Writer.Header().Add("WWW-Authenticate", value)
+	}
+
+	if authErr.Status == http.StatusBadRequest {
+		ctx.AbortWithStatusJSON(authErr.Status, INVALID_AUTHORIZATION)
+		return
+	}
+	if authErr.Status == http.StatusTooManyRequests {
+		ctx.Header("Retry-After", authErr.RetryAfter)
+		ctx.AbortWithStatusJSON(authErr.Status, ACCOUNT_LOCKED)
+		return
+	}
+	ctx.AbortWithStatusJSON(authErr.Status, UNAUTHORIZED)
+}
+
+type basicAuthenticator struct {
+	mutex    sync.RWMutex
+	accounts config.Accounts
+	lockout  lockout.LockoutService
+}
+
+// Authenticator of Basic scheme whose accounts can be replaced while serving requests
+type BasicAuthenticator interface {
+	Authenticator
+	// Replaces the accounts, requests already authenticated are not affected
+	Reload(accounts config.Accounts)
+}
+
+// Returns an authenticator of Basic scheme for the accounts, verifying bcrypt password hashes.
+// Failed attempts of each user are counted on lockout, throttling password guessing.
+func NewBasicAuthenticator(accounts config.Accounts, lockout lockout.LockoutService) BasicAuthenticator {
+	return &basicAuthenticator{accounts: accounts, lockout: lockout}
+}
+
+func (a *basicAuthenticator) Reload(accounts config.Accounts) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.accounts = accounts
+}
+
+func (a *basicAuthenticator) Scheme() string {
+	return "Basic"
+}
+
+func (a *basicAuthenticator) Authenticate(credentials string) (*identity.Principal, *AuthError) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+
+	user, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+
+	key := lockout.BasicKey(user)
+	if err := a.lockout.Check(key); err != nil {
+		if locked, ok := err.(*lockout.LockedError); ok {
+			return nil, &AuthError{Status: http.StatusTooManyRequests, RetryAfter: locked.RetryAfterSeconds()}
+		}
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+
+	a.mutex.RLock()
+	account, exists := a.accounts[user]
+	a.mutex.RUnlock()
+
+	if !exists {
+		// Compares anyway, so response time does not reveal existing users
+		bcrypt.CompareHashAndPassword(basicDummyHash, []byte(password))
+		a.lockout.Fail(key)
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		a.lockout.Fail(key)
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+	a.lockout.Reset(key)
+
+	return &identity.Principal{ID: user, Method: identity.BASIC_METHOD, Roles: account.Roles}, nil
+}
+
+var basicDummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+type bearerAuthenticator struct {
+	secret  []byte
+	keys    map[string]crypto.PublicKey
+	issuer  string
+	methods []string
+}
+
+/*
+Returns an authenticator of Bearer scheme for JWT access tokens.
+
+Tokens are verified with HS256 using JWT_SECRET and, when JWKS_FILE is set,
+with RS256 or ES256 using the public keys of the local JWKS file.
+*/
+func NewBearerAuthenticator(config config.Config) (Authenticator, error) {
+	a := &bearerAuthenticator{
+		issuer: config.JWTIssuer,
+	}
+
+	if config.JWTSecret != "" {
+		a.secret = []byte(config.JWTSecret)
+		a.methods = append(a.methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if config.JWKSFile != "" {
+		keys, err := loadJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+		a.methods = append(a.methods, jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg())
+	}
+
+	return a, nil
+}
+
+func (a *bearerAuthenticator) Scheme() string {
+	return "Bearer"
+}
+
+func (a *bearerAuthenticator) Authenticate(credentials string) (*identity.Principal, *AuthError) {
+	if credentials == "" || strings.Contains(credentials, " ") {
+		return nil, &AuthError{Status: http.StatusBadRequest, Code: INVALID_REQUEST, Description: "Malformed bearer token"}
+	}
+
+	var claims auth.AccessClaims
+	parser := jwt.NewParser(jwt.WithValidMethods(a.methods))
+	_, err := parser.ParseWithClaims(credentials, &claims, a.key)
+	if err != nil {
+		description := "The access token is invalid"
+		if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+			description = "The access token expired"
+		}
+		return nil, &AuthError{Status: http.StatusUnauthorized, Code: INVALID_TOKEN, Description: description}
+	}
+
+	if claims.ExpiresAt == nil || claims.Subject == "" || (a.issuer != "" && !claims.VerifyIssuer(a.issuer, true)) {
+		return nil, &AuthError{Status: http.StatusUnauthorized, Code: INVALID_TOKEN, Description: "The access token is invalid"}
+	}
+
+	return &identity.Principal{ID: claims.Subject, Method: identity.BEARER_METHOD, Roles: claims.Roles, EmailVerified: claims.EmailVerified}, nil
+}
+
+// Returns the key verifying the token signature, according to its algorithm and key id
+func (a *bearerAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(a.secret) == 0 {
+			return nil, fmt.Errorf("no secret to verify %s", token.Method.Alg())
+		}
+		return a.secret, nil
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+const API_KEY_HEADER string = "X-API-Key"
+
+type apiKeyAuthenticator struct {
+	service apikeys.APIKeyService
+}
+
+// Returns an authenticator of API keys sent on X-API-Key header, granting the key scopes
+func NewAPIKeyAuthenticator(service apikeys.APIKeyService) KeyAuthenticator {
+	return &apiKeyAuthenticator{service: service}
+}
+
+func (a *apiKeyAuthenticator) Header() string {
+	return API_KEY_HEADER
+}
+
+func (a *apiKeyAuthenticator) Authenticate(key string) (*identity.Principal, *AuthError) {
+	apiKey, err := a.service.Authenticate(key)
+	if err != nil {
+		return nil, &AuthError{Status: http.StatusUnauthorized}
+	}
+
+	return &identity.Principal{ID: apiKey.ID, Method: identity.API_KEY_METHOD, Scopes: apiKey.Scopes}, nil
+}
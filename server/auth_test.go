@@ -0,0 +1,285 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"userapi/apikeys"
+	"userapi/auth"
+	"userapi/config"
+	"userapi/identity"
+	"userapi/lockout"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testSecret string = "test-secret-with-at-least-32-characters"
+
+// Locks Basic auth users after 3 failures, without delaying the attempts before it
+func newTestLockout() lockout.LockoutService {
+	return lockout.NewLockoutService(lockout.NewMemoryAttemptRepository(), nil, config.Config{LockoutThreshold: 3, LockoutDuration: time.Minute})
+}
+
+func signToken(method jwt.SigningMethod, key interface{}, kid string, subject string, expiresAt time.Time) string {
+	token := jwt.NewWithClaims(method, auth.AccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    "userapi",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Roles: []string{"self"},
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, _ := token.SignedString(key)
+	return signed
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func TestAuthMiddleware(t *testing.T) {
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-key", "use": "sig", "n": "%s", "e": "%s"},
+		{"kty": "EC", "kid": "ec-key", "crv": "P-256", "x": "%s", "y": "%s"}
+	]}`,
+		encodeBigInt(rsaKey.N), encodeBigInt(big.NewInt(int64(rsaKey.E))),
+		encodeBigInt(ecKey.X), encodeBigInt(ecKey.Y))
+	if err := os.WriteFile(jwksFile, []byte(jwks), 0600); err != nil {
+		t.Fatalf("Error writing JWKS file : %v", err)
+	}
+
+	bearer, err := NewBearerAuthenticator(config.Config{JWTSecret: testSecret, JWTIssuer: "userapi", JWKSFile: jwksFile})
+	if err != nil {
+		t.Fatalf("Error creating bearer authenticator : %v", err)
+	}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("apipass"), bcrypt.MinCost)
+	basic := NewBasicAuthenticator(config.Accounts{"apiuser": {User: "apiuser", PasswordHash: string(hash), Roles: []string{"admin"}}}, newTestLockout())
+
+	ctrl := gomock.NewController(t)
+	apiKeyService := apikeys.NewMockAPIKeyService(ctrl)
+	apiKeyService.
+		EXPECT().
+		Authenticate("uak_valid").
+		Return(&apikeys.APIKey{ID: "key-1", Scopes: []string{"users:read"}}, nil).
+		AnyTimes()
+	apiKeyService.
+		EXPECT().
+		Authenticate(gomock.Any()).
+		Return(nil, fmt.Errorf(apikeys.API_KEY_INVALID)).
+		AnyTimes()
+	keys := NewAPIKeyAuthenticator(apiKeyService)
+
+	hour := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name              string
+		authorization     string
+		apiKey            string
+		expectedStatus    int
+		expectedPrincipal string
+		expectedChallenge []string
+	}{
+		{
+			name:              "basic success",
+			authorization:     "Basic " + base64.StdEncoding.EncodeToString([]byte("apiuser:apipass")),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "apiuser:basic:[admin]:[]",
+		},
+		{
+			name:              "basic wrong password",
+			authorization:     "Basic " + base64.StdEncoding.EncodeToString([]byte("apiuser:wrong")),
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: []string{`Basic realm="userapi"`, `Bearer realm="userapi"`},
+		},
+		{
+			name:              "basic unknown user",
+			authorization:     "Basic " + base64.StdEncoding.EncodeToString([]byte("other:apipass")),
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: []string{`Basic realm="userapi"`, `Bearer realm="userapi"`},
+		},
+		{
+			name:              "missing authorization",
+			authorization:     "",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: []string{`Basic realm="userapi"`, `Bearer realm="userapi"`},
+		},
+		{
+			name:              "bearer HS256 success",
+			authorization:     "Bearer " + signToken(jwt.SigningMethodHS256, []byte(testSecret), "", "user-1", hour),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "user-1:bearer:[self]:[]",
+		},
+		{
+			name:              "bearer RS256 success",
+			authorization:     "Bearer " + signToken(jwt.SigningMethodRS256, rsaKey, "rsa-key", "user-2", hour),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "user-2:bearer:[self]:[]",
+		},
+		{
+			name:              "bearer ES256 success",
+			authorization:     "bearer " + signToken(jwt.SigningMethodES256, ecKey, "ec-key", "user-3", hour),
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "user-3:bearer:[self]:[]",
+		},
+		{
+			name:           "bearer expired",
+			authorization:  "Bearer " + signToken(jwt.SigningMethodHS256, []byte(testSecret), "", "user-1", time.Now().Add(-time.Hour)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: []string{
+				`Basic realm="userapi"`,
+				`Bearer realm="userapi", error="invalid_token", error_description="The access token expired"`,
+			},
+		},
+		{
+			name:           "bearer wrong secret",
+			authorization:  "Bearer " + signToken(jwt.SigningMethodHS256, []byte("another-secret"), "", "user-1", hour),
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: []string{
+				`Basic realm="userapi"`,
+				`Bearer realm="userapi", error="invalid_token", error_description="The access token is invalid"`,
+			},
+		},
+		{
+			name:           "bearer unknown key id",
+			authorization:  "Bearer " + signToken(jwt.SigningMethodRS256, rsaKey, "other-key", "user-2", hour),
+			expectedStatus: http.StatusUnauthorized,
+			expectedChallenge: []string{
+				`Basic realm="userapi"`,
+				`Bearer realm="userapi", error="invalid_token", error_description="The access token is invalid"`,
+			},
+		},
+		{
+			name:           "bearer malformed",
+			authorization:  "Bearer ",
+			expectedStatus: http.StatusBadRequest,
+			expectedChallenge: []string{
+				`Basic realm="userapi"`,
+				`Bearer realm="userapi", error="invalid_request", error_description="Malformed bearer token"`,
+			},
+		},
+		{
+			name:              "api key success",
+			apiKey:            "uak_valid",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "key-1:apikey:[]:[users:read]",
+		},
+		{
+			name:              "api key over authorization",
+			authorization:     "Basic " + base64.StdEncoding.EncodeToString([]byte("apiuser:apipass")),
+			apiKey:            "uak_valid",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "key-1:apikey:[]:[users:read]",
+		},
+		{
+			name:              "api key invalid",
+			apiKey:            "uak_revoked",
+			expectedStatus:    http.StatusUnauthorized,
+			expectedChallenge: []string{`Basic realm="userapi"`, `Bearer realm="userapi"`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			r := gin.Default()
+			r.GET("/api/v1/users", authMiddleware(keys, basic, bearer), func(c *gin.Context) {
+				principal, _ := identity.GetPrincipal(c)
+				c.String(200, "%s:%s:%v:%v", principal.ID, principal.Method, principal.Roles, principal.Scopes)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/api/v1/users", nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			req.Header.Set("Authorization", tc.authorization)
+			if tc.apiKey != "" {
+				req.Header.Set("X-API-Key", tc.apiKey)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if tc.expectedPrincipal != "" && w.Body.String() != tc.expectedPrincipal {
+				t.Errorf("Expecting principal %s , but returns %s", tc.expectedPrincipal, w.Body.String())
+			}
+
+			challenges := w.Header().Values("WWW-Authenticate")
+			if fmt.Sprint(challenges) != fmt.Sprint(tc.expectedChallenge) {
+				t.Errorf("Expecting challenges %v , but returns %v", tc.expectedChallenge, challenges)
+			}
+		})
+	}
+}
+
+func TestBasicAuthenticatorReload(t *testing.T) {
+
+	oldHash, _ := bcrypt.GenerateFromPassword([]byte("oldpass"), bcrypt.MinCost)
+	newHash, _ := bcrypt.GenerateFromPassword([]byte("newpass"), bcrypt.MinCost)
+
+	basic := NewBasicAuthenticator(config.Accounts{"apiuser": {User: "apiuser", PasswordHash: string(oldHash)}}, newTestLockout())
+	basic.Reload(config.Accounts{"apiuser": {User: "apiuser", PasswordHash: string(newHash), Roles: []string{"support"}}})
+
+	if _, authErr := basic.Authenticate(base64.StdEncoding.EncodeToString([]byte("apiuser:oldpass"))); authErr == nil {
+		t.Errorf("Expecting old password rejected after reload")
+	}
+
+	principal, authErr := basic.Authenticate(base64.StdEncoding.EncodeToString([]byte("apiuser:newpass")))
+	if authErr != nil || fmt.Sprint(principal.Roles) != "[support]" {
+		t.Errorf("Expecting new password accepted with roles [support] , but returns %v %v", principal, authErr)
+	}
+}
+
+func TestBasicAuthenticatorLockout(t *testing.T) {
+
+	hash, _ := bcrypt.GenerateFromPassword([]byte("apipass"), bcrypt.MinCost)
+	basic := NewBasicAuthenticator(config.Accounts{"apiuser": {User: "apiuser", PasswordHash: string(hash)}}, newTestLockout())
+
+	wrong := base64.StdEncoding.EncodeToString([]byte("apiuser:wrong"))
+	right := base64.StdEncoding.EncodeToString([]byte("apiuser:apipass"))
+
+	// A success forgets the failures before it
+	basic.Authenticate(wrong)
+	basic.Authenticate(wrong)
+	if _, authErr := basic.Authenticate(right); authErr != nil {
+		t.Errorf("Expecting password accepted before lockout , but returns %v", authErr)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, authErr := basic.Authenticate(wrong); authErr == nil || authErr.Status != http.StatusUnauthorized {
+			t.Errorf("Expecting statusCode %d , but returns %v", http.StatusUnauthorized, authErr)
+		}
+	}
+
+	_, authErr := basic.Authenticate(right)
+	if authErr == nil || authErr.Status != http.StatusTooManyRequests || authErr.RetryAfter != "60" {
+		t.Errorf("Expecting statusCode %d retrying after 60 seconds , but returns %v", http.StatusTooManyRequests, authErr)
+	}
+
+	// Other users are not affected
+	if _, authErr := basic.Authenticate(base64.StdEncoding.EncodeToString([]byte("other:apipass"))); authErr == nil || authErr.Status != http.StatusUnauthorized {
+		t.Errorf("Expecting statusCode %d , but returns %v", http.StatusUnauthorized, authErr)
+	}
+}
@@ -60,7 +60,7 @@ func limitMiddleware(limiter *IPRateLimiter) func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
 		limiter := limiter.GetLimiter(ctx.ClientIP())
 		if !limiter.Allow() {
-			ctx.JSON(429, gin.H{})
+			ctx.AbortWithStatusJSON(429, TOO_MANY_REQUESTS)
 			return
 		}
 	}
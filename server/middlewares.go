@@ -1,11 +1,18 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"regexp"
+	"userapi/identity"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Request IDs accepted from callers, others are replaced so they can be logged and stored as they are
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
 func Cors(ctx *gin.Context) {
 	ctx.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 	ctx.Writer.Header().Set("Access-Control-Allow-Headers", "*")
@@ -16,3 +23,17 @@ func Cors(ctx *gin.Context) {
 		return
 	}
 }
+
+// Middleware identifying each request by the X-Request-ID received, or a new one,
+// which is returned on the response and recorded on the changes it makes
+func RequestID(ctx *gin.Context) {
+	requestID := ctx.GetHeader(identity.REQUEST_ID_HEADER)
+	if !requestIDPattern.MatchString(requestID) {
+		bytes := make([]byte, 16)
+		rand.Read(bytes)
+		requestID = hex.EncodeToString(bytes)
+	}
+
+	identity.SetRequestID(ctx, requestID)
+	ctx.Writer.Header().Set(identity.REQUEST_ID_HEADER, requestID)
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"userapi/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		inputHeader string
+		generated   bool
+	}{
+		{
+			name:        "request id received",
+			inputHeader: "5f0c2b6e-9a1d-4c3e-8f7a-2b1d0e9c8a7f",
+			generated:   false,
+		},
+		{
+			name:        "request id missing",
+			inputHeader: "",
+			generated:   true,
+		},
+		{
+			name:        "request id not printable",
+			inputHeader: "id with spaces\t",
+			generated:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			var requestID string
+			r := gin.New()
+			r.GET("/", RequestID, func(c *gin.Context) {
+				requestID = identity.GetRequestID(c)
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(identity.REQUEST_ID_HEADER, tc.inputHeader)
+			r.ServeHTTP(w, req)
+
+			if header := w.Header().Get(identity.REQUEST_ID_HEADER); header != requestID {
+				t.Errorf("Expecting header %s , but returns %s", requestID, header)
+			}
+			if tc.generated && (requestID == tc.inputHeader || len(requestID) != 32) {
+				t.Errorf("Expecting a generated request id , but returns %q", requestID)
+			}
+			if !tc.generated && requestID != tc.inputHeader {
+				t.Errorf("Expecting request id %s , but returns %s", tc.inputHeader, requestID)
+			}
+		})
+	}
+}
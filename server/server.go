@@ -5,8 +5,14 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"userapi/apikeys"
+	"userapi/auth"
 	"userapi/config"
+	"userapi/lockout"
+	"userapi/mfa"
 	"userapi/users"
+	"userapi/webhooks"
 
 	_ "userapi/docs"
 
@@ -19,18 +25,28 @@ import (
 
 type Server interface {
 	Run() error
+	Reload() error
 	Shutdown(ctx context.Context) error
 }
 
 type server struct {
 	config config.Config
 	srv    *http.Server
+	basic  BasicAuthenticator
+	// Failed attempts of Basic auth accounts, swept while the server runs
+	attempts lockout.AttemptRepository
+	// Stops background jobs on shutdown
+	stop context.CancelFunc
 }
 
 // Returns a new instance of Server
 func NewServer(config config.Config) Server {
+	// Basic auth accounts are not users, their attempts are counted in memory of each instance
+	attempts := lockout.NewMemoryAttemptRepository()
 	return &server{
-		config: config,
+		config:   config,
+		basic:    NewBasicAuthenticator(nil, lockout.NewLockoutService(attempts, nil, config)),
+		attempts: attempts,
 	}
 }
 
@@ -52,15 +68,56 @@ func (s *server) Run() error {
 		panic(err)
 	}
 
+	// Authentication
+	bearer, err := NewBearerAuthenticator(s.config)
+	if err != nil {
+		return err
+	}
+	accounts, err := s.config.LoadAccounts()
+	if err != nil {
+		return err
+	}
+	s.basic.Reload(accounts)
+	keys := NewAPIKeyAuthenticator(apikeys.NewAPIKeyService(apikeys.NewAPIKeyRepository(client, s.config.Database)))
+
+	// Users, shared by the routes and the background jobs
+	bus := users.NewEventBus(s.config.EventHistory)
+	passwords, err := users.NewPasswordPolicy(s.config)
+	if err != nil {
+		return err
+	}
+	userService, err := users.NewService(s.config, client, passwords, bus)
+	if err != nil {
+		return err
+	}
+
+	// Background jobs, started once nothing else can fail and stopped when the server stops
+	jobs, stop := context.WithCancel(context.Background())
+	s.stop = stop
+	defer stop()
+	users.StartPurger(jobs, s.config, userService)
+	users.StartRelay(jobs, s.config, client, s.publisher(client))
+	users.StartEventStream(jobs, s.config, client, bus)
+	webhooks.StartDeliverer(jobs, s.config, client)
+	lockout.StartSweeper(jobs, s.config, s.attempts)
+
 	// CORS
 	router.Use(Cors)
+	router.Use(RequestID)
 
-	apiV1 := router.Group("/api/v1", gin.BasicAuth(gin.Accounts{
-		s.config.ApiUser: s.config.ApiPass,
-	}))
-
+	apiV1 := router.Group("/api/v1")
 	apiV1.Use(limitMiddleware(limiter))
-	users.AddRoutes(apiV1, s.config, client)
+
+	// Authentication endpoints are public
+	auth.AddRoutes(apiV1, s.config, client, passwords, users.StreamPublisher(s.config, bus))
+	users.AddPublicRoutes(apiV1, userService)
+
+	protected := apiV1.Group("", authMiddleware(keys, s.basic, bearer))
+	users.AddRoutes(protected, s.config, userService, bus)
+	apikeys.AddRoutes(protected, s.config, client)
+	webhooks.AddRoutes(protected, s.config, client)
+	mfa.AddRoutes(protected, s.config, client)
+	lockout.AddRoutes(protected, s.config, client)
 
 	// API Documentation with swagger
 	router.GET("/doc/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -69,7 +126,42 @@ func (s *server) Run() error {
 	return s.srv.ListenAndServe()
 }
 
+// Returns the publisher of the user events relayed from the outbox to every EVENT_PUBLISHERS
+func (s *server) publisher(client *mongo.Client) users.Publisher {
+	publishers := make([]users.Publisher, 0, len(s.config.EventPublishers))
+	for _, name := range s.config.EventPublishers {
+		switch name {
+		case config.PUBLISHER_WEBHOOK:
+			publishers = append(publishers, webhooks.NewPublisher(s.config, client))
+		case config.PUBLISHER_STDOUT:
+			publishers = append(publishers, users.NewWriterPublisher(os.Stdout))
+		case config.PUBLISHER_MEMORY:
+			publishers = append(publishers, users.NewMemoryPublisher(s.config.EventHistory))
+		}
+	}
+	return users.NewMultiPublisher(publishers...)
+}
+
+// Method to reload Basic auth accounts from the credentials file.
+// On failure the accounts in use are kept.
+func (s *server) Reload() error {
+	if s.config.CredentialsFile == "" {
+		return nil
+	}
+
+	accounts, err := s.config.LoadAccounts()
+	if err != nil {
+		return err
+	}
+	s.basic.Reload(accounts)
+	fmt.Printf("Reloaded %d accounts from %s \n", len(accounts), s.config.CredentialsFile)
+	return nil
+}
+
 // Method to shutdown the server
 func (s *server) Shutdown(ctx context.Context) error {
+	if s.stop != nil {
+		s.stop()
+	}
 	return s.srv.Shutdown(ctx)
 }
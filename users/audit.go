@@ -0,0 +1,258 @@
+package users
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const AUDIT_CREATE string = "create"
+const AUDIT_UPDATE string = "update"
+const AUDIT_DELETE string = "delete"
+const AUDIT_RESTORE string = "restore"
+const AUDIT_PURGE string = "purge"
+
+var AuditActions = []string{AUDIT_CREATE, AUDIT_UPDATE, AUDIT_DELETE, AUDIT_RESTORE, AUDIT_PURGE}
+
+// Value recorded in place of passwords, only telling they were set
+const REDACTED string = "[REDACTED]"
+
+// Change made on a user, entries are only appended to the audit log
+type AuditEntry struct {
+	ID        string    `json:"id" bson:"_id,omitempty"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Action    string    `json:"action" bson:"action"`
+	Actor     string    `json:"actor" bson:"actor"`
+	RequestID string    `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	// User version resulting from the change
+	Version int64         `json:"version" bson:"version"`
+	Changes []FieldChange `json:"changes" bson:"changes"`
+}
+
+// Value of a field before and after a change, null when the field is missing
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+}
+
+// Parameters to list a page of audit entries, newest first
+type AuditQuery struct {
+	Next      string
+	Limit     int
+	UserID    string
+	Actor     string
+	Action    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+}
+
+// Page of audit entries returned by audit listing
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Next    string       `json:"next,omitempty"`
+}
+
+// Audit entries matching a query, after the entry of AfterID
+type AuditCriteria struct {
+	UserID    string
+	Actor     string
+	Action    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	AfterID   string
+}
+
+func (c AuditCriteria) filterBSON() (bson.M, error) {
+	filter := bson.M{}
+	if c.UserID != "" {
+		filter["userId"] = bson.M{"$eq": c.UserID}
+	}
+	if c.Actor != "" {
+		filter["actor"] = bson.M{"$eq": c.Actor}
+	}
+	if c.Action != "" {
+		filter["action"] = bson.M{"$eq": c.Action}
+	}
+	if c.RequestID != "" {
+		filter["requestId"] = bson.M{"$eq": c.RequestID}
+	}
+
+	timestamp := bson.M{}
+	if c.From != nil {
+		timestamp["$gte"] = c.From.UTC()
+	}
+	if c.To != nil {
+		timestamp["$lte"] = c.To.UTC()
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	// Entries are listed newest first, so the next page holds older entries
+	if c.AfterID != "" {
+		objID, err := primitive.ObjectIDFromHex(c.AfterID)
+		if err != nil {
+			return nil, fmt.Errorf(INVALID_OBJECT_ID)
+		}
+		filter["_id"] = bson.M{"$lt": objID}
+	}
+	return filter, nil
+}
+
+func isAuditAction(action string) bool {
+	for _, a := range AuditActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the values of the audited fields of a user, fields missing on the user are left out
+func auditValues(u *User) map[string]interface{} {
+	values := map[string]interface{}{}
+	if u == nil {
+		return values
+	}
+	for field, get := range UserAccess {
+		// Passwords are compared apart, stored hashes are never read
+		if value := get(u); value != "" && field != "password" {
+			values[field] = value
+		}
+	}
+	if len(u.Roles) > 0 {
+		values["roles"] = u.Roles
+	}
+	values["emailVerified"] = u.EmailVerified
+	if u.VerifiedAt != nil {
+		values["verifiedAt"] = *u.VerifiedAt
+	}
+	return values
+}
+
+/*
+Returns the fields changed from before to after, in field order.
+
+A nil user stands for a user not existing, so every field of the other user
+is changed. A password set on after is a new password, recorded as REDACTED.
+*/
+func auditChanges(before *User, after *User) []FieldChange {
+	old, new := auditValues(before), auditValues(after)
+
+	fields := make([]string, 0, len(old)+len(new))
+	for field := range old {
+		fields = append(fields, field)
+	}
+	for field := range new {
+		if _, ok := old[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := make([]FieldChange, 0)
+	for _, field := range fields {
+		if !reflect.DeepEqual(old[field], new[field]) {
+			changes = append(changes, FieldChange{Field: field, Before: old[field], After: new[field]})
+		}
+	}
+
+	if after != nil && after.Password != "" {
+		change := FieldChange{Field: "password", After: REDACTED}
+		if before != nil {
+			change.Before = REDACTED
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+/*
+Returns the audit entry of the change made as action by actor, from the user stored
+before the change, nil on creation, and after it, nil on purge. Passwords stored on
+both users are only recorded when they differ.
+
+Deleted users are recorded with every field removed, while restored and purged users
+are recorded without field changes, as they were when deleted.
+*/
+func newAuditEntry(actor Actor, action string, before *User, after *User) AuditEntry {
+	old, new := before, after
+	if old != nil && new != nil && old.Password == new.Password {
+		unchanged := *new
+		unchanged.Password = ""
+		new = &unchanged
+	}
+	switch action {
+	case AUDIT_DELETE:
+		new = nil
+	case AUDIT_RESTORE, AUDIT_PURGE:
+		old, new = nil, nil
+	}
+
+	entry := AuditEntry{
+		Action:    action,
+		Actor:     actor.ID,
+		RequestID: actor.RequestID,
+		Timestamp: time.Now().UTC(),
+		Changes:   auditChanges(old, new),
+	}
+	if after != nil {
+		entry.UserID, entry.Version = after.ID, after.Version
+	} else {
+		entry.UserID, entry.Version = before.ID, before.Version+1
+	}
+	return entry
+}
+
+func (svc *userService) GetUserHistory(userID string, query AuditQuery) (*AuditPage, error) {
+	if !primitive.IsValidObjectID(userID) {
+		fmt.Println(fmt.Errorf("Invalid user id : %s", userID))
+		return nil, &userServiceError{code: USER_ID_INVALID}
+	}
+	query.UserID = userID
+	return svc.ListAuditEntries(query)
+}
+
+func (svc *userService) ListAuditEntries(query AuditQuery) (*AuditPage, error) {
+	if query.UserID != "" && !primitive.IsValidObjectID(query.UserID) {
+		fmt.Println(fmt.Errorf("Invalid user id : %s", query.UserID))
+		return nil, &userServiceError{code: USER_ID_INVALID}
+	}
+	if query.Next != "" && !primitive.IsValidObjectID(query.Next) {
+		fmt.Println(fmt.Errorf("Invalid page token : %s", query.Next))
+		return nil, &userServiceError{code: PAGE_TOKEN_INVALID}
+	}
+
+	criteria := AuditCriteria{
+		UserID:    query.UserID,
+		Actor:     query.Actor,
+		Action:    query.Action,
+		RequestID: query.RequestID,
+		From:      query.From,
+		To:        query.To,
+		AfterID:   query.Next,
+	}
+	limit := svc.pageSize(query.Limit)
+
+	// Fetch one more entry than requested to know if there is a next page
+	entries, err := svc.audits.ListEntries(criteria, limit+1)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListEntries : %v", err))
+		return nil, &userServiceError{code: LIST_AUDIT_FAILED}
+	}
+
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.Next = page.Entries[limit-1].ID
+	}
+	return page, nil
+}
@@ -0,0 +1,242 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestAuditChanges(t *testing.T) {
+
+	verifiedAt := time.Date(2023, 3, 30, 10, 0, 0, 0, time.UTC)
+	user := User{Name: "Test", Email: "test@test.com", Roles: []string{ROLE_SELF}}
+
+	tests := []struct {
+		name            string
+		inputBefore     *User
+		inputAfter      *User
+		expectedChanges []FieldChange
+	}{
+		{
+			name:        "created user with redacted password",
+			inputBefore: nil,
+			inputAfter:  &User{Name: "Test", Email: "test@test.com", Password: "hash", Roles: []string{ROLE_SELF}},
+			expectedChanges: []FieldChange{
+				{Field: "email", Before: nil, After: "test@test.com"},
+				{Field: "emailVerified", Before: nil, After: false},
+				{Field: "name", Before: nil, After: "Test"},
+				{Field: "roles", Before: nil, After: []string{ROLE_SELF}},
+				{Field: "password", Before: nil, After: REDACTED},
+			},
+		},
+		{
+			name:        "changed and removed fields",
+			inputBefore: &User{Name: "Test", Email: "test@test.com", Age: "33", Roles: []string{ROLE_SELF}},
+			inputAfter:  &User{Name: "New Name", Email: "test@test.com", Roles: []string{ROLE_SELF}},
+			expectedChanges: []FieldChange{
+				{Field: "age", Before: "33", After: nil},
+				{Field: "name", Before: "Test", After: "New Name"},
+			},
+		},
+		{
+			name:        "new password",
+			inputBefore: &user,
+			inputAfter:  &User{Name: "Test", Email: "test@test.com", Password: "hash", Roles: []string{ROLE_SELF}},
+			expectedChanges: []FieldChange{
+				{Field: "password", Before: REDACTED, After: REDACTED},
+			},
+		},
+		{
+			name:        "verified email",
+			inputBefore: &user,
+			inputAfter:  &User{Name: "Test", Email: "test@test.com", Roles: []string{ROLE_SELF}, EmailVerified: true, VerifiedAt: &verifiedAt},
+			expectedChanges: []FieldChange{
+				{Field: "emailVerified", Before: false, After: true},
+				{Field: "verifiedAt", Before: nil, After: verifiedAt},
+			},
+		},
+		{
+			name:        "deleted user",
+			inputBefore: &user,
+			inputAfter:  nil,
+			expectedChanges: []FieldChange{
+				{Field: "email", Before: "test@test.com", After: nil},
+				{Field: "emailVerified", Before: false, After: nil},
+				{Field: "name", Before: "Test", After: nil},
+				{Field: "roles", Before: []string{ROLE_SELF}, After: nil},
+			},
+		},
+		{
+			name:            "nothing changed",
+			inputBefore:     &user,
+			inputAfter:      &user,
+			expectedChanges: []FieldChange{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			changes := auditChanges(tc.inputBefore, tc.inputAfter)
+
+			if !reflect.DeepEqual(changes, tc.expectedChanges) {
+				t.Errorf("Expecting changes %v , but returns %v", tc.expectedChanges, changes)
+			}
+		})
+	}
+}
+
+func TestNewAuditEntry(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	before := User{ID: userID, Name: "Test", Email: "test@test.com", Password: "hash", Version: 3}
+	renamed := before
+	renamed.Name, renamed.Version = "New Name", 4
+	reset := before
+	reset.Password, reset.Version = "new hash", 4
+
+	tests := []struct {
+		name            string
+		inputAction     string
+		inputBefore     *User
+		inputAfter      *User
+		expectedVersion int64
+		expectedChanges []FieldChange
+	}{
+		{
+			name:            "password unchanged",
+			inputAction:     AUDIT_UPDATE,
+			inputBefore:     &before,
+			inputAfter:      &renamed,
+			expectedVersion: 4,
+			expectedChanges: []FieldChange{{Field: "name", Before: "Test", After: "New Name"}},
+		},
+		{
+			name:            "password reset",
+			inputAction:     AUDIT_UPDATE,
+			inputBefore:     &before,
+			inputAfter:      &reset,
+			expectedVersion: 4,
+			expectedChanges: []FieldChange{{Field: "password", Before: REDACTED, After: REDACTED}},
+		},
+		{
+			name:            "restored user",
+			inputAction:     AUDIT_RESTORE,
+			inputBefore:     &before,
+			inputAfter:      &renamed,
+			expectedVersion: 4,
+			expectedChanges: []FieldChange{},
+		},
+		{
+			name:            "purged user",
+			inputAction:     AUDIT_PURGE,
+			inputBefore:     &before,
+			inputAfter:      nil,
+			expectedVersion: 4,
+			expectedChanges: []FieldChange{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			entry := newAuditEntry(testActor, tc.inputAction, tc.inputBefore, tc.inputAfter)
+
+			expected := AuditEntry{
+				UserID:    userID,
+				Action:    tc.inputAction,
+				Actor:     testActor.ID,
+				RequestID: testActor.RequestID,
+				Timestamp: entry.Timestamp,
+				Version:   tc.expectedVersion,
+				Changes:   tc.expectedChanges,
+			}
+			if !reflect.DeepEqual(entry, expected) {
+				t.Errorf("Expecting entry %v , but returns %v", expected, entry)
+			}
+		})
+	}
+}
+
+func TestServiceGetUserHistory(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	entries := []AuditEntry{
+		{ID: "64260e1da4c0c814bda57353", UserID: userID, Action: AUDIT_UPDATE},
+		{ID: "64260e1da4c0c814bda57352", UserID: userID, Action: AUDIT_UPDATE},
+		{ID: "64260e1da4c0c814bda57351", UserID: userID, Action: AUDIT_CREATE},
+	}
+
+	tests := []struct {
+		name          string
+		setupMock     func(audits *MockAuditRepository)
+		inputUserID   string
+		inputQuery    AuditQuery
+		expectedPage  *AuditPage
+		expectedError error
+	}{
+		{
+			name: "page with next page",
+			setupMock: func(audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: userID, Action: AUDIT_UPDATE}, 3).
+					Return(entries, nil)
+			},
+			inputUserID:   userID,
+			inputQuery:    AuditQuery{Limit: 2, Action: AUDIT_UPDATE},
+			expectedPage:  &AuditPage{Entries: entries[:2], Next: entries[1].ID},
+			expectedError: nil,
+		},
+		{
+			name: "last page",
+			setupMock: func(audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: userID, AfterID: entries[1].ID}, 3).
+					Return(entries[2:], nil)
+			},
+			inputUserID:   userID,
+			inputQuery:    AuditQuery{Limit: 2, Next: entries[1].ID},
+			expectedPage:  &AuditPage{Entries: entries[2:]},
+			expectedError: nil,
+		},
+		{
+			name:          "invalid user id",
+			setupMock:     func(audits *MockAuditRepository) {},
+			inputUserID:   "any id invalid",
+			expectedError: &userServiceError{code: USER_ID_INVALID},
+		},
+		{
+			name:          "invalid page token",
+			setupMock:     func(audits *MockAuditRepository) {},
+			inputUserID:   userID,
+			inputQuery:    AuditQuery{Next: "any token"},
+			expectedError: &userServiceError{code: PAGE_TOKEN_INVALID},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			audits := NewMockAuditRepository(ctrl)
+			tc.setupMock(audits)
+
+			service := NewUserService(NewMockUserRepository(ctrl), NewMockVerificationRepository(ctrl), audits, nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{PageSize: 5})
+
+			page, err := service.GetUserHistory(tc.inputUserID, tc.inputQuery)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(page, tc.expectedPage) {
+				t.Errorf("Expecting page %v , but returns %v", tc.expectedPage, page)
+			}
+		})
+	}
+}
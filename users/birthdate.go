@@ -0,0 +1,62 @@
+package users
+
+import (
+	"strconv"
+	"time"
+)
+
+// Birth dates are ISO dates, so they sort and range query as strings
+const BIRTH_DATE_LAYOUT string = "2006-01-02"
+
+const MAX_AGE int = 150
+
+// Layouts of legacy ages holding a date instead of a number, dates with slashes are day first
+var legacyDateLayouts = []string{BIRTH_DATE_LAYOUT, "02/01/2006", "2006/01/02", "02-01-2006"}
+
+func parseBirthDate(value string) (time.Time, bool) {
+	birthDate, err := time.Parse(BIRTH_DATE_LAYOUT, value)
+	return birthDate, err == nil
+}
+
+// Returns the age in full years on the day of now
+func ageAt(birthDate time.Time, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// Converts a legacy age holding a date into the birth date, a numeric age can not be converted
+func legacyBirthDate(age string) (string, bool) {
+	for _, layout := range legacyDateLayouts {
+		if birthDate, err := time.Parse(layout, age); err == nil {
+			return birthDate.Format(BIRTH_DATE_LAYOUT), true
+		}
+	}
+	return "", false
+}
+
+/*
+Prepares the age of a user received as input.
+
+The birth date prevails over the age, which is computed on read. A legacy age is
+converted into the birth date when it holds a date, otherwise it is kept as is.
+*/
+func (u *User) normalizeAge() {
+	if u.BirthDate == "" && u.Age != "" {
+		if birthDate, ok := legacyBirthDate(u.Age); ok {
+			u.BirthDate = birthDate
+		}
+	}
+	if u.BirthDate != "" {
+		u.Age = ""
+	}
+}
+
+// Fills the age computed from the birth date, users without one keep their legacy age
+func (u *User) computeAge(now time.Time) {
+	if birthDate, ok := parseBirthDate(u.BirthDate); ok {
+		u.Age = strconv.Itoa(ageAt(birthDate, now.UTC()))
+	}
+}
@@ -0,0 +1,79 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserNormalizeAge(t *testing.T) {
+
+	tests := []struct {
+		name              string
+		inputParam        User
+		expectedAge       string
+		expectedBirthDate string
+	}{
+		{
+			name:              "birth date prevails over age",
+			inputParam:        User{Age: "33", BirthDate: "1990-05-20"},
+			expectedAge:       "",
+			expectedBirthDate: "1990-05-20",
+		},
+		{
+			name:              "legacy age holding an ISO date",
+			inputParam:        User{Age: "1990-05-20"},
+			expectedAge:       "",
+			expectedBirthDate: "1990-05-20",
+		},
+		{
+			name:              "legacy age holding a day first date",
+			inputParam:        User{Age: "20/05/1990"},
+			expectedAge:       "",
+			expectedBirthDate: "1990-05-20",
+		},
+		{
+			name:              "numeric legacy age is kept",
+			inputParam:        User{Age: "33"},
+			expectedAge:       "33",
+			expectedBirthDate: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			user := tc.inputParam
+			user.normalizeAge()
+
+			if user.Age != tc.expectedAge || user.BirthDate != tc.expectedBirthDate {
+				t.Errorf("Expecting age %s and birth date %s , but returns %s and %s", tc.expectedAge, tc.expectedBirthDate, user.Age, user.BirthDate)
+			}
+		})
+	}
+}
+
+func TestUserComputeAge(t *testing.T) {
+
+	now := time.Date(2023, time.May, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		inputParam  User
+		expectedAge string
+	}{
+		{name: "birthday today", inputParam: User{BirthDate: "1990-05-20"}, expectedAge: "33"},
+		{name: "birthday tomorrow", inputParam: User{BirthDate: "1990-05-21"}, expectedAge: "32"},
+		{name: "born on leap day", inputParam: User{BirthDate: "2000-02-29"}, expectedAge: "23"},
+		{name: "legacy age without birth date", inputParam: User{Age: "40"}, expectedAge: "40"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			user := tc.inputParam
+			user.computeAge(now)
+
+			if user.Age != tc.expectedAge {
+				t.Errorf("Expecting age %s , but returns %s", tc.expectedAge, user.Age)
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@ package users
 
 import (
 	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+	"userapi/identity"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,13 +26,16 @@ func NewUserController(service UserService) UserController {
 //
 //	@Summary		Create new user
 //	@Description	This endpoint creates a new user from user data in request body.
+//	@Description	The password must follow the password policy. Every rule violated is listed in a single 422 response.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			request	body		User	true	"body"
 //	@Success		201		{object}	UserID
 //	@Failure		401
+//	@Failure		403		{object}	UserResponse
 //	@Failure		400		{object}	UserResponse
+//	@Failure		422		{object}	ValidationErrors
 //	@Failure		502		{object}	UserResponse
 //	@Router			/users [post]
 func (ctr UserController) CreateUser(c *gin.Context) {
@@ -40,16 +47,14 @@ func (ctr UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
-	validation := ValidateUser(user)
-	if validation != nil {
-		c.JSON(400, validation)
-		return
-	}
-
 	var userID string
-	userID, err = ctr.service.CreateUser(user)
+	userID, err = ctr.service.CreateUser(actorOf(c), user)
 
 	if err != nil {
+		if validation, ok := err.(*ValidationErrors); ok {
+			c.JSON(422, validation)
+			return
+		}
 		if err.Error() == USER_EXISTS {
 			c.JSON(400, USER_ALREADY_EXISTS)
 			return
@@ -65,18 +70,32 @@ func (ctr UserController) CreateUser(c *gin.Context) {
 //
 //	@Summary		Return user data
 //	@Description	This endpoint returns a user by user id.
+//	@Description	With asOf, the user is returned as it was at that time, rebuilt from its history; only users
+//	@Description	allowed to read any user can ask for it and no ETag is returned.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id		path		string	true	"userID"
-//	@Success		201		{object}	UserID
+//	@Param			id				path		string	true	"userID"
+//	@Param			asOf			query		string	false	"time the user is returned as of, RFC 3339"
+//	@Param			If-None-Match	header		string	false	"user ETag"
+//	@Success		200				{object}	User
+//	@Header			200				{string}	ETag	"user version"
+//	@Success		304
 //	@Failure		401
-//	@Failure		400		{object}	UserResponse
-//	@Failure		502		{object}	UserResponse
+//	@Failure		403		{object}	UserResponse
+//	@Failure		400				{object}	UserResponse
+//	@Failure		404				{object}	UserResponse
+//	@Failure		409				{object}	UserResponse
+//	@Failure		502				{object}	UserResponse
 //	@Router			/users/{id} [get]
 func (ctr UserController) GetUser(c *gin.Context) {
 	var userID string = c.Param("id")
 
+	if c.Query("asOf") != "" {
+		ctr.getUserAsOf(c, userID)
+		return
+	}
+
 	user, err := ctr.service.GetUser(userID)
 	if err != nil {
 		if err.Error() == USER_ID_INVALID {
@@ -92,22 +111,142 @@ func (ctr UserController) GetUser(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", UserETag(user.Version))
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && MatchesIfNoneMatch(ifNoneMatch, user.Version) {
+		c.Status(304)
+		return
+	}
+
+	c.JSON(200, user)
+}
+
+func (ctr UserController) getUserAsOf(c *gin.Context, userID string) {
+	asOf, err := time.Parse(time.RFC3339, c.Query("asOf"))
+	if err != nil {
+		c.JSON(400, INVALID_AS_OF)
+		return
+	}
+
+	// Past data is read from the history, which is only shown to those reading any user
+	principal, ok := identity.GetPrincipal(c)
+	if !ok || !IsGranted(principal, PERMISSION_READ) {
+		c.JSON(403, ACCESS_DENIED)
+		return
+	}
+
+	user, err := ctr.service.GetUserAsOf(userID, asOf)
+	if err != nil {
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == HISTORY_INCOMPLETE {
+			c.JSON(409, USER_HISTORY_INCOMPLETE)
+			return
+		}
+		c.JSON(502, USER_FIND_FAILED)
+		return
+	}
+
 	c.JSON(200, user)
 }
 
+// ListUsers godoc
+//
+//	@Summary		List users
+//	@Description	This endpoint returns a page of users. Use the next token from a page to fetch the following one.
+//	@Description	Users can be filtered by email, name (prefix), address.city, address.state, address.country
+//	@Description	and birthDate (range as from..to) and sorted by any of those fields, descending when prefixed by "-".
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"page size"
+//	@Param			next	query		string	false	"next page token"
+//	@Param			sort	query		string	false	"sort fields, e.g. -name,email"
+//	@Param			email	query		string	false	"email"
+//	@Param			name	query		string	false	"name prefix"
+//	@Param			address.city	query	string	false	"address city"
+//	@Param			address.state	query	string	false	"address state"
+//	@Param			address.country	query	string	false	"address country"
+//	@Param			birthDate	query	string	false	"birth date range, e.g. 1990-01-01..1999-12-31"
+//	@Param			includeDeleted	query	bool	false	"list soft deleted users as well, admins only"
+//	@Success		200		{object}	UserPage
+//	@Failure		401
+//	@Failure		403		{object}	UserResponse
+//	@Failure		400		{object}	UserResponse
+//	@Failure		502		{object}	UserResponse
+//	@Router			/users [get]
+func (ctr UserController) ListUsers(c *gin.Context) {
+	filters, sort, validation := ParseListCriteria(c.Request.URL.Query())
+	if validation != nil {
+		c.JSON(400, validation)
+		return
+	}
+
+	query := ListQuery{Next: c.Query("next"), Filters: filters, Sort: sort}
+
+	if includeDeleted := c.Query("includeDeleted"); includeDeleted != "" {
+		value, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			c.JSON(400, INVALID_FILTER_VALUE)
+			return
+		}
+		// Deleted users are only listed to those who can restore them
+		principal, ok := identity.GetPrincipal(c)
+		if value && (!ok || !IsGranted(principal, PERMISSION_DELETE)) {
+			c.JSON(403, ACCESS_DENIED)
+			return
+		}
+		query.IncludeDeleted = value
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			c.JSON(400, INVALID_PAGE_SIZE)
+			return
+		}
+		query.Limit = value
+	}
+
+	page, err := ctr.service.ListUsers(query)
+	if err != nil {
+		if err.Error() == PAGE_TOKEN_INVALID {
+			c.JSON(400, INVALID_PAGE_TOKEN)
+			return
+		}
+		c.JSON(502, USER_LIST_FAILED)
+		return
+	}
+
+	c.JSON(200, page)
+}
+
 // UpdateUser godoc
 //
 //	@Summary		Update user
-//	@Description	This endpoint updates a user from user data in request body.
+//	@Description	This endpoint fully replaces a user with user data in request body.
+//	@Description	Fields not informed are cleared, except password which is kept when not informed.
+//	@Description	Every rule violated is listed in a single 422 response.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		string	true	"userID"
-//	@Param			request	body		User	true	"body"
-//	@Success		200	{object}	UserResponse
+//	@Param			id			path		string	true	"userID"
+//	@Param			If-Match	header		string	false	"user ETag"
+//	@Param			request		body		User	true	"body"
+//	@Success		200			{object}	UserResponse
+//	@Header			200			{string}	ETag	"new user version"
 //	@Failure		401
-//	@Failure		400	{object}	UserResponse
-//	@Failure		502	{object}	UserResponse
+//	@Failure		403		{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		404			{object}	UserResponse
+//	@Failure		412			{object}	UserResponse
+//	@Failure		422			{object}	ValidationErrors
+//	@Failure		502			{object}	UserResponse
 //	@Router			/users/{id} [put]
 func (ctr UserController) UpdateUser(c *gin.Context) {
 	var user User
@@ -119,43 +258,512 @@ func (ctr UserController) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	if err := ctr.service.UpdateUser(userID, user); err != nil {
+	precondition, ok := ParseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(412, USER_VERSION_MISMATCH)
+		return
+	}
+
+	version, err := ctr.service.UpdateUser(actorOf(c), userID, user, precondition)
+	if err != nil {
+		if validation, ok := err.(*ValidationErrors); ok {
+			c.JSON(422, validation)
+			return
+		}
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == USER_EXISTS {
+			c.JSON(400, USER_ALREADY_EXISTS)
+			return
+		}
+		if err.Error() == PRECONDITION_FAILED {
+			c.JSON(412, USER_VERSION_MISMATCH)
+			return
+		}
+		c.JSON(502, USER_UPDATE_FAILED)
+		return
+	}
+
+	c.Header("ETag", UserETag(version))
+	c.JSON(200, USER_UPDATED)
+}
+
+// PatchUser godoc
+//
+//	@Summary		Partially update user
+//	@Description	This endpoint partially updates a user with a JSON Merge Patch (RFC 7396)
+//	@Description	or a JSON Patch (RFC 6902) document in request body. Fields set to null or removed are cleared.
+//	@Description	Every rule violated is listed in a single 422 response.
+//	@Tags			users
+//	@Accept			application/merge-patch+json
+//	@Accept			application/json-patch+json
+//	@Produce		json
+//	@Param			id			path		string	true	"userID"
+//	@Param			If-Match	header		string	false	"user ETag"
+//	@Param			request		body		object	true	"patch document"
+//	@Success		200			{object}	UserResponse
+//	@Header			200			{string}	ETag	"new user version"
+//	@Failure		401
+//	@Failure		403		{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		404			{object}	UserResponse
+//	@Failure		412			{object}	UserResponse
+//	@Failure		415			{object}	UserResponse
+//	@Failure		422			{object}	ValidationErrors
+//	@Failure		502			{object}	UserResponse
+//	@Router			/users/{id} [patch]
+func (ctr UserController) PatchUser(c *gin.Context) {
+	var userID string = c.Param("id")
+
+	contentType := c.ContentType()
+	if contentType != MERGE_PATCH_CONTENT_TYPE && contentType != JSON_PATCH_CONTENT_TYPE {
+		c.JSON(415, UNSUPPORTED_PATCH_TYPE)
+		return
+	}
+
+	document, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(400, INVALID_PATCH)
+		return
+	}
+
+	precondition, ok := ParseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(412, USER_VERSION_MISMATCH)
+		return
+	}
+
+	patch := UserPatch{ContentType: contentType, Document: document}
+	version, err := ctr.service.PatchUser(actorOf(c), userID, patch, precondition)
+	if err != nil {
+		if validation, ok := err.(*ValidationErrors); ok {
+			c.JSON(422, validation)
+			return
+		}
 		if err.Error() == USER_ID_INVALID {
 			c.JSON(400, INVALID_USER_ID)
 			return
 		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == USER_EXISTS {
+			c.JSON(400, USER_ALREADY_EXISTS)
+			return
+		}
+		if err.Error() == PATCH_INVALID {
+			c.JSON(400, INVALID_PATCH)
+			return
+		}
+		if err.Error() == PRECONDITION_FAILED {
+			c.JSON(412, USER_VERSION_MISMATCH)
+			return
+		}
 		c.JSON(502, USER_UPDATE_FAILED)
 		return
 	}
 
+	c.Header("ETag", UserETag(version))
 	c.JSON(200, USER_UPDATED)
 }
 
+// RevertUser godoc
+//
+//	@Summary		Revert user to a revision
+//	@Description	This endpoint replaces a user with the data it had on a revision, the user version listed on its history.
+//	@Description	The revision is applied as a new update: it is validated, roles and password are kept and it is recorded on the history.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"userID"
+//	@Param			rev			path		int		true	"revision"
+//	@Param			If-Match	header		string	false	"user ETag"
+//	@Success		200			{object}	UserResponse
+//	@Header			200			{string}	ETag	"new user version"
+//	@Failure		401
+//	@Failure		403			{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		404			{object}	UserResponse
+//	@Failure		409			{object}	UserResponse
+//	@Failure		412			{object}	UserResponse
+//	@Failure		422			{object}	ValidationErrors
+//	@Failure		502			{object}	UserResponse
+//	@Router			/users/{id}/revisions/{rev}/revert [post]
+func (ctr UserController) RevertUser(c *gin.Context) {
+	var userID string = c.Param("id")
+
+	revision, err := strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil || revision <= 0 {
+		c.JSON(400, INVALID_REVISION)
+		return
+	}
+
+	precondition, ok := ParseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(412, USER_VERSION_MISMATCH)
+		return
+	}
+
+	version, err := ctr.service.RevertUser(actorOf(c), userID, revision, precondition)
+	if err != nil {
+		if validation, ok := err.(*ValidationErrors); ok {
+			c.JSON(422, validation)
+			return
+		}
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == REVISION_NOT_FOUND {
+			c.JSON(404, USER_REVISION_NOT_FOUND)
+			return
+		}
+		if err.Error() == HISTORY_INCOMPLETE {
+			c.JSON(409, USER_HISTORY_INCOMPLETE)
+			return
+		}
+		if err.Error() == USER_EXISTS {
+			c.JSON(400, USER_ALREADY_EXISTS)
+			return
+		}
+		if err.Error() == PRECONDITION_FAILED {
+			c.JSON(412, USER_VERSION_MISMATCH)
+			return
+		}
+		c.JSON(502, USER_UPDATE_FAILED)
+		return
+	}
+
+	c.Header("ETag", UserETag(version))
+	c.JSON(200, USER_REVERTED)
+}
+
 // DeleteUser godoc
 //
 //	@Summary		Delete user
-//	@Description	This endpoint delete a user by user id.
+//	@Description	This endpoint soft deletes a user by user id, the user can be restored until it is purged.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		string	true	"userID"
-//	@Success		200	{object}	UserResponse
+//	@Param			id			path		string	true	"userID"
+//	@Param			If-Match	header		string	false	"user ETag"
+//	@Success		200			{object}	UserResponse
 //	@Failure		401
-//	@Failure		400	{object}	UserResponse
-//	@Failure		502	{object}	UserResponse
+//	@Failure		403		{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		404			{object}	UserResponse
+//	@Failure		412			{object}	UserResponse
+//	@Failure		502			{object}	UserResponse
 //	@Router			/users/{id} [delete]
 func (ctr UserController) DeleteUser(c *gin.Context) {
 	var userID string = c.Param("id")
 
-	err := ctr.service.DeleteUser(userID)
+	precondition, ok := ParseIfMatch(c.GetHeader("If-Match"))
+	if !ok {
+		c.JSON(412, USER_VERSION_MISMATCH)
+		return
+	}
+
+	err := ctr.service.DeleteUser(actorOf(c), userID, precondition)
 	if err != nil {
 		if err.Error() == USER_ID_INVALID {
 			c.JSON(400, INVALID_USER_ID)
 			return
 		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == PRECONDITION_FAILED {
+			c.JSON(412, USER_VERSION_MISMATCH)
+			return
+		}
 		c.JSON(502, USER_DELETE_FAILED)
 		return
 	}
 
 	c.JSON(200, USER_DELETED)
 }
+
+// RestoreUser godoc
+//
+//	@Summary		Restore user
+//	@Description	This endpoint restores a soft deleted user, until it is purged after the retention window.
+//	@Description	Users whose email was taken by another user meanwhile can not be restored.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"userID"
+//	@Success		200	{object}	UserResponse
+//	@Failure		401
+//	@Failure		403	{object}	UserResponse
+//	@Failure		400	{object}	UserResponse
+//	@Failure		404	{object}	UserResponse
+//	@Failure		409	{object}	UserResponse
+//	@Failure		502	{object}	UserResponse
+//	@Router			/users/{id}/restore [post]
+func (ctr UserController) RestoreUser(c *gin.Context) {
+	var userID string = c.Param("id")
+
+	version, err := ctr.service.RestoreUser(actorOf(c), userID)
+	if err != nil {
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		if err.Error() == USER_NOT_DELETED {
+			c.JSON(409, USER_NOT_RESTORABLE)
+			return
+		}
+		if err.Error() == USER_EXISTS {
+			c.JSON(409, USER_EMAIL_TAKEN)
+			return
+		}
+		c.JSON(502, USER_RESTORE_FAILED)
+		return
+	}
+
+	c.Header("ETag", UserETag(version))
+	c.JSON(200, USER_RESTORED)
+}
+
+// SetRoles godoc
+//
+//	@Summary		Replace user roles
+//	@Description	This endpoint replaces the roles granted to a user. Only admins can manage roles.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string		true	"userID"
+//	@Param			request	body		UserRoles	true	"body"
+//	@Success		200		{object}	UserResponse
+//	@Failure		401
+//	@Failure		400		{object}	UserResponse
+//	@Failure		403		{object}	UserResponse
+//	@Failure		404		{object}	UserResponse
+//	@Failure		502		{object}	UserResponse
+//	@Router			/users/{id}/roles [put]
+func (ctr UserController) SetRoles(c *gin.Context) {
+	var roles UserRoles
+	var userID string = c.Param("id")
+
+	err := json.NewDecoder(c.Request.Body).Decode(&roles)
+	if err != nil {
+		c.JSON(400, INVALID_USER_DATA)
+		return
+	}
+
+	if err := ctr.service.SetRoles(actorOf(c), userID, roles.Roles); err != nil {
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == ROLES_INVALID {
+			c.JSON(400, INVALID_ROLES)
+			return
+		}
+		if err.Error() == USER_NOT_EXISTS {
+			c.JSON(404, USER_NOT_FOUND)
+			return
+		}
+		c.JSON(502, USER_UPDATE_FAILED)
+		return
+	}
+
+	c.JSON(200, USER_UPDATED)
+}
+
+// GetUserHistory godoc
+//
+//	@Summary		List user changes
+//	@Description	This endpoint returns a page of the changes made on a user, newest first, also after the user is deleted.
+//	@Description	Each change records its actor, request ID and the fields changed, passwords are redacted.
+//	@Tags			audit
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string	true	"userID"
+//	@Param			limit		query		int		false	"page size"
+//	@Param			next		query		string	false	"next page token"
+//	@Param			actor		query		string	false	"actor"
+//	@Param			action		query		string	false	"action: create, update, delete, restore or purge"
+//	@Param			requestId	query		string	false	"request ID"
+//	@Param			from		query		string	false	"changes made since, RFC 3339"
+//	@Param			to			query		string	false	"changes made until, RFC 3339"
+//	@Success		200			{object}	AuditPage
+//	@Failure		401
+//	@Failure		403			{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		502			{object}	UserResponse
+//	@Router			/users/{id}/history [get]
+func (ctr UserController) GetUserHistory(c *gin.Context) {
+	var userID string = c.Param("id")
+
+	query, validation := auditQueryOf(c)
+	if validation != nil {
+		c.JSON(400, validation)
+		return
+	}
+
+	page, err := ctr.service.GetUserHistory(userID, *query)
+	if err != nil {
+		ctr.auditError(c, err)
+		return
+	}
+
+	c.JSON(200, page)
+}
+
+// ListAuditEntries godoc
+//
+//	@Summary		List changes on users
+//	@Description	This endpoint returns a page of the changes made on any user, newest first. Only admins can list them.
+//	@Tags			audit
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit		query		int		false	"page size"
+//	@Param			next		query		string	false	"next page token"
+//	@Param			userId		query		string	false	"user ID"
+//	@Param			actor		query		string	false	"actor"
+//	@Param			action		query		string	false	"action: create, update, delete, restore or purge"
+//	@Param			requestId	query		string	false	"request ID"
+//	@Param			from		query		string	false	"changes made since, RFC 3339"
+//	@Param			to			query		string	false	"changes made until, RFC 3339"
+//	@Success		200			{object}	AuditPage
+//	@Failure		401
+//	@Failure		403			{object}	UserResponse
+//	@Failure		400			{object}	UserResponse
+//	@Failure		502			{object}	UserResponse
+//	@Router			/audit [get]
+func (ctr UserController) ListAuditEntries(c *gin.Context) {
+	query, validation := auditQueryOf(c)
+	if validation != nil {
+		c.JSON(400, validation)
+		return
+	}
+	query.UserID = c.Query("userId")
+
+	page, err := ctr.service.ListAuditEntries(*query)
+	if err != nil {
+		ctr.auditError(c, err)
+		return
+	}
+
+	c.JSON(200, page)
+}
+
+// Returns the audit query of the request parameters, or why they are invalid
+func auditQueryOf(c *gin.Context) (*AuditQuery, interface{}) {
+	query := &AuditQuery{
+		Next:      c.Query("next"),
+		Actor:     c.Query("actor"),
+		Action:    c.Query("action"),
+		RequestID: c.Query("requestId"),
+	}
+
+	if query.Action != "" && !isAuditAction(query.Action) {
+		return nil, INVALID_FILTER_VALUE
+	}
+
+	var ok bool
+	if query.From, ok = timeParam(c, "from"); !ok {
+		return nil, INVALID_FILTER_VALUE
+	}
+	if query.To, ok = timeParam(c, "to"); !ok {
+		return nil, INVALID_FILTER_VALUE
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return nil, INVALID_PAGE_SIZE
+		}
+		query.Limit = value
+	}
+	return query, nil
+}
+
+// Returns the RFC 3339 time of a query parameter, nil when it is missing
+func timeParam(c *gin.Context, param string) (*time.Time, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+func (ctr UserController) auditError(c *gin.Context, err error) {
+	if err.Error() == USER_ID_INVALID {
+		c.JSON(400, INVALID_USER_ID)
+		return
+	}
+	if err.Error() == PAGE_TOKEN_INVALID {
+		c.JSON(400, INVALID_PAGE_TOKEN)
+		return
+	}
+	c.JSON(502, AUDIT_LIST_FAILED)
+}
+
+// VerifyEmail godoc
+//
+//	@Summary		Verify user email
+//	@Description	This endpoint confirms the user email with the token sent to it on signup or email change.
+//	@Description	It needs no authentication, the token proves access to the email.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"userID"
+//	@Param			request	body		VerifyEmailRequest	true	"body"
+//	@Success		200		{object}	UserResponse
+//	@Failure		400		{object}	UserResponse
+//	@Failure		502		{object}	UserResponse
+//	@Router			/users/{id}/verify-email [post]
+func (ctr UserController) VerifyEmail(c *gin.Context) {
+	var request VerifyEmailRequest
+	var userID string = c.Param("id")
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil || request.Token == "" {
+		c.JSON(400, INVALID_VERIFICATION_TOKEN)
+		return
+	}
+
+	if err := ctr.service.VerifyEmail(userID, request.Token); err != nil {
+		if err.Error() == USER_ID_INVALID {
+			c.JSON(400, INVALID_USER_ID)
+			return
+		}
+		if err.Error() == VERIFICATION_TOKEN_INVALID {
+			c.JSON(400, INVALID_VERIFICATION_TOKEN)
+			return
+		}
+		c.JSON(502, EMAIL_VERIFY_FAILED)
+		return
+	}
+
+	c.JSON(200, EMAIL_VERIFIED)
+}
+
+// Returns the authenticated principal and the ID of the request as the author of its changes
+func actorOf(c *gin.Context) Actor {
+	principal, _ := identity.GetPrincipal(c)
+	return Actor{ID: principal.ID, RequestID: identity.GetRequestID(c)}
+}
@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+	"userapi/identity"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
@@ -27,7 +29,7 @@ func TestCreateUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					CreateUser(gomock.Any()).
+					CreateUser(Actor{ID: "admin"}, gomock.Any()).
 					Return(userID, nil)
 			},
 			inputBody: `{
@@ -55,18 +57,23 @@ func TestCreateUser(t *testing.T) {
 			expectedResponse: `{"message":"Invalid User Data","code":"INVALID_USER_DATA"}`,
 		},
 		{
-			name:             "email required",
-			setupMock:        func(service *MockUserService) {},
+			name: "email required",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Return("", NewValidationErrors([]FieldError{*EMAIL_REQUIRED}))
+			},
 			inputBody:        `{}`,
-			expectedStatus:   http.StatusBadRequest,
-			expectedResponse: `{"message":"Email Required","code":"EMAIL_REQUIRED"}`,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedResponse: `{"message":"Invalid User Data","code":"VALIDATION_FAILED","errors":[{"field":"email","code":"EMAIL_REQUIRED","message":"Email Required"}]}`,
 		},
 		{
 			name: "user already exists",
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					CreateUser(gomock.Any()).
+					CreateUser(gomock.Any(), gomock.Any()).
 					Return("", &userServiceError{code: USER_EXISTS})
 			},
 			inputBody:        `{"email": "test@test.com"}`,
@@ -78,7 +85,7 @@ func TestCreateUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					CreateUser(gomock.Any()).
+					CreateUser(gomock.Any(), gomock.Any()).
 					Return("", &userServiceError{code: CREATE_USER_FAILED})
 			},
 			inputBody:        `{"email": "test@test.com"}`,
@@ -96,7 +103,9 @@ func TestCreateUser(t *testing.T) {
 
 			controller := NewUserController(svc)
 			r := gin.Default()
-			r.POST("/api/v1/users", controller.CreateUser)
+			r.POST("/api/v1/users", func(c *gin.Context) {
+				identity.SetPrincipal(c, identity.Principal{ID: "admin", Method: identity.BASIC_METHOD})
+			}, controller.CreateUser)
 
 			req, err := http.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(tc.inputBody))
 			if err != nil {
@@ -138,6 +147,7 @@ func TestGetUser(t *testing.T) {
 		name             string
 		setupMock        func(service *MockUserService)
 		inputParam       string
+		inputIfNoneMatch string
 		expectedResponse string
 		expectedStatus   int
 	}{
@@ -151,7 +161,20 @@ func TestGetUser(t *testing.T) {
 			},
 			inputParam:       userID,
 			expectedStatus:   http.StatusOK,
-			expectedResponse: `{"id":"","name":"Test","age":"33","email":"test@test.com","password":"12345","address":{"street":"Rua hum","number":"111","zip":"12345-678","city":"SP","state":"SP","country":"BR"}}`,
+			expectedResponse: `{"id":"","name":"Test","age":"33","email":"test@test.com","password":"12345","address":{"street":"Rua hum","number":"111","zip":"12345-678","city":"SP","state":"SP","country":"BR"},"emailVerified":false}`,
+		},
+		{
+			name: "user not modified",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUser(gomock.Any()).
+					Return(&User{ID: userID, Email: "test@test.com", Version: 5}, nil)
+			},
+			inputParam:       userID,
+			inputIfNoneMatch: `"4", W/"5"`,
+			expectedStatus:   http.StatusNotModified,
+			expectedResponse: ``,
 		},
 		{
 			name: "invalid user id",
@@ -208,6 +231,120 @@ func TestGetUser(t *testing.T) {
 				t.Errorf("Error in request : %v", err)
 			}
 
+			req.Header.Set("If-None-Match", tc.inputIfNoneMatch)
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputQuery       string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "list users success",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(ListQuery{Limit: 1}).
+					Return(&UserPage{Users: []User{{ID: "64260e1da4c0c814bda5734a", Email: "test@test.com"}}, Next: "token"}, nil)
+			},
+			inputQuery:       "?limit=1",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"users":[{"id":"64260e1da4c0c814bda5734a","name":"","age":"","email":"test@test.com","address":{"street":"","number":"","zip":"","city":"","state":"","country":""},"emailVerified":false}],"next":"token"}`,
+		},
+		{
+			name: "list users filtered and sorted",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(ListQuery{
+						Filters: []FilterCondition{{Field: "name", Operator: FILTER_PREFIX, Value: "Te"}},
+						Sort:    []SortField{{Field: "address.city"}, {Field: "email", Descending: true}},
+					}).
+					Return(&UserPage{Users: []User{}}, nil)
+			},
+			inputQuery:       "?name=Te&sort=address.city,-email",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"users":[]}`,
+		},
+		{
+			name:             "unknown filter field",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?password=12345",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Filter Field","code":"INVALID_FILTER_FIELD"}`,
+		},
+		{
+			name:             "unknown sort field",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?sort=age",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Sort Field","code":"INVALID_SORT_FIELD"}`,
+		},
+		{
+			name:             "invalid page size",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?limit=abc",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Page Size","code":"INVALID_PAGE_SIZE"}`,
+		},
+		{
+			name: "invalid page token",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(ListQuery{Next: "abc"}).
+					Return(nil, &userServiceError{code: PAGE_TOKEN_INVALID})
+			},
+			inputQuery:       "?next=abc",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Page Token","code":"INVALID_PAGE_TOKEN"}`,
+		},
+		{
+			name: "user list failed",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(gomock.Any()).
+					Return(nil, &userServiceError{code: LIST_USERS_FAILED})
+			},
+			inputQuery:       "",
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"User List Failed","code":"USER_LIST_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+
+			r := gin.Default()
+			r.GET("/api/v1/users", controller.ListUsers)
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users%s", tc.inputQuery), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+
 			r.ServeHTTP(w, req)
 
 			if w.Result().StatusCode != tc.expectedStatus {
@@ -238,8 +375,8 @@ func TestUpdateUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(nil)
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(2), nil)
 			},
 			inputBody: `{
 				"age": "33",
@@ -258,13 +395,26 @@ func TestUpdateUser(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: `{"message":"Invalid User Data","code":"INVALID_USER_DATA"}`,
 		},
+		{
+			name: "email required",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), NewValidationErrors([]FieldError{*EMAIL_REQUIRED}))
+			},
+			inputBody:        `{"name": "Test"}`,
+			inputParam:       userID,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedResponse: `{"message":"Invalid User Data","code":"VALIDATION_FAILED","errors":[{"field":"email","code":"EMAIL_REQUIRED","message":"Email Required"}]}`,
+		},
 		{
 			name: "invalid user id",
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(&userServiceError{code: USER_ID_INVALID})
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: USER_ID_INVALID})
 			},
 			inputBody: `{
 				"age": "33",
@@ -275,13 +425,29 @@ func TestUpdateUser(t *testing.T) {
 			expectedStatus:   http.StatusBadRequest,
 			expectedResponse: `{"message":"Invalid User ID","code":"INVALID_USER_ID"}`,
 		},
+		{
+			name: "email taken by another user",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: USER_EXISTS})
+			},
+			inputBody: `{
+				"email": "taken@test.com",
+				"name": "Test"
+			  }`,
+			inputParam:       userID,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"User Already Exists","code":"USER_ALREADY_EXISTS"}`,
+		},
 		{
 			name: "user update failed",
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(&userServiceError{code: UPDATE_USER_FAILED})
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: UPDATE_USER_FAILED})
 			},
 			inputBody: `{
 				"age": "33",
@@ -322,6 +488,154 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+func TestPatchUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputBody        string
+		inputType        string
+		inputIfMatch     string
+		expectedResponse string
+		expectedStatus   int
+		expectedETag     string
+	}{
+		{
+			name: "patch user success",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserPatch{ContentType: MERGE_PATCH_CONTENT_TYPE, Document: []byte(`{"age": null}`)}, Precondition{3}).
+					Return(int64(4), nil)
+			},
+			inputBody:        `{"age": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			inputIfMatch:     `"3"`,
+			expectedStatus:   http.StatusOK,
+			expectedETag:     `"4"`,
+			expectedResponse: `{"message":"User Updated","code":"USER_UPDATED"}`,
+		},
+		{
+			name:             "unsupported content type",
+			setupMock:        func(service *MockUserService) {},
+			inputBody:        `{"age": null}`,
+			inputType:        "application/json",
+			expectedStatus:   http.StatusUnsupportedMediaType,
+			expectedResponse: `{"message":"Unsupported Patch Content Type","code":"UNSUPPORTED_PATCH_TYPE"}`,
+		},
+		{
+			name: "invalid patch",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: PATCH_INVALID})
+			},
+			inputBody:        `[{"op": "remove", "path": "/unknown"}]`,
+			inputType:        JSON_PATCH_CONTENT_TYPE,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Patch Document","code":"INVALID_PATCH"}`,
+		},
+		{
+			name: "user version mismatch",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), Precondition{2}).
+					Return(int64(0), &userServiceError{code: PRECONDITION_FAILED})
+			},
+			inputBody:        `{"age": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			inputIfMatch:     `"2"`,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedResponse: `{"message":"User Version Does Not Match","code":"USER_VERSION_MISMATCH"}`,
+		},
+		{
+			name:             "weak etag never matches",
+			setupMock:        func(service *MockUserService) {},
+			inputBody:        `{"age": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			inputIfMatch:     `W/"3"`,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedResponse: `{"message":"User Version Does Not Match","code":"USER_VERSION_MISMATCH"}`,
+		},
+		{
+			name: "patched user invalid",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), NewValidationErrors([]FieldError{*EMAIL_REQUIRED}))
+			},
+			inputBody:        `{"email": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			expectedStatus:   http.StatusUnprocessableEntity,
+			expectedResponse: `{"message":"Invalid User Data","code":"VALIDATION_FAILED","errors":[{"field":"email","code":"EMAIL_REQUIRED","message":"Email Required"}]}`,
+		},
+		{
+			name: "user not found",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: USER_NOT_EXISTS})
+			},
+			inputBody:        `{"age": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Not Found","code":"USER_NOT_FOUND"}`,
+		},
+		{
+			name: "user update failed",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(int64(0), &userServiceError{code: UPDATE_USER_FAILED})
+			},
+			inputBody:        `{"age": null}`,
+			inputType:        MERGE_PATCH_CONTENT_TYPE,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"User Update Failed","code":"USER_UPDATE_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.PATCH("/api/v1/users/:id", controller.PatchUser)
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/users/%s", userID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			req.Header.Set("Content-Type", tc.inputType)
+			req.Header.Set("If-Match", tc.inputIfMatch)
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if etag := w.Header().Get("ETag"); etag != tc.expectedETag {
+				t.Errorf("Expecting ETag %s , but returns %s", tc.expectedETag, etag)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
 func TestDeleteUser(t *testing.T) {
 
 	const userID string = "64260e1da4c0c814bda5734a"
@@ -338,7 +652,7 @@ func TestDeleteUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					DeleteUser(gomock.Any()).
+					DeleteUser(gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil)
 			},
 			inputParam:       userID,
@@ -350,7 +664,7 @@ func TestDeleteUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					DeleteUser(gomock.Any()).
+					DeleteUser(gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(&userServiceError{code: USER_ID_INVALID})
 			},
 			inputParam:       `gdfhdhgh`,
@@ -362,7 +676,7 @@ func TestDeleteUser(t *testing.T) {
 			setupMock: func(service *MockUserService) {
 				service.
 					EXPECT().
-					DeleteUser(gomock.Any()).
+					DeleteUser(gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(&userServiceError{code: DELETE_USER_FAILED})
 			},
 			inputParam:       userID,
@@ -398,3 +712,715 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestRestoreUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputParam       string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "restore user success",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RestoreUser(gomock.Any(), userID).
+					Return(int64(3), nil)
+			},
+			inputParam:       userID,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"User Restored","code":"USER_RESTORED"}`,
+		},
+		{
+			name: "user not deleted",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RestoreUser(gomock.Any(), userID).
+					Return(int64(0), &userServiceError{code: USER_NOT_DELETED})
+			},
+			inputParam:       userID,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"message":"User Is Not Deleted","code":"USER_NOT_RESTORABLE"}`,
+		},
+		{
+			name: "email taken while deleted",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RestoreUser(gomock.Any(), userID).
+					Return(int64(0), &userServiceError{code: USER_EXISTS})
+			},
+			inputParam:       userID,
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"message":"User Email Is Taken By Another User","code":"USER_EMAIL_TAKEN"}`,
+		},
+		{
+			name: "user purged",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RestoreUser(gomock.Any(), userID).
+					Return(int64(0), &userServiceError{code: USER_NOT_EXISTS})
+			},
+			inputParam:       userID,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Not Found","code":"USER_NOT_FOUND"}`,
+		},
+		{
+			name: "restore user failed",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RestoreUser(gomock.Any(), userID).
+					Return(int64(0), &userServiceError{code: RESTORE_USER_FAILED})
+			},
+			inputParam:       userID,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"User Restore Failed","code":"USER_RESTORE_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/restore", controller.RestoreUser)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/restore", tc.inputParam), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestListUsersIncludeDeleted(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		setupMock      func(service *MockUserService)
+		principal      identity.Principal
+		inputQuery     string
+		expectedStatus int
+	}{
+		{
+			name: "admin lists deleted users",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(ListQuery{IncludeDeleted: true}).
+					Return(&UserPage{Users: []User{}}, nil)
+			},
+			principal:      identity.Principal{ID: "64260e1da4c0c814bda5734a", Roles: []string{ROLE_ADMIN}},
+			inputQuery:     "?includeDeleted=true",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "support can not list deleted users",
+			setupMock:      func(service *MockUserService) {},
+			principal:      identity.Principal{ID: "64260e1da4c0c814bda5734a", Roles: []string{ROLE_SUPPORT}},
+			inputQuery:     "?includeDeleted=true",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "support lists users without deleted ones",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListUsers(ListQuery{}).
+					Return(&UserPage{Users: []User{}}, nil)
+			},
+			principal:      identity.Principal{ID: "64260e1da4c0c814bda5734a", Roles: []string{ROLE_SUPPORT}},
+			inputQuery:     "?includeDeleted=false",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid include deleted",
+			setupMock:      func(service *MockUserService) {},
+			principal:      identity.Principal{ID: "64260e1da4c0c814bda5734a", Roles: []string{ROLE_ADMIN}},
+			inputQuery:     "?includeDeleted=maybe",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+
+			r := gin.Default()
+			r.GET("/api/v1/users", func(c *gin.Context) {
+				identity.SetPrincipal(c, tc.principal)
+			}, controller.ListUsers)
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users%s", tc.inputQuery), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func TestSetRoles(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "set roles success",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					SetRoles(gomock.Any(), userID, []string{"support"}).
+					Return(nil)
+			},
+			inputBody:        `{"roles": ["support"]}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"User Updated","code":"USER_UPDATED"}`,
+		},
+		{
+			name: "invalid roles",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					SetRoles(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&userServiceError{code: ROLES_INVALID})
+			},
+			inputBody:        `{"roles": ["root"]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Roles","code":"INVALID_ROLES"}`,
+		},
+		{
+			name: "user not found",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					SetRoles(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(&userServiceError{code: USER_NOT_EXISTS})
+			},
+			inputBody:        `{"roles": ["admin"]}`,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Not Found","code":"USER_NOT_FOUND"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.PUT("/api/v1/users/:id/roles", controller.SetRoles)
+
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/users/%s/roles", userID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "email verified",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					VerifyEmail(userID, "token").
+					Return(nil)
+			},
+			inputBody:        `{"token": "token"}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Email Verified","code":"EMAIL_VERIFIED"}`,
+		},
+		{
+			name:             "token required",
+			setupMock:        func(service *MockUserService) {},
+			inputBody:        `{}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Verification Token","code":"INVALID_VERIFICATION_TOKEN"}`,
+		},
+		{
+			name: "invalid token",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					VerifyEmail(gomock.Any(), gomock.Any()).
+					Return(&userServiceError{code: VERIFICATION_TOKEN_INVALID})
+			},
+			inputBody:        `{"token": "used"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Verification Token","code":"INVALID_VERIFICATION_TOKEN"}`,
+		},
+		{
+			name: "verify failed",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					VerifyEmail(gomock.Any(), gomock.Any()).
+					Return(&userServiceError{code: VERIFY_EMAIL_FAILED})
+			},
+			inputBody:        `{"token": "token"}`,
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Email Verify Failed","code":"EMAIL_VERIFY_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/verify-email", controller.VerifyEmail)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/verify-email", userID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestGetUserHistory(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputQuery       string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "history with filters",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUserHistory(userID, AuditQuery{Limit: 1, Actor: "admin", Action: AUDIT_UPDATE, From: &from}).
+					Return(&AuditPage{
+						Entries: []AuditEntry{{
+							ID:        "64260e1da4c0c814bda57351",
+							UserID:    userID,
+							Action:    AUDIT_UPDATE,
+							Actor:     "admin",
+							RequestID: "request-1",
+							Timestamp: from,
+							Version:   2,
+							Changes:   []FieldChange{{Field: "name", Before: "Test", After: "New Name"}},
+						}},
+						Next: "64260e1da4c0c814bda57351",
+					}, nil)
+			},
+			inputQuery:       "?limit=1&actor=admin&action=update&from=2023-03-01T00:00:00Z",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"entries":[{"id":"64260e1da4c0c814bda57351","userId":"64260e1da4c0c814bda5734a","action":"update","actor":"admin","requestId":"request-1","timestamp":"2023-03-01T00:00:00Z","version":2,"changes":[{"field":"name","before":"Test","after":"New Name"}]}],"next":"64260e1da4c0c814bda57351"}`,
+		},
+		{
+			name:             "invalid action",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?action=erase",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Filter Value","code":"INVALID_FILTER_VALUE"}`,
+		},
+		{
+			name:             "invalid time",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?to=yesterday",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Filter Value","code":"INVALID_FILTER_VALUE"}`,
+		},
+		{
+			name: "invalid page token",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUserHistory(gomock.Any(), gomock.Any()).
+					Return(nil, &userServiceError{code: PAGE_TOKEN_INVALID})
+			},
+			inputQuery:       "?next=any",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Page Token","code":"INVALID_PAGE_TOKEN"}`,
+		},
+		{
+			name: "history failed",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUserHistory(gomock.Any(), gomock.Any()).
+					Return(nil, &userServiceError{code: LIST_AUDIT_FAILED})
+			},
+			inputQuery:       "",
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Audit List Failed","code":"AUDIT_LIST_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.GET("/api/v1/users/:id/history", controller.GetUserHistory)
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%s/history%s", userID, tc.inputQuery), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestListAuditEntries(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputQuery       string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "entries of a request",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListAuditEntries(AuditQuery{UserID: userID, RequestID: "request-1"}).
+					Return(&AuditPage{Entries: []AuditEntry{}}, nil)
+			},
+			inputQuery:       fmt.Sprintf("?userId=%s&requestId=request-1", userID),
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"entries":[]}`,
+		},
+		{
+			name: "invalid user id",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					ListAuditEntries(gomock.Any()).
+					Return(nil, &userServiceError{code: USER_ID_INVALID})
+			},
+			inputQuery:       "?userId=any",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid User ID","code":"INVALID_USER_ID"}`,
+		},
+		{
+			name:             "invalid page size",
+			setupMock:        func(service *MockUserService) {},
+			inputQuery:       "?limit=0",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Page Size","code":"INVALID_PAGE_SIZE"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.GET("/api/v1/audit", controller.ListAuditEntries)
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/audit%s", tc.inputQuery), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestGetUserAsOf(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	asOf := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		principal        identity.Principal
+		inputAsOf        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "user as of time",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUserAsOf(userID, asOf).
+					Return(&User{ID: userID, Name: "First", Email: "test@test.com", Version: 1}, nil)
+			},
+			principal:        identity.Principal{ID: "admin", Roles: []string{ROLE_SUPPORT}},
+			inputAsOf:        "2023-04-01T10:00:00Z",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"id":"64260e1da4c0c814bda5734a","name":"First","age":"","email":"test@test.com","address":{"street":"","number":"","zip":"","city":"","state":"","country":""},"emailVerified":false}`,
+		},
+		{
+			name:             "invalid time",
+			setupMock:        func(service *MockUserService) {},
+			principal:        identity.Principal{ID: "admin", Roles: []string{ROLE_SUPPORT}},
+			inputAsOf:        "yesterday",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid asOf Time","code":"INVALID_AS_OF"}`,
+		},
+		{
+			name:             "user can not read own history",
+			setupMock:        func(service *MockUserService) {},
+			principal:        identity.Principal{ID: userID, Roles: []string{ROLE_SELF}},
+			inputAsOf:        "2023-04-01T10:00:00Z",
+			expectedStatus:   http.StatusForbidden,
+			expectedResponse: `{"message":"Access Denied","code":"ACCESS_DENIED"}`,
+		},
+		{
+			name: "user not existing by then",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					GetUserAsOf(userID, asOf).
+					Return(nil, &userServiceError{code: USER_NOT_EXISTS})
+			},
+			principal:        identity.Principal{ID: "admin", Roles: []string{ROLE_SUPPORT}},
+			inputAsOf:        "2023-04-01T10:00:00Z",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Not Found","code":"USER_NOT_FOUND"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.GET("/api/v1/users/:id", func(c *gin.Context) {
+				identity.SetPrincipal(c, tc.principal)
+			}, controller.GetUser)
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%s?asOf=%s", userID, tc.inputAsOf), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestRevertUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserService)
+		inputRevision    string
+		inputIfMatch     string
+		expectedResponse string
+		expectedStatus   int
+		expectedETag     string
+	}{
+		{
+			name: "revert user success",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RevertUser(gomock.Any(), userID, int64(2), Precondition{4}).
+					Return(int64(5), nil)
+			},
+			inputRevision:    "2",
+			inputIfMatch:     `"4"`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"User Reverted","code":"USER_REVERTED"}`,
+			expectedETag:     `"5"`,
+		},
+		{
+			name:             "invalid revision",
+			setupMock:        func(service *MockUserService) {},
+			inputRevision:    "first",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Revision","code":"INVALID_REVISION"}`,
+		},
+		{
+			name: "revision not found",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RevertUser(gomock.Any(), userID, int64(9), nil).
+					Return(int64(0), &userServiceError{code: REVISION_NOT_FOUND})
+			},
+			inputRevision:    "9",
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"User Revision Not Found","code":"USER_REVISION_NOT_FOUND"}`,
+		},
+		{
+			name: "history incomplete",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RevertUser(gomock.Any(), userID, int64(1), nil).
+					Return(int64(0), &userServiceError{code: HISTORY_INCOMPLETE})
+			},
+			inputRevision:    "1",
+			expectedStatus:   http.StatusConflict,
+			expectedResponse: `{"message":"User History Is Incomplete","code":"USER_HISTORY_INCOMPLETE"}`,
+		},
+		{
+			name: "user changed meanwhile",
+			setupMock: func(service *MockUserService) {
+				service.
+					EXPECT().
+					RevertUser(gomock.Any(), userID, int64(2), Precondition{4}).
+					Return(int64(0), &userServiceError{code: PRECONDITION_FAILED})
+			},
+			inputRevision:    "2",
+			inputIfMatch:     `"4"`,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedResponse: `{"message":"User Version Does Not Match","code":"USER_VERSION_MISMATCH"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockUserService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewUserController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/revisions/:rev/revert", controller.RevertUser)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/revisions/%s/revert", userID, tc.inputRevision), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			if tc.inputIfMatch != "" {
+				req.Header.Set("If-Match", tc.inputIfMatch)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+
+			if etag := w.Header().Get("ETag"); etag != tc.expectedETag {
+				t.Errorf("Expecting ETag %s , but returns %s", tc.expectedETag, etag)
+			}
+		})
+	}
+}
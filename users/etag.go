@@ -0,0 +1,96 @@
+package users
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User versions accepted by a conditional write, any version is accepted when empty
+type Precondition []int64
+
+func (p Precondition) matches(version int64) bool {
+	if len(p) == 0 {
+		return true
+	}
+	for _, v := range p {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Precondition) filter(objID primitive.ObjectID) bson.M {
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "deletedAt": notDeleted}
+	if len(p) == 0 {
+		return filter
+	}
+
+	versions := bson.A{}
+	for _, v := range p {
+		versions = append(versions, v)
+		if v == 0 {
+			// Users stored before versioning have no version field
+			versions = append(versions, nil)
+		}
+	}
+	filter["version"] = bson.M{"$in": versions}
+	return filter
+}
+
+// Returns the entity tag of a user version
+func UserETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+/*
+Parses an If-Match header into the accepted versions.
+
+An empty header or `*` accept any version. Returns false when no entity tag
+can match a user version, as weak or malformed tags never match on If-Match.
+*/
+func ParseIfMatch(header string) (Precondition, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	precondition := make(Precondition, 0)
+	for _, tag := range strings.Split(header, ",") {
+		if version, ok := parseETag(strings.TrimSpace(tag)); ok {
+			precondition = append(precondition, version)
+		}
+	}
+	return precondition, len(precondition) > 0
+}
+
+// Tells if an If-None-Match header matches the user version, using weak comparison
+func MatchesIfNoneMatch(header string, version int64) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if v, ok := parseETag(tag); ok && v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func parseETag(tag string) (int64, bool) {
+	if len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,126 @@
+package users
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const EVENT_USER_CREATED string = "user.created"
+const EVENT_USER_UPDATED string = "user.updated"
+const EVENT_USER_DELETED string = "user.deleted"
+
+var EventTypes = []string{EVENT_USER_CREATED, EVENT_USER_UPDATED, EVENT_USER_DELETED}
+
+// Change stored on a user, told to other systems
+type UserEvent struct {
+	ID        string    `json:"id" bson:"id"`
+	Type      string    `json:"type" bson:"type"`
+	UserID    string    `json:"userId" bson:"userId"`
+	Version   int64     `json:"version" bson:"version"`
+	Actor     string    `json:"actor" bson:"actor"`
+	RequestID string    `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	Timestamp time.Time `json:"timestamp" bson:"timestamp"`
+	// User after the change without its password, missing on deletion
+	User *User `json:"user,omitempty" bson:"user,omitempty"`
+}
+
+type Publisher interface {
+	/*
+		Method to tell other systems about a change already stored on a user. Events
+		are published at least once, so the same event may be published again.
+
+		Parameters
+
+		event: Event type, the user changed and the author of the change.
+	*/
+	Publish(event UserEvent) error
+}
+
+// Keeps the last size events published in memory, in publication order
+type MemoryPublisher struct {
+	mutex  sync.Mutex
+	size   int
+	events []UserEvent
+}
+
+func NewMemoryPublisher(size int) *MemoryPublisher {
+	return &MemoryPublisher{size: size, events: []UserEvent{}}
+}
+
+func (p *MemoryPublisher) Publish(event UserEvent) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.events = append(p.events, event)
+	if len(p.events) > p.size {
+		p.events = append([]UserEvent{}, p.events[len(p.events)-p.size:]...)
+	}
+	return nil
+}
+
+// Returns the events kept, oldest first
+func (p *MemoryPublisher) Events() []UserEvent {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]UserEvent{}, p.events...)
+}
+
+// Returns a publisher writing every event as a JSON line on writer, as os.Stdout
+func NewWriterPublisher(writer io.Writer) Publisher {
+	return &writerPublisher{writer: writer}
+}
+
+type writerPublisher struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (p *writerPublisher) Publish(event UserEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	_, err = p.writer.Write(append(line, '\n'))
+	return err
+}
+
+/*
+Returns a publisher publishing every event to all publishers, in order.
+
+A failure on any publisher fails the event, which is then published again to all
+of them, so publishers succeeding before see the event twice.
+*/
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return multiPublisher(publishers)
+}
+
+type multiPublisher []Publisher
+
+func (p multiPublisher) Publish(event UserEvent) error {
+	for _, publisher := range p {
+		if err := publisher.Publish(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Publishes the event of a change made on a user, the one written on the outbox, to the
+in-process publisher of the service, feeding the event stream. The change is already
+made, so a failure is only logged. No event is published without publisher, as when
+change streams feed the event stream.
+*/
+func (svc *userService) publish(event *UserEvent) {
+	if svc.events == nil {
+		return
+	}
+	if err := svc.events.Publish(*event); err != nil {
+		fmt.Println(fmt.Errorf("Error on Publish : %v", err))
+	}
+}
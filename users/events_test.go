@@ -0,0 +1,187 @@
+package users
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
+
+	"github.com/golang/mock/gomock"
+)
+
+var testEvent UserEvent = UserEvent{ID: "event-1", Type: EVENT_USER_DELETED, UserID: "64260e1da4c0c814bda5734a", Version: 2, Actor: "admin"}
+
+func TestWriterPublisher(t *testing.T) {
+	var buffer bytes.Buffer
+	publisher := NewWriterPublisher(&buffer)
+
+	if err := publisher.Publish(testEvent); err != nil {
+		t.Errorf("Expecting error %v , but returns %v", nil, err)
+	}
+
+	expected := `{"id":"event-1","type":"user.deleted","userId":"64260e1da4c0c814bda5734a","version":2,"actor":"admin","timestamp":"0001-01-01T00:00:00Z"}` + "\n"
+	if buffer.String() != expected {
+		t.Errorf("Expecting line %s , but returns %s", expected, buffer.String())
+	}
+}
+
+func TestMemoryPublisherKeepsLastEvents(t *testing.T) {
+	publisher := NewMemoryPublisher(2)
+	for _, ID := range []string{"event-1", "event-2", "event-3"} {
+		event := testEvent
+		event.ID = ID
+		if err := publisher.Publish(event); err != nil {
+			t.Errorf("Expecting error %v , but returns %v", nil, err)
+		}
+	}
+
+	events := publisher.Events()
+	if len(events) != 2 || events[0].ID != "event-2" || events[1].ID != "event-3" {
+		t.Errorf("Expecting events %v , but returns %v", []string{"event-2", "event-3"}, events)
+	}
+}
+
+func TestMultiPublisher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	first, last := NewMemoryPublisher(10), NewMemoryPublisher(10)
+	failing := NewMockPublisher(ctrl)
+	failing.EXPECT().Publish(testEvent).Return(fmt.Errorf("any error"))
+
+	if err := NewMultiPublisher(first, last).Publish(testEvent); err != nil {
+		t.Errorf("Expecting error %v , but returns %v", nil, err)
+	}
+	if err := NewMultiPublisher(first, failing, last).Publish(testEvent); err == nil {
+		t.Errorf("Expecting error %v , but returns %v", "any error", err)
+	}
+
+	if events := first.Events(); !reflect.DeepEqual(events, []UserEvent{testEvent, testEvent}) {
+		t.Errorf("Expecting events %v , but returns %v", []UserEvent{testEvent, testEvent}, events)
+	}
+	if events := last.Events(); !reflect.DeepEqual(events, []UserEvent{testEvent}) {
+		t.Errorf("Expecting events %v , but returns %v", []UserEvent{testEvent}, events)
+	}
+}
+
+func TestRelayEvents(t *testing.T) {
+
+	const outboxID string = "64260e1da4c0c814bda57361"
+
+	tests := []struct {
+		name              string
+		setupMock         func(outbox *MockOutboxRepository, publisher *MockPublisher)
+		expectedPublished int
+		expectedError     error
+	}{
+		{
+			name: "event published",
+			setupMock: func(outbox *MockOutboxRepository, publisher *MockPublisher) {
+				gomock.InOrder(
+					outbox.EXPECT().ClaimEvent(gomock.Any(), relayLease).Return(&OutboxEvent{ID: outboxID, Event: testEvent}, nil),
+					publisher.EXPECT().Publish(testEvent).Return(nil),
+					outbox.EXPECT().MarkPublished(outboxID, gomock.Any()).Return(nil),
+					outbox.EXPECT().ClaimEvent(gomock.Any(), relayLease).Return(nil, nil),
+				)
+			},
+			expectedPublished: 1,
+			expectedError:     nil,
+		},
+		{
+			name: "publish failed",
+			setupMock: func(outbox *MockOutboxRepository, publisher *MockPublisher) {
+				gomock.InOrder(
+					outbox.EXPECT().ClaimEvent(gomock.Any(), relayLease).Return(&OutboxEvent{ID: outboxID, Event: testEvent}, nil),
+					publisher.EXPECT().Publish(testEvent).Return(fmt.Errorf("any error")),
+					outbox.EXPECT().FailEvent(outboxID, "any error").Return(nil),
+					outbox.EXPECT().ClaimEvent(gomock.Any(), relayLease).Return(nil, nil),
+				)
+			},
+			expectedPublished: 0,
+			expectedError:     nil,
+		},
+		{
+			name: "claim failed",
+			setupMock: func(outbox *MockOutboxRepository, publisher *MockPublisher) {
+				outbox.EXPECT().ClaimEvent(gomock.Any(), relayLease).Return(nil, fmt.Errorf("any error"))
+			},
+			expectedPublished: 0,
+			expectedError:     fmt.Errorf("any error"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			outbox := NewMockOutboxRepository(ctrl)
+			publisher := NewMockPublisher(ctrl)
+			tc.setupMock(outbox, publisher)
+
+			retention := time.Hour
+			if tc.expectedError == nil {
+				outbox.
+					EXPECT().
+					PurgePublished(gomock.Any()).
+					DoAndReturn(func(publishedBefore time.Time) (int64, error) {
+						if publishedBefore.After(time.Now().Add(-retention)) {
+							t.Errorf("Expecting events published before %v , but returns %v", time.Now().Add(-retention), publishedBefore)
+						}
+						return 0, nil
+					})
+			}
+
+			relay := NewEventRelay(outbox, publisher, config.Config{OutboxRetention: retention})
+
+			published, err := relay.RelayEvents()
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if published != tc.expectedPublished {
+				t.Errorf("Expecting published %v , but returns %v", tc.expectedPublished, published)
+			}
+		})
+	}
+}
+
+func TestServicePublishesEvent(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockUserRepository(ctrl)
+	events := NewMemoryPublisher(10)
+
+	// The event written on the outbox along with the change
+	written := UserEvent{
+		ID:        "64260e1da4c0c814bda57361",
+		Type:      EVENT_USER_UPDATED,
+		UserID:    userID,
+		Version:   4,
+		Actor:     testActor.ID,
+		RequestID: testActor.RequestID,
+		User:      &User{ID: userID, Name: "New Name", Email: "test@test.com", Version: 4},
+	}
+
+	repo.
+		EXPECT().
+		FindUserByID(userID, gomock.Any()).
+		Return(&User{ID: userID, Name: "Test", Email: "test@test.com", Password: "hash", Version: 3}, nil)
+	repo.
+		EXPECT().
+		PatchUser(testActor, userID, gomock.Any(), Precondition{3}).
+		Return(&written, nil)
+
+	service := NewUserService(repo, NewMockVerificationRepository(ctrl), NewMockAuditRepository(ctrl), events, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{})
+
+	patch := UserPatch{ContentType: MERGE_PATCH_CONTENT_TYPE, Document: []byte(`{"name": "New Name"}`)}
+	if _, err := service.PatchUser(testActor, userID, patch, nil); err != nil {
+		t.Errorf("Expecting error %v , but returns %v", nil, err)
+	}
+
+	if published := events.Events(); !reflect.DeepEqual(published, []UserEvent{written}) {
+		t.Errorf("Expecting events %v , but returns %v", []UserEvent{written}, published)
+	}
+}
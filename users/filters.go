@@ -0,0 +1,263 @@
+package users
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const FILTER_EQUALS string = "eq"
+const FILTER_PREFIX string = "prefix"
+
+// Inclusive range of ISO dates as `from..to`, either bound may be omitted and a single date matches that day
+const FILTER_DATE_RANGE string = "dateRange"
+
+// Query parameters used by pagination and sorting, never taken as filters
+var listParams = map[string]bool{
+	"limit":          true,
+	"next":           true,
+	"sort":           true,
+	"includeDeleted": true,
+}
+
+// Fields of UserAccess allowed on user listing filters and sort,
+// with the operator applied when filtering by each one
+var UserFilters = map[string]string{
+	"email":           FILTER_EQUALS,
+	"name":            FILTER_PREFIX,
+	"address.city":    FILTER_EQUALS,
+	"address.state":   FILTER_EQUALS,
+	"address.country": FILTER_EQUALS,
+	"birthDate":       FILTER_DATE_RANGE,
+}
+
+var INVALID_FILTER_FIELD *ValidationResponse = &ValidationResponse{
+	Code:    "INVALID_FILTER_FIELD",
+	Message: "Invalid Filter Field",
+}
+
+var INVALID_FILTER_VALUE *ValidationResponse = &ValidationResponse{
+	Code:    "INVALID_FILTER_VALUE",
+	Message: "Invalid Filter Value",
+}
+
+var INVALID_SORT_FIELD *ValidationResponse = &ValidationResponse{
+	Code:    "INVALID_SORT_FIELD",
+	Message: "Invalid Sort Field",
+}
+
+// Condition applied on a user field when listing users
+type FilterCondition struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// Field used to sort users when listing users
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// Filters, sort and position used by repository to list users
+type ListCriteria struct {
+	Filters []FilterCondition
+	Sort    []SortField
+	AfterID string
+	// Values of the sort fields on the cursor, nil when missing on the user
+	AfterValues []*string
+	// Soft deleted users are left out unless set
+	IncludeDeleted bool
+}
+
+func isFilterable(field string) bool {
+	if _, ok := UserAccess[field]; !ok {
+		return false
+	}
+	_, ok := UserFilters[field]
+	return ok
+}
+
+/*
+Parses filters and sort from query parameters.
+
+Each filterable field is a query parameter (e.g. `?address.city=SP&name=Jo`) and
+sort is a comma separated list of fields, descending when prefixed by `-`
+(e.g. `?sort=-name,email`).
+*/
+func ParseListCriteria(values url.Values) ([]FilterCondition, []SortField, *ValidationResponse) {
+	var filters []FilterCondition
+	for field, value := range values {
+		if listParams[field] {
+			continue
+		}
+		if !isFilterable(field) {
+			return nil, nil, INVALID_FILTER_FIELD
+		}
+		if len(value) != 1 || value[0] == "" {
+			return nil, nil, INVALID_FILTER_VALUE
+		}
+		if _, _, ok := parseDateRange(value[0]); UserFilters[field] == FILTER_DATE_RANGE && !ok {
+			return nil, nil, INVALID_FILTER_VALUE
+		}
+		filterValue := value[0]
+		if field == "email" {
+			filterValue = NormalizeEmail(filterValue)
+		}
+		filters = append(filters, FilterCondition{Field: field, Operator: UserFilters[field], Value: filterValue})
+	}
+	sort.Slice(filters, func(i, j int) bool { return filters[i].Field < filters[j].Field })
+
+	var sortFields []SortField
+	if spec := values.Get("sort"); spec != "" {
+		seen := make(map[string]bool)
+		for _, field := range strings.Split(spec, ",") {
+			sortField := SortField{Field: field}
+			if strings.HasPrefix(field, "-") {
+				sortField = SortField{Field: field[1:], Descending: true}
+			}
+			if !isFilterable(sortField.Field) || seen[sortField.Field] {
+				return nil, nil, INVALID_SORT_FIELD
+			}
+			seen[sortField.Field] = true
+			sortFields = append(sortFields, sortField)
+		}
+	}
+
+	return filters, sortFields, nil
+}
+
+// Returns the bounds of a date range, at least one of them is informed
+func parseDateRange(value string) (string, string, bool) {
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange {
+		to = from
+	}
+	if from == "" && to == "" {
+		return "", "", false
+	}
+	for _, bound := range []string{from, to} {
+		if _, ok := parseBirthDate(bound); bound != "" && !ok {
+			return "", "", false
+		}
+	}
+	return from, to, true
+}
+
+func sortSpec(sortFields []SortField) string {
+	fields := make([]string, len(sortFields))
+	for i, s := range sortFields {
+		fields[i] = s.Field
+		if s.Descending {
+			fields[i] = "-" + s.Field
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func (c ListCriteria) filterBSON() (bson.M, error) {
+	conditions := make(bson.A, 0)
+	for _, f := range c.Filters {
+		if !isFilterable(f.Field) {
+			return nil, fmt.Errorf("field %s is not filterable", f.Field)
+		}
+		switch f.Operator {
+		case FILTER_PREFIX:
+			pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(f.Value)}
+			conditions = append(conditions, bson.M{f.Field: bson.M{"$regex": pattern}})
+		case FILTER_DATE_RANGE:
+			from, to, ok := parseDateRange(f.Value)
+			if !ok {
+				return nil, fmt.Errorf("invalid date range %s", f.Value)
+			}
+			bounds := bson.M{}
+			if from != "" {
+				bounds["$gte"] = from
+			}
+			if to != "" {
+				bounds["$lte"] = to
+			}
+			conditions = append(conditions, bson.M{f.Field: bounds})
+		default:
+			conditions = append(conditions, bson.M{f.Field: bson.M{"$eq": f.Value}})
+		}
+	}
+
+	if c.AfterID != "" {
+		after, err := c.afterBSON()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, after)
+	}
+
+	if len(conditions) == 0 {
+		return bson.M{}, nil
+	}
+	return bson.M{"$and": conditions}, nil
+}
+
+/*
+Builds the keyset condition to fetch users placed after the cursor on the sort order.
+
+Users missing a sorted field are placed first when ascending and last when descending,
+as MongoDB sorts them, and matched with null, which also matches missing fields.
+*/
+func (c ListCriteria) afterBSON() (bson.M, error) {
+	objID, err := primitive.ObjectIDFromHex(c.AfterID)
+	if err != nil || len(c.AfterValues) != len(c.Sort) {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	keys := append(append([]SortField{}, c.Sort...), SortField{Field: "_id"})
+	values := make([]interface{}, 0, len(keys))
+	for _, v := range c.AfterValues {
+		if v == nil {
+			values = append(values, nil)
+			continue
+		}
+		values = append(values, *v)
+	}
+	values = append(values, objID)
+
+	or := make(bson.A, 0, len(keys))
+	for i, key := range keys {
+		and := bson.M{}
+		for j := 0; j < i; j++ {
+			and[keys[j].Field] = bson.M{"$eq": values[j]}
+		}
+		switch {
+		case values[i] == nil && key.Descending:
+			// Nothing is placed after missing values but missing values
+			continue
+		case values[i] == nil:
+			and[key.Field] = bson.M{"$ne": nil}
+		case key.Descending:
+			and["$or"] = bson.A{
+				bson.M{key.Field: bson.M{"$lt": values[i]}},
+				bson.M{key.Field: bson.M{"$eq": nil}},
+			}
+		default:
+			and[key.Field] = bson.M{"$gt": values[i]}
+		}
+		or = append(or, and)
+	}
+	return bson.M{"$or": or}, nil
+}
+
+func (c ListCriteria) sortBSON() bson.D {
+	fields := make(bson.D, 0, len(c.Sort)+1)
+	for _, s := range c.Sort {
+		direction := 1
+		if s.Descending {
+			direction = -1
+		}
+		fields = append(fields, bson.E{Key: s.Field, Value: direction})
+	}
+	return append(fields, bson.E{Key: "_id", Value: 1})
+}
@@ -0,0 +1,348 @@
+package users
+
+import (
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseListCriteria(t *testing.T) {
+
+	tests := []struct {
+		name               string
+		inputQuery         string
+		expectedFilters    []FilterCondition
+		expectedSort       []SortField
+		expectedValidation *ValidationResponse
+	}{
+		{
+			name:            "no filters",
+			inputQuery:      "limit=10&next=abc",
+			expectedFilters: nil,
+			expectedSort:    nil,
+		},
+		{
+			name:       "filters and sort",
+			inputQuery: "name=Te&email=test@test.com&sort=-address.country,name",
+			expectedFilters: []FilterCondition{
+				{Field: "email", Operator: FILTER_EQUALS, Value: "test@test.com"},
+				{Field: "name", Operator: FILTER_PREFIX, Value: "Te"},
+			},
+			expectedSort: []SortField{
+				{Field: "address.country", Descending: true},
+				{Field: "name"},
+			},
+		},
+		{
+			name:               "field out of user access",
+			inputQuery:         "role=admin",
+			expectedValidation: INVALID_FILTER_FIELD,
+		},
+		{
+			name:               "field not filterable",
+			inputQuery:         "address.zip=12345-678",
+			expectedValidation: INVALID_FILTER_FIELD,
+		},
+		{
+			name:               "repeated filter",
+			inputQuery:         "email=a@test.com&email=b@test.com",
+			expectedValidation: INVALID_FILTER_VALUE,
+		},
+		{
+			name:               "empty filter",
+			inputQuery:         "name=",
+			expectedValidation: INVALID_FILTER_VALUE,
+		},
+		{
+			name:            "birth date range",
+			inputQuery:      "birthDate=1990-01-01..1999-12-31",
+			expectedFilters: []FilterCondition{{Field: "birthDate", Operator: FILTER_DATE_RANGE, Value: "1990-01-01..1999-12-31"}},
+			expectedSort:    nil,
+		},
+		{
+			name:               "birth date range out of ISO format",
+			inputQuery:         "birthDate=01/01/1990..",
+			expectedValidation: INVALID_FILTER_VALUE,
+		},
+		{
+			name:               "repeated sort field",
+			inputQuery:         "sort=name,-name",
+			expectedValidation: INVALID_SORT_FIELD,
+		},
+		{
+			name:               "sort by password",
+			inputQuery:         "sort=password",
+			expectedValidation: INVALID_SORT_FIELD,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			values, _ := url.ParseQuery(tc.inputQuery)
+
+			filters, sort, validation := ParseListCriteria(values)
+
+			if validation != tc.expectedValidation {
+				t.Errorf("Expecting validation %v , but returns %v", tc.expectedValidation, validation)
+			}
+
+			if !reflect.DeepEqual(filters, tc.expectedFilters) {
+				t.Errorf("Expecting filters %v , but returns %v", tc.expectedFilters, filters)
+			}
+
+			if !reflect.DeepEqual(sort, tc.expectedSort) {
+				t.Errorf("Expecting sort %v , but returns %v", tc.expectedSort, sort)
+			}
+		})
+	}
+}
+
+func TestListCriteriaFilterBSON(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	objID, _ := primitive.ObjectIDFromHex(userID)
+	name := "Test"
+
+	tests := []struct {
+		name             string
+		inputParam       ListCriteria
+		expectedResponse bson.M
+		expectError      bool
+	}{
+		{
+			name:             "empty criteria",
+			inputParam:       ListCriteria{},
+			expectedResponse: bson.M{},
+		},
+		{
+			name: "prefix filter",
+			inputParam: ListCriteria{
+				Filters: []FilterCondition{{Field: "name", Operator: FILTER_PREFIX, Value: "A.b"}},
+			},
+			expectedResponse: bson.M{"$and": bson.A{
+				bson.M{"name": bson.M{"$regex": primitive.Regex{Pattern: `^A\.b`}}},
+			}},
+		},
+		{
+			name: "date range filter",
+			inputParam: ListCriteria{
+				Filters: []FilterCondition{
+					{Field: "birthDate", Operator: FILTER_DATE_RANGE, Value: "1990-01-01.."},
+					{Field: "birthDate", Operator: FILTER_DATE_RANGE, Value: "1990-05-01"},
+				},
+			},
+			expectedResponse: bson.M{"$and": bson.A{
+				bson.M{"birthDate": bson.M{"$gte": "1990-01-01"}},
+				bson.M{"birthDate": bson.M{"$gte": "1990-05-01", "$lte": "1990-05-01"}},
+			}},
+		},
+		{
+			name: "after cursor on sort",
+			inputParam: ListCriteria{
+				Sort:        []SortField{{Field: "name", Descending: true}},
+				AfterID:     userID,
+				AfterValues: []*string{&name},
+			},
+			expectedResponse: bson.M{"$and": bson.A{
+				bson.M{"$or": bson.A{
+					bson.M{"$or": bson.A{
+						bson.M{"name": bson.M{"$lt": "Test"}},
+						bson.M{"name": bson.M{"$eq": nil}},
+					}},
+					bson.M{"name": bson.M{"$eq": "Test"}, "_id": bson.M{"$gt": objID}},
+				}},
+			}},
+		},
+		{
+			name: "after cursor missing the sort field",
+			inputParam: ListCriteria{
+				Sort:        []SortField{{Field: "birthDate"}},
+				AfterID:     userID,
+				AfterValues: []*string{nil},
+			},
+			expectedResponse: bson.M{"$and": bson.A{
+				bson.M{"$or": bson.A{
+					bson.M{"birthDate": bson.M{"$ne": nil}},
+					bson.M{"birthDate": bson.M{"$eq": nil}, "_id": bson.M{"$gt": objID}},
+				}},
+			}},
+		},
+		{
+			name: "field not whitelisted",
+			inputParam: ListCriteria{
+				Filters: []FilterCondition{{Field: "password", Operator: FILTER_EQUALS, Value: "12345"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "cursor values out of sort",
+			inputParam: ListCriteria{
+				AfterID:     userID,
+				AfterValues: []*string{&name},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			result, err := tc.inputParam.filterBSON()
+
+			if (err != nil) != tc.expectError {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectError, err)
+			}
+
+			if !tc.expectError && !reflect.DeepEqual(result, tc.expectedResponse) {
+				t.Errorf("Expecting filter %v , but returns %v", tc.expectedResponse, result)
+			}
+		})
+	}
+}
+
+func TestListCriteriaPagesUsersMissingSortField(t *testing.T) {
+
+	// Users sorted by birthDate, some without one, two sharing it
+	users := []User{
+		{ID: "64260e1da4c0c814bda57341", BirthDate: "1990-05-01"},
+		{ID: "64260e1da4c0c814bda57342"},
+		{ID: "64260e1da4c0c814bda57343", BirthDate: "1985-01-01"},
+		{ID: "64260e1da4c0c814bda57344"},
+		{ID: "64260e1da4c0c814bda57345", BirthDate: "1990-05-01"},
+		{ID: "64260e1da4c0c814bda57346"},
+	}
+	documents := make([]bson.M, 0, len(users))
+	for _, user := range users {
+		objID, _ := primitive.ObjectIDFromHex(user.ID)
+		document := bson.M{"_id": objID}
+		if user.BirthDate != "" {
+			document["birthDate"] = user.BirthDate
+		}
+		documents = append(documents, document)
+	}
+
+	tests := []struct {
+		name        string
+		inputSort   []SortField
+		expectedIDs []string
+	}{
+		{
+			name:        "ascending",
+			inputSort:   []SortField{{Field: "birthDate"}},
+			expectedIDs: []string{users[1].ID, users[3].ID, users[5].ID, users[2].ID, users[0].ID, users[4].ID},
+		},
+		{
+			name:        "descending",
+			inputSort:   []SortField{{Field: "birthDate", Descending: true}},
+			expectedIDs: []string{users[0].ID, users[4].ID, users[2].ID, users[1].ID, users[3].ID, users[5].ID},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			criteria := ListCriteria{Sort: tc.inputSort}
+			listed := []string{}
+			for pages := 0; pages < len(users); pages++ {
+				filter, err := criteria.filterBSON()
+				if err != nil {
+					t.Fatalf("Expecting error %v , but returns %v", nil, err)
+				}
+				page := findDocuments(documents, filter, criteria.sortBSON(), 2)
+				for _, document := range page {
+					listed = append(listed, document["_id"].(primitive.ObjectID).Hex())
+				}
+				if len(page) < 2 {
+					break
+				}
+				last := page[len(page)-1]
+				user := User{ID: last["_id"].(primitive.ObjectID).Hex()}
+				user.BirthDate, _ = last["birthDate"].(string)
+				cursor := newCursor(user, tc.inputSort)
+				criteria.AfterID, criteria.AfterValues = cursor.ID, cursor.Values
+			}
+
+			if !reflect.DeepEqual(listed, tc.expectedIDs) {
+				t.Errorf("Expecting users %v , but returns %v", tc.expectedIDs, listed)
+			}
+		})
+	}
+}
+
+// Returns the first limit documents matching filter on the sort order, as MongoDB would
+func findDocuments(documents []bson.M, filter bson.M, order bson.D, limit int) []bson.M {
+	found := []bson.M{}
+	for _, document := range documents {
+		if matchesFilter(document, filter) {
+			found = append(found, document)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		for _, key := range order {
+			if c := compareValues(found[i][key.Key], found[j][key.Key]); c != 0 {
+				return c*key.Value.(int) < 0
+			}
+		}
+		return false
+	})
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found
+}
+
+// Evaluates the operators built by ListCriteria, missing fields match null and compare to no value
+func matchesFilter(document bson.M, filter bson.M) bool {
+	for key, condition := range filter {
+		switch key {
+		case "$and", "$or":
+			matched := 0
+			for _, item := range condition.(bson.A) {
+				if matchesFilter(document, item.(bson.M)) {
+					matched++
+				}
+			}
+			if (key == "$and" && matched < len(condition.(bson.A))) || (key == "$or" && matched == 0) {
+				return false
+			}
+		default:
+			value := document[key]
+			for operator, operand := range condition.(bson.M) {
+				c := compareValues(value, operand)
+				comparable := value != nil && operand != nil
+				ok := map[string]bool{
+					"$eq":  c == 0,
+					"$ne":  c != 0,
+					"$gt":  comparable && c > 0,
+					"$lt":  comparable && c < 0,
+					"$gte": comparable && c >= 0,
+					"$lte": comparable && c <= 0,
+				}[operator]
+				if !ok {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Null sorts before any value, as on MongoDB
+func compareValues(a interface{}, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	if objID, ok := a.(primitive.ObjectID); ok {
+		return strings.Compare(objID.Hex(), b.(primitive.ObjectID).Hex())
+	}
+	return strings.Compare(a.(string), b.(string))
+}
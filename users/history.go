@@ -0,0 +1,172 @@
+package users
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Audit entries read at once when walking back the history of a user
+const historyBatch int = 100
+
+/*
+Returns the user as it was before the changes selected by reverted, or nil when
+the user did not exist or was deleted by then.
+
+The current user is copied and walked back through its audit log, newest first, putting
+back the values changed until the first entry not selected, which tells the
+version and the last update of the user returned. The walk ends on the oldest
+entry recorded, so older changes can not be reverted.
+
+Entries walked must follow each other from the current version down, a version
+missing on the audit log fails with HISTORY_INCOMPLETE, as the user can not be
+rebuilt past it.
+*/
+func (svc *userService) userBefore(current *User, reverted func(entry AuditEntry) bool) (*User, error) {
+	user := *current
+	exists, walked := true, false
+	expected := current.Version
+	criteria := AuditCriteria{UserID: current.ID}
+	for {
+		entries, err := svc.audits.ListEntries(criteria, historyBatch)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on ListEntries : %v", err))
+			return nil, &userServiceError{code: GET_USER_FAILED}
+		}
+
+		for _, entry := range entries {
+			if entry.Version != expected {
+				fmt.Println(fmt.Errorf("Version %d missing on the history of user %s", expected, current.ID))
+				return nil, &userServiceError{code: HISTORY_INCOMPLETE}
+			}
+			expected--
+
+			if !reverted(entry) {
+				if walked {
+					user.Version = entry.Version
+					user.UpdatedAt, user.UpdatedBy = &entry.Timestamp, entry.Actor
+				}
+				return existing(&user, exists), nil
+			}
+			walked = true
+			for _, change := range entry.Changes {
+				user.setAudited(change.Field, change.Before)
+			}
+			user.Version = entry.Version - 1
+			user.UpdatedAt, user.UpdatedBy = nil, ""
+
+			switch entry.Action {
+			case AUDIT_CREATE, AUDIT_RESTORE:
+				exists = false
+			case AUDIT_DELETE:
+				exists = true
+			}
+		}
+
+		if len(entries) < historyBatch {
+			return existing(&user, exists), nil
+		}
+		criteria.AfterID = entries[len(entries)-1].ID
+	}
+}
+
+func existing(user *User, exists bool) *User {
+	if !exists {
+		return nil
+	}
+	return user
+}
+
+// Puts back a value recorded on the audit log, a nil value removes the field
+func (u *User) setAudited(field string, value interface{}) {
+	switch field {
+	case "password":
+		// Passwords are redacted, the current one is kept
+	case "roles":
+		u.Roles = auditedStrings(value)
+	case "emailVerified":
+		verified, _ := value.(bool)
+		u.EmailVerified = verified
+	case "verifiedAt":
+		u.VerifiedAt = auditedTime(value)
+	default:
+		if set, ok := userSetters[field]; ok {
+			str, _ := value.(string)
+			set(u, str)
+		}
+	}
+}
+
+// Values read from the audit log are decoded as BSON values
+func auditedStrings(value interface{}) []string {
+	switch values := value.(type) {
+	case []string:
+		return values
+	case primitive.A:
+		strs := make([]string, 0, len(values))
+		for _, v := range values {
+			if str, ok := v.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+func auditedTime(value interface{}) *time.Time {
+	switch t := value.(type) {
+	case time.Time:
+		return &t
+	case primitive.DateTime:
+		utc := t.Time().UTC()
+		return &utc
+	}
+	return nil
+}
+
+func (svc *userService) GetUserAsOf(userID string, asOf time.Time) (*User, error) {
+	current, err := svc.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := svc.userBefore(current, func(entry AuditEntry) bool { return entry.Timestamp.After(asOf) })
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		fmt.Println(fmt.Errorf("User not exists at %v", asOf))
+		return nil, &userServiceError{code: USER_NOT_EXISTS}
+	}
+
+	user.computeAge(asOf)
+	return user, nil
+}
+
+func (svc *userService) RevertUser(actor Actor, userID string, revision int64, precondition Precondition) (int64, error) {
+	current, err := svc.findUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	if revision <= 0 || revision > current.Version {
+		fmt.Println(fmt.Errorf("Revision %d not found", revision))
+		return 0, &userServiceError{code: REVISION_NOT_FOUND}
+	}
+
+	user, err := svc.userBefore(current, func(entry AuditEntry) bool { return entry.Version > revision })
+	if err != nil {
+		return 0, err
+	}
+
+	// Revisions older than the audit log, or when the user did not exist, can not be reverted to
+	if user == nil || user.Version > revision {
+		fmt.Println(fmt.Errorf("Revision %d not found", revision))
+		return 0, &userServiceError{code: REVISION_NOT_FOUND}
+	}
+
+	// Roles and the password are not replaced, the revision is applied as any other update
+	return svc.UpdateUser(actor, userID, *user, precondition)
+}
@@ -0,0 +1,209 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
+
+	"github.com/golang/mock/gomock"
+)
+
+const historyUserID string = "64260e1da4c0c814bda5734a"
+
+var createdAt time.Time = time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+var renamedAt time.Time = time.Date(2023, 4, 2, 10, 0, 0, 0, time.UTC)
+var deletedAt time.Time = time.Date(2023, 4, 3, 10, 0, 0, 0, time.UTC)
+var restoredAt time.Time = time.Date(2023, 4, 4, 10, 0, 0, 0, time.UTC)
+
+// History of a user created, renamed, deleted and restored, newest first
+var historyEntries []AuditEntry = []AuditEntry{
+	{ID: "64260e1da4c0c814bda57354", UserID: historyUserID, Action: AUDIT_RESTORE, Actor: "admin", Timestamp: restoredAt, Version: 4, Changes: []FieldChange{}},
+	{ID: "64260e1da4c0c814bda57353", UserID: historyUserID, Action: AUDIT_DELETE, Actor: "admin", Timestamp: deletedAt, Version: 3, Changes: []FieldChange{
+		{Field: "email", Before: "test@test.com", After: nil},
+		{Field: "name", Before: "Second", After: nil},
+	}},
+	{ID: "64260e1da4c0c814bda57352", UserID: historyUserID, Action: AUDIT_UPDATE, Actor: "admin", Timestamp: renamedAt, Version: 2, Changes: []FieldChange{
+		{Field: "age", Before: "33", After: nil},
+		{Field: "name", Before: "First", After: "Second"},
+	}},
+	{ID: "64260e1da4c0c814bda57351", UserID: historyUserID, Action: AUDIT_CREATE, Actor: historyUserID, Timestamp: createdAt, Version: 1, Changes: []FieldChange{
+		{Field: "age", Before: nil, After: "33"},
+		{Field: "email", Before: nil, After: "test@test.com"},
+		{Field: "name", Before: nil, After: "First"},
+		{Field: "password", Before: nil, After: REDACTED},
+	}},
+}
+
+func historyUser() *User {
+	return &User{ID: historyUserID, Name: "Second", Email: "test@test.com", Version: 4, UpdatedAt: &restoredAt, UpdatedBy: "admin"}
+}
+
+func TestServiceGetUserAsOf(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		inputAsOf     time.Time
+		expectedUser  *User
+		expectedError error
+	}{
+		{
+			name:         "current user",
+			inputAsOf:    restoredAt.Add(time.Hour),
+			expectedUser: historyUser(),
+		},
+		{
+			name:         "user before rename",
+			inputAsOf:    createdAt.Add(time.Hour),
+			expectedUser: &User{ID: historyUserID, Name: "First", Email: "test@test.com", Age: "33", Version: 1, UpdatedAt: &createdAt, UpdatedBy: historyUserID},
+		},
+		{
+			name:         "user renamed",
+			inputAsOf:    renamedAt,
+			expectedUser: &User{ID: historyUserID, Name: "Second", Email: "test@test.com", Version: 2, UpdatedAt: &renamedAt, UpdatedBy: "admin"},
+		},
+		{
+			name:          "user deleted",
+			inputAsOf:     deletedAt.Add(time.Hour),
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
+		{
+			name:          "user not created yet",
+			inputAsOf:     createdAt.Add(-time.Hour),
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			audits := NewMockAuditRepository(ctrl)
+
+			repo.
+				EXPECT().
+				FindUserByID(historyUserID, gomock.Any()).
+				Return(historyUser(), nil)
+			audits.
+				EXPECT().
+				ListEntries(AuditCriteria{UserID: historyUserID}, historyBatch).
+				Return(historyEntries, nil)
+
+			service := NewUserService(repo, NewMockVerificationRepository(ctrl), audits, nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{})
+
+			user, err := service.GetUserAsOf(historyUserID, tc.inputAsOf)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(user, tc.expectedUser) {
+				t.Errorf("Expecting user %v , but returns %v", tc.expectedUser, user)
+			}
+		})
+	}
+}
+
+func TestServiceRevertUser(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		setupMock       func(repository *MockUserRepository, audits *MockAuditRepository)
+		inputRevision   int64
+		expectedVersion int64
+		expectedError   error
+	}{
+		{
+			name: "revert to revision",
+			setupMock: func(repository *MockUserRepository, audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: historyUserID}, historyBatch).
+					Return(historyEntries, nil)
+				repository.
+					EXPECT().
+					UpdateUser(testActor, historyUserID, gomock.Any(), Precondition{4}).
+					DoAndReturn(func(actor Actor, ID string, user User, precondition Precondition) (*UserEvent, error) {
+						if user.Name != "First" || user.Age != "33" || user.Email != "test@test.com" {
+							t.Errorf("Expecting user of revision 1 , but returns %v", user)
+						}
+						return &UserEvent{Version: 5}, nil
+					})
+			},
+			inputRevision:   1,
+			expectedVersion: 5,
+			expectedError:   nil,
+		},
+		{
+			name: "revision when the user was deleted",
+			setupMock: func(repository *MockUserRepository, audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: historyUserID}, historyBatch).
+					Return(historyEntries, nil)
+			},
+			inputRevision:   3,
+			expectedVersion: 0,
+			expectedError:   &userServiceError{code: REVISION_NOT_FOUND},
+		},
+		{
+			name: "revision older than the audit log",
+			setupMock: func(repository *MockUserRepository, audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: historyUserID}, historyBatch).
+					Return(historyEntries[:2], nil)
+			},
+			inputRevision:   1,
+			expectedVersion: 0,
+			expectedError:   &userServiceError{code: REVISION_NOT_FOUND},
+		},
+		{
+			name: "revision missing on the audit log",
+			setupMock: func(repository *MockUserRepository, audits *MockAuditRepository) {
+				audits.
+					EXPECT().
+					ListEntries(AuditCriteria{UserID: historyUserID}, historyBatch).
+					Return([]AuditEntry{historyEntries[0], historyEntries[1], historyEntries[3]}, nil)
+			},
+			inputRevision:   1,
+			expectedVersion: 0,
+			expectedError:   &userServiceError{code: HISTORY_INCOMPLETE},
+		},
+		{
+			name:            "revision not made yet",
+			setupMock:       func(repository *MockUserRepository, audits *MockAuditRepository) {},
+			inputRevision:   5,
+			expectedVersion: 0,
+			expectedError:   &userServiceError{code: REVISION_NOT_FOUND},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			audits := NewMockAuditRepository(ctrl)
+
+			repo.
+				EXPECT().
+				FindUserByID(historyUserID, gomock.Any()).
+				Return(historyUser(), nil).
+				AnyTimes()
+			tc.setupMock(repo, audits)
+
+			service := NewUserService(repo, NewMockVerificationRepository(ctrl), audits, nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{})
+
+			version, err := service.RevertUser(testActor, historyUserID, tc.inputRevision, nil)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if version != tc.expectedVersion {
+				t.Errorf("Expecting version %v , but returns %v", tc.expectedVersion, version)
+			}
+		})
+	}
+}
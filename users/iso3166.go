@@ -0,0 +1,85 @@
+package users
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ISO 3166-1 alpha-2 country codes
+var countryCodes = codeSet(`
+AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW
+BY BZ CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI
+FJ FK FM FO FR GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN
+IO IQ IR IS IT JE JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME
+MF MG MH MK ML MM MN MO MP MQ MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF
+PG PH PK PL PM PN PR PS PT PW PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV
+SX SY SZ TC TD TF TG TH TJ TK TL TM TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE
+YT ZA ZM ZW`)
+
+// ISO 3166-2 subdivision codes, without the country prefix, of the countries checked.
+// States of other countries only need the subdivision code format.
+var subdivisionCodes = map[string]map[string]bool{
+	"AR": codeSet(`A B C D E F G H J K L M N P Q R S T U V W X Y Z`),
+	"AU": codeSet(`ACT NSW NT QLD SA TAS VIC WA`),
+	"BR": codeSet(`AC AL AM AP BA CE DF ES GO MA MG MS MT PA PB PE PI PR RJ RN RO RR RS SC SE SP TO`),
+	"CA": codeSet(`AB BC MB NB NL NS NT NU ON PE QC SK YT`),
+	"DE": codeSet(`BB BE BW BY HB HE HH MV NI NW RP SH SL SN ST TH`),
+	"MX": codeSet(`AGU BCN BCS CAM CHH CHP CMX COA COL DUR GRO GUA HID JAL MEX MIC MOR NAY NLE OAX PUE QUE ROO SIN SLP SON TAB TAM TLA VER YUC ZAC`),
+	"US": codeSet(`AK AL AR AS AZ CA CO CT DC DE FL GA GU HI IA ID IL IN KS KY LA MA MD ME MI MN MO MP MS MT NC ND NE NH NJ NM
+		NV NY OH OK OR PA PR RI SC SD TN TX UM UT VA VI VT WA WI WV WY`),
+}
+
+var subdivisionFormat = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+
+// Postal code formats of the countries checked, other countries only need the generic format
+var zipFormats = map[string]*regexp.Regexp{
+	"AR": regexp.MustCompile(`^[A-Z]?\d{4}([A-Z]{3})?$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+var zipFormat = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 -]{1,9}$`)
+
+func codeSet(codes string) map[string]bool {
+	set := make(map[string]bool)
+	for _, code := range strings.Fields(codes) {
+		set[code] = true
+	}
+	return set
+}
+
+// Tells if the state is a subdivision of the country, either as "SP" or as "BR-SP"
+func validSubdivision(country string, state string) bool {
+	if prefix, code, found := strings.Cut(state, "-"); found {
+		if prefix != country {
+			return false
+		}
+		state = code
+	}
+
+	if subdivisions, ok := subdivisionCodes[country]; ok {
+		return subdivisions[state]
+	}
+	return subdivisionFormat.MatchString(state)
+}
+
+// Tells if the ZIP follows the postal code format of the country
+func validZIP(country string, zip string) bool {
+	if format, ok := zipFormats[country]; ok {
+		return format.MatchString(strings.ToUpper(zip))
+	}
+	return zipFormat.MatchString(zip)
+}
@@ -0,0 +1,214 @@
+package users
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Actor recorded on the audit log for the users changed by data migrations
+const MIGRATOR string = "migrator"
+
+// Result of converting legacy ages into birth dates
+type BirthDateReport struct {
+	Converted int
+	// Users whose legacy age could not be converted, they keep it until updated
+	Failed []BirthDateFailure
+}
+
+type BirthDateFailure struct {
+	ID  string
+	Age string
+}
+
+// Stored user with a legacy age, which may not even be a string
+type legacyAgeDocument struct {
+	ID  primitive.ObjectID `bson:"_id"`
+	Age interface{}        `bson:"age"`
+}
+
+/*
+Converts legacy ages holding a date into birth dates.
+
+Numeric ages can not be converted, since the birth date would be made up,
+so they are reported along with any other value not holding a date.
+Running it again only goes through the users not converted yet.
+
+Each user converted is a new version, recorded on the audit log and the outbox
+by MIGRATOR as any other change.
+*/
+func MigrateBirthDates(client *mongo.Client, database string) (*BirthDateReport, error) {
+	repo := &userRepository{client: client, database: database}
+	coll := client.Database(database).Collection(userCollection)
+	ctx := context.Background()
+
+	filter := bson.M{
+		"age":       bson.M{"$exists": true, "$nin": bson.A{nil, ""}},
+		"birthDate": bson.M{"$in": bson.A{nil, ""}},
+	}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &BirthDateReport{Failed: []BirthDateFailure{}}
+	for cursor.Next(ctx) {
+		var document legacyAgeDocument
+		if err := cursor.Decode(&document); err != nil {
+			return report, err
+		}
+
+		age, isString := document.Age.(string)
+		birthDate, ok := legacyBirthDate(age)
+		if !isString || !ok {
+			report.Failed = append(report.Failed, BirthDateFailure{ID: document.ID.Hex(), Age: fmt.Sprint(document.Age)})
+			continue
+		}
+
+		// The age is matched again so a user updated meanwhile is left as is
+		update := bson.M{
+			"$set":   bson.M{"birthDate": birthDate},
+			"$unset": bson.M{"age": ""},
+			"$inc":   bson.M{"version": 1},
+		}
+		converted, err := repo.migrate(bson.M{"_id": document.ID, "age": age}, update)
+		if err != nil {
+			return report, err
+		}
+		if converted {
+			report.Converted++
+		}
+	}
+	return report, cursor.Err()
+}
+
+// Applies update on the user matching filter as MIGRATOR, returns false when no user matches
+func (repo *userRepository) migrate(filter bson.M, update bson.M) (bool, error) {
+	actor := Actor{ID: MIGRATOR}
+	stamp(update, actor)
+	err := repo.transact(func(ctx context.Context) error {
+		_, err := repo.updateChanged(ctx, AUDIT_UPDATE, actor, filter, update)
+		return err
+	})
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Result of normalizing stored emails
+type EmailReport struct {
+	Normalized int
+	// Users whose email matches the email of another user ignoring case, they are left as is
+	Conflicts []EmailConflict
+}
+
+type EmailConflict struct {
+	ID         string
+	Email      string
+	ConflictID string
+}
+
+// Stored user with an email to normalize
+type emailDocument struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Email string             `bson:"email"`
+}
+
+/*
+Stores user emails as normalized by NormalizeEmail.
+
+Users sharing an email ignoring case are reported as conflicts and kept as
+they are, they must be resolved before EMAIL_INDEX can be created. Each user
+normalized is a new version, recorded by MIGRATOR as any other change.
+*/
+func NormalizeEmails(client *mongo.Client, database string) (*EmailReport, error) {
+	repo := &userRepository{client: client, database: database}
+	coll := client.Database(database).Collection(userCollection)
+	ctx := context.Background()
+
+	// Emails with upper case letters or surrounding spaces
+	filter := bson.M{"email": bson.M{"$regex": primitive.Regex{Pattern: `[A-Z]|^\s|\s$`}}}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &EmailReport{Conflicts: []EmailConflict{}}
+	for cursor.Next(ctx) {
+		var document emailDocument
+		if err := cursor.Decode(&document); err != nil {
+			return report, err
+		}
+		email := NormalizeEmail(document.Email)
+
+		var other emailDocument
+		conflict := bson.M{"_id": bson.M{"$ne": document.ID}, "email": email}
+		err := coll.FindOne(ctx, conflict, options.FindOne().SetCollation(EmailCollation)).Decode(&other)
+		if err == nil {
+			report.Conflicts = append(report.Conflicts, EmailConflict{ID: document.ID.Hex(), Email: document.Email, ConflictID: other.ID.Hex()})
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return report, err
+		}
+
+		update := bson.M{"$set": bson.M{"email": email}, "$inc": bson.M{"version": 1}}
+		normalized, err := repo.migrate(bson.M{"_id": document.ID, "email": document.Email}, update)
+		if err != nil {
+			return report, err
+		}
+		if normalized {
+			report.Normalized++
+		}
+	}
+	return report, cursor.Err()
+}
+
+// Creates EMAIL_INDEX, failing while users share an email ignoring case
+func CreateEmailIndex(client *mongo.Client, database string) error {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName(EMAIL_INDEX).SetUnique(true).SetCollation(EmailCollation),
+	}
+	_, err := client.Database(database).Collection(userCollection).Indexes().CreateOne(context.Background(), index)
+	return err
+}
+
+/*
+Replaces EMAIL_INDEX by one leaving soft deleted users out, so their emails can sign up again.
+
+Partial indexes can not filter on a missing deletedAt, so the index is unique on email and
+deletedAt: users not deleted share a null deletedAt and keep unique emails, while deleted
+users hold their deletion time. Restoring a user whose email was taken meanwhile fails.
+*/
+func ExcludeDeletedFromEmailIndex(client *mongo.Client, database string) error {
+	if err := DropEmailIndex(client, database); err != nil {
+		return err
+	}
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}, {Key: "deletedAt", Value: 1}},
+		Options: options.Index().SetName(EMAIL_INDEX).SetUnique(true).SetCollation(EmailCollation),
+	}
+	_, err := client.Database(database).Collection(userCollection).Indexes().CreateOne(context.Background(), index)
+	return err
+}
+
+// Puts back EMAIL_INDEX over deleted users, failing while a deleted user shares an email with another user
+func IncludeDeletedInEmailIndex(client *mongo.Client, database string) error {
+	if err := DropEmailIndex(client, database); err != nil {
+		return err
+	}
+	return CreateEmailIndex(client, database)
+}
+
+func DropEmailIndex(client *mongo.Client, database string) error {
+	_, err := client.Database(database).Collection(userCollection).Indexes().DropOne(context.Background(), EMAIL_INDEX)
+	return err
+}
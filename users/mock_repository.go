@@ -5,7 +5,9 @@
 package users
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,17 +36,18 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 }
 
 // DeleteUser mocks base method.
-func (m *MockUserRepository) DeleteUser(userID string) error {
+func (m *MockUserRepository) DeleteUser(actor Actor, userID string, precondition Precondition) (*UserEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", userID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "DeleteUser", actor, userID, precondition)
+	ret0, _ := ret[0].(*UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserRepositoryMockRecorder) DeleteUser(userID interface{}) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) DeleteUser(actor, userID, precondition interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepository)(nil).DeleteUser), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepository)(nil).DeleteUser), actor, userID, precondition)
 }
 
 // FindUserByEmail mocks base method.
@@ -78,30 +81,333 @@ func (mr *MockUserRepositoryMockRecorder) FindUserByID(ID, projection interface{
 }
 
 // InsertUser mocks base method.
-func (m *MockUserRepository) InsertUser(user User) (string, error) {
+func (m *MockUserRepository) InsertUser(actor Actor, user User) (*UserEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertUser", user)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "InsertUser", actor, user)
+	ret0, _ := ret[0].(*UserEvent)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // InsertUser indicates an expected call of InsertUser.
-func (mr *MockUserRepositoryMockRecorder) InsertUser(user interface{}) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) InsertUser(actor, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockUserRepository)(nil).InsertUser), actor, user)
+}
+
+// ListUsers mocks base method.
+func (m *MockUserRepository) ListUsers(criteria ListCriteria, limit int, projection Projection) ([]User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", criteria, limit, projection)
+	ret0, _ := ret[0].([]User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUserRepositoryMockRecorder) ListUsers(criteria, limit, projection interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertUser", reflect.TypeOf((*MockUserRepository)(nil).InsertUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserRepository)(nil).ListUsers), criteria, limit, projection)
+}
+
+// PatchUser mocks base method.
+func (m *MockUserRepository) PatchUser(actor Actor, userID string, changes UserChanges, precondition Precondition) (*UserEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchUser", actor, userID, changes, precondition)
+	ret0, _ := ret[0].(*UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchUser indicates an expected call of PatchUser.
+func (mr *MockUserRepositoryMockRecorder) PatchUser(actor, userID, changes, precondition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchUser", reflect.TypeOf((*MockUserRepository)(nil).PatchUser), actor, userID, changes, precondition)
+}
+
+// PurgeUsers mocks base method.
+func (m *MockUserRepository) PurgeUsers(actor Actor, deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUsers", actor, deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeUsers indicates an expected call of PurgeUsers.
+func (mr *MockUserRepositoryMockRecorder) PurgeUsers(actor, deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUsers", reflect.TypeOf((*MockUserRepository)(nil).PurgeUsers), actor, deletedBefore)
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserRepository) RestoreUser(actor Actor, userID string) (*UserEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", actor, userID)
+	ret0, _ := ret[0].(*UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserRepositoryMockRecorder) RestoreUser(actor, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserRepository)(nil).RestoreUser), actor, userID)
 }
 
 // UpdateUser mocks base method.
-func (m *MockUserRepository) UpdateUser(userID string, user User) error {
+func (m *MockUserRepository) UpdateUser(actor Actor, userID string, user User, precondition Precondition) (*UserEvent, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", userID, user)
+	ret := m.ctrl.Call(m, "UpdateUser", actor, userID, user, precondition)
+	ret0, _ := ret[0].(*UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockUserRepositoryMockRecorder) UpdateUser(actor, userID, user, precondition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserRepository)(nil).UpdateUser), actor, userID, user, precondition)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUserRepository) VerifyEmail(actor Actor, userID, email string, verifiedAt time.Time) (*UserEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", actor, userID, email, verifiedAt)
+	ret0, _ := ret[0].(*UserEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserRepositoryMockRecorder) VerifyEmail(actor, userID, email, verifiedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUserRepository)(nil).VerifyEmail), actor, userID, email, verifiedAt)
+}
+
+// MockVerificationRepository is a mock of VerificationRepository interface.
+type MockVerificationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockVerificationRepositoryMockRecorder
+}
+
+// MockVerificationRepositoryMockRecorder is the mock recorder for MockVerificationRepository.
+type MockVerificationRepositoryMockRecorder struct {
+	mock *MockVerificationRepository
+}
+
+// NewMockVerificationRepository creates a new mock instance.
+func NewMockVerificationRepository(ctrl *gomock.Controller) *MockVerificationRepository {
+	mock := &MockVerificationRepository{ctrl: ctrl}
+	mock.recorder = &MockVerificationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVerificationRepository) EXPECT() *MockVerificationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ConsumeVerification mocks base method.
+func (m *MockVerificationRepository) ConsumeVerification(userID, tokenHash string) (*EmailVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeVerification", userID, tokenHash)
+	ret0, _ := ret[0].(*EmailVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeVerification indicates an expected call of ConsumeVerification.
+func (mr *MockVerificationRepositoryMockRecorder) ConsumeVerification(userID, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeVerification", reflect.TypeOf((*MockVerificationRepository)(nil).ConsumeVerification), userID, tokenHash)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockVerificationRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockUserRepositoryMockRecorder) UpdateUser(userID, user interface{}) *gomock.Call {
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockVerificationRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockVerificationRepository)(nil).EnsureIndexes))
+}
+
+// InsertVerification mocks base method.
+func (m *MockVerificationRepository) InsertVerification(verification EmailVerification) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertVerification", verification)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertVerification indicates an expected call of InsertVerification.
+func (mr *MockVerificationRepositoryMockRecorder) InsertVerification(verification interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertVerification", reflect.TypeOf((*MockVerificationRepository)(nil).InsertVerification), verification)
+}
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockAuditRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockAuditRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockAuditRepository)(nil).EnsureIndexes))
+}
+
+// ListEntries mocks base method.
+func (m *MockAuditRepository) ListEntries(criteria AuditCriteria, limit int) ([]AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEntries", criteria, limit)
+	ret0, _ := ret[0].([]AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEntries indicates an expected call of ListEntries.
+func (mr *MockAuditRepositoryMockRecorder) ListEntries(criteria, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockAuditRepository)(nil).ListEntries), criteria, limit)
+}
+
+// MockOutboxRepository is a mock of OutboxRepository interface.
+type MockOutboxRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepositoryMockRecorder
+}
+
+// MockOutboxRepositoryMockRecorder is the mock recorder for MockOutboxRepository.
+type MockOutboxRepositoryMockRecorder struct {
+	mock *MockOutboxRepository
+}
+
+// NewMockOutboxRepository creates a new mock instance.
+func NewMockOutboxRepository(ctrl *gomock.Controller) *MockOutboxRepository {
+	mock := &MockOutboxRepository{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepository) EXPECT() *MockOutboxRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimEvent mocks base method.
+func (m *MockOutboxRepository) ClaimEvent(now time.Time, lease time.Duration) (*OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimEvent", now, lease)
+	ret0, _ := ret[0].(*OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimEvent indicates an expected call of ClaimEvent.
+func (mr *MockOutboxRepositoryMockRecorder) ClaimEvent(now, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimEvent", reflect.TypeOf((*MockOutboxRepository)(nil).ClaimEvent), now, lease)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockOutboxRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockOutboxRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockOutboxRepository)(nil).EnsureIndexes))
+}
+
+// FailEvent mocks base method.
+func (m *MockOutboxRepository) FailEvent(ID, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailEvent", ID, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailEvent indicates an expected call of FailEvent.
+func (mr *MockOutboxRepositoryMockRecorder) FailEvent(ID, lastError interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailEvent", reflect.TypeOf((*MockOutboxRepository)(nil).FailEvent), ID, lastError)
+}
+
+// MarkPublished mocks base method.
+func (m *MockOutboxRepository) MarkPublished(ID string, publishedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkPublished", ID, publishedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkPublished indicates an expected call of MarkPublished.
+func (mr *MockOutboxRepositoryMockRecorder) MarkPublished(ID, publishedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkPublished", reflect.TypeOf((*MockOutboxRepository)(nil).MarkPublished), ID, publishedAt)
+}
+
+// PurgePublished mocks base method.
+func (m *MockOutboxRepository) PurgePublished(publishedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgePublished", publishedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgePublished indicates an expected call of PurgePublished.
+func (mr *MockOutboxRepositoryMockRecorder) PurgePublished(publishedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgePublished", reflect.TypeOf((*MockOutboxRepository)(nil).PurgePublished), publishedBefore)
+}
+
+// WatchEvents mocks base method.
+func (m *MockOutboxRepository) WatchEvents(ctx context.Context, publisher Publisher) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchEvents", ctx, publisher)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchEvents indicates an expected call of WatchEvents.
+func (mr *MockOutboxRepositoryMockRecorder) WatchEvents(ctx, publisher interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserRepository)(nil).UpdateUser), userID, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchEvents", reflect.TypeOf((*MockOutboxRepository)(nil).WatchEvents), ctx, publisher)
 }
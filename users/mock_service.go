@@ -6,6 +6,7 @@ package users
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,32 +35,32 @@ func (m *MockUserService) EXPECT() *MockUserServiceMockRecorder {
 }
 
 // CreateUser mocks base method.
-func (m *MockUserService) CreateUser(user User) (string, error) {
+func (m *MockUserService) CreateUser(actor Actor, user User) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", user)
+	ret := m.ctrl.Call(m, "CreateUser", actor, user)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockUserServiceMockRecorder) CreateUser(user interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) CreateUser(actor, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserService)(nil).CreateUser), actor, user)
 }
 
 // DeleteUser mocks base method.
-func (m *MockUserService) DeleteUser(userID string) error {
+func (m *MockUserService) DeleteUser(actor Actor, userID string, precondition Precondition) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", userID)
+	ret := m.ctrl.Call(m, "DeleteUser", actor, userID, precondition)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserServiceMockRecorder) DeleteUser(userID interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) DeleteUser(actor, userID, precondition interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserService)(nil).DeleteUser), userID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserService)(nil).DeleteUser), actor, userID, precondition)
 }
 
 // GetUser mocks base method.
@@ -77,16 +78,165 @@ func (mr *MockUserServiceMockRecorder) GetUser(userID interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockUserService)(nil).GetUser), userID)
 }
 
-// UpdateUser mocks base method.
-func (m *MockUserService) UpdateUser(userID string, user User) error {
+// GetUserAsOf mocks base method.
+func (m *MockUserService) GetUserAsOf(userID string, asOf time.Time) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserAsOf", userID, asOf)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserAsOf indicates an expected call of GetUserAsOf.
+func (mr *MockUserServiceMockRecorder) GetUserAsOf(userID, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserAsOf", reflect.TypeOf((*MockUserService)(nil).GetUserAsOf), userID, asOf)
+}
+
+// GetUserHistory mocks base method.
+func (m *MockUserService) GetUserHistory(userID string, query AuditQuery) (*AuditPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserHistory", userID, query)
+	ret0, _ := ret[0].(*AuditPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserHistory indicates an expected call of GetUserHistory.
+func (mr *MockUserServiceMockRecorder) GetUserHistory(userID, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockUserService)(nil).GetUserHistory), userID, query)
+}
+
+// ListAuditEntries mocks base method.
+func (m *MockUserService) ListAuditEntries(query AuditQuery) (*AuditPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEntries", query)
+	ret0, _ := ret[0].(*AuditPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEntries indicates an expected call of ListAuditEntries.
+func (mr *MockUserServiceMockRecorder) ListAuditEntries(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEntries", reflect.TypeOf((*MockUserService)(nil).ListAuditEntries), query)
+}
+
+// ListUsers mocks base method.
+func (m *MockUserService) ListUsers(query ListQuery) (*UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", query)
+	ret0, _ := ret[0].(*UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUserServiceMockRecorder) ListUsers(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserService)(nil).ListUsers), query)
+}
+
+// PatchUser mocks base method.
+func (m *MockUserService) PatchUser(actor Actor, userID string, patch UserPatch, precondition Precondition) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PatchUser", actor, userID, patch, precondition)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PatchUser indicates an expected call of PatchUser.
+func (mr *MockUserServiceMockRecorder) PatchUser(actor, userID, patch, precondition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PatchUser", reflect.TypeOf((*MockUserService)(nil).PatchUser), actor, userID, patch, precondition)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockUserService) PurgeDeletedUsers() (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", userID, user)
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockUserServiceMockRecorder) PurgeDeletedUsers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockUserService)(nil).PurgeDeletedUsers))
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserService) RestoreUser(actor Actor, userID string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", actor, userID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserServiceMockRecorder) RestoreUser(actor, userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserService)(nil).RestoreUser), actor, userID)
+}
+
+// RevertUser mocks base method.
+func (m *MockUserService) RevertUser(actor Actor, userID string, revision int64, precondition Precondition) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevertUser", actor, userID, revision, precondition)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevertUser indicates an expected call of RevertUser.
+func (mr *MockUserServiceMockRecorder) RevertUser(actor, userID, revision, precondition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevertUser", reflect.TypeOf((*MockUserService)(nil).RevertUser), actor, userID, revision, precondition)
+}
+
+// SetRoles mocks base method.
+func (m *MockUserService) SetRoles(actor Actor, userID string, roles []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRoles", actor, userID, roles)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// SetRoles indicates an expected call of SetRoles.
+func (mr *MockUserServiceMockRecorder) SetRoles(actor, userID, roles interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoles", reflect.TypeOf((*MockUserService)(nil).SetRoles), actor, userID, roles)
+}
+
+// UpdateUser mocks base method.
+func (m *MockUserService) UpdateUser(actor Actor, userID string, user User, precondition Precondition) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", actor, userID, user, precondition)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockUserServiceMockRecorder) UpdateUser(userID, user interface{}) *gomock.Call {
+func (mr *MockUserServiceMockRecorder) UpdateUser(actor, userID, user, precondition interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserService)(nil).UpdateUser), actor, userID, user, precondition)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockUserService) VerifyEmail(userID, token string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", userID, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockUserServiceMockRecorder) VerifyEmail(userID, token interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserService)(nil).UpdateUser), userID, user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockUserService)(nil).VerifyEmail), userID, token)
 }
@@ -1,32 +1,84 @@
 package users
 
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
 type UserID struct {
 	ID string
 }
 
 type User struct {
-	ID       string  `json:"id" bson:"_id,omitempty"`
-	Name     string  `json:"name"`
-	Age      string  `json:"age"`
-	Email    string  `json:"email"`
-	Password string  `json:"password,omitempty"`
-	Address  Address `json:"address"`
+	ID        string   `json:"id" bson:"_id,omitempty"`
+	Name      string   `json:"name"`
+	Age       string   `json:"age"`
+	BirthDate string   `json:"birthDate,omitempty" bson:"birthDate,omitempty"`
+	Email     string   `json:"email"`
+	Password  string   `json:"password,omitempty"`
+	Address   Address  `json:"address"`
+	Roles     []string `json:"roles,omitempty" bson:"roles,omitempty"`
+	// Set by the server when the user confirms the email, reset when the email changes
+	EmailVerified bool       `json:"emailVerified" bson:"emailVerified"`
+	VerifiedAt    *time.Time `json:"verifiedAt,omitempty" bson:"verifiedAt,omitempty"`
+	// Set when the user is soft deleted, the user is purged once DELETED_RETENTION is over
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	// Set by the server on creation and on every change, missing on users stored before they were recorded
+	CreatedAt *time.Time `json:"createdAt,omitempty" bson:"createdAt,omitempty" readonly:"true"`
+	CreatedBy string     `json:"createdBy,omitempty" bson:"createdBy,omitempty" readonly:"true"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty" readonly:"true"`
+	UpdatedBy string     `json:"updatedBy,omitempty" bson:"updatedBy,omitempty" readonly:"true"`
+	Version   int64      `json:"-" bson:"version"`
 }
 
-func (u *User) projection() Projection {
-	m := make(Projection, 0)
-	for k := range UserAccess {
+// Author of a change, recorded on the users it changes
+type Actor struct {
+	// ID of the authenticated principal, the user itself when changing its own data without authentication
+	ID string
+	// Request making the change, recorded on the audit log
+	RequestID string
+}
+
+// Returns the email as stored, emails are compared ignoring case
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type UserRoles struct {
+	Roles []string `json:"roles"`
+}
+
+// Returns the changes to fully replace a stored user, along with the email verification.
+// Empty fields are unset, as on a patch. Password is only replaced when informed.
+func (u *User) replacement() UserChanges {
+	changes := UserChanges{Set: Projection{}}
+	for _, k := range sortedUserFields() {
 		v := UserAccess[k](u)
-		if v != "" {
-			m = append(m, ProjectionsFields{Key: k, Value: v})
+		if v == "" {
+			if k != "password" {
+				changes.Unset = append(changes.Unset, k)
+			}
+			continue
 		}
+		changes.Set = append(changes.Set, ProjectionsFields{Key: k, Value: v})
 	}
-	return m
+	changes.Set = append(changes.Set, ProjectionsFields{Key: "emailVerified", Value: u.EmailVerified})
+	if u.VerifiedAt == nil {
+		changes.Unset = append(changes.Unset, "verifiedAt")
+	} else {
+		changes.Set = append(changes.Set, ProjectionsFields{Key: "verifiedAt", Value: u.VerifiedAt})
+	}
+	return changes
 }
 
+// Fields only changed by the server, a patch must keep them as they are
+var readOnlyFields = []string{"roles", "emailVerified", "verifiedAt", "createdAt", "createdBy", "updatedAt", "updatedBy"}
+
 var UserAccess = map[string]UserGetter{
 	"name":            func(v *User) string { return v.Name },
 	"age":             func(v *User) string { return v.Age },
+	"birthDate":       func(v *User) string { return v.BirthDate },
 	"email":           func(v *User) string { return v.Email },
 	"password":        func(v *User) string { return v.Password },
 	"address.street":  func(v *User) string { return v.Address.Street },
@@ -39,6 +91,32 @@ var UserAccess = map[string]UserGetter{
 
 type UserGetter func(v *User) string
 
+var userSetters = map[string]UserSetter{
+	"name":            func(v *User, s string) { v.Name = s },
+	"age":             func(v *User, s string) { v.Age = s },
+	"birthDate":       func(v *User, s string) { v.BirthDate = s },
+	"email":           func(v *User, s string) { v.Email = s },
+	"password":        func(v *User, s string) { v.Password = s },
+	"address.street":  func(v *User, s string) { v.Address.Street = s },
+	"address.number":  func(v *User, s string) { v.Address.Number = s },
+	"address.zip":     func(v *User, s string) { v.Address.ZIP = s },
+	"address.city":    func(v *User, s string) { v.Address.City = s },
+	"address.state":   func(v *User, s string) { v.Address.State = s },
+	"address.country": func(v *User, s string) { v.Address.Country = s },
+}
+
+type UserSetter func(v *User, value string)
+
+// Returns UserAccess fields in a stable order
+func sortedUserFields() []string {
+	fields := make([]string, 0, len(UserAccess))
+	for k := range UserAccess {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
 type Address struct {
 	Street  string `json:"street"`
 	Number  string `json:"number"`
@@ -0,0 +1,57 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserReplacement(t *testing.T) {
+
+	verifiedAt := time.Date(2023, time.May, 20, 12, 0, 0, 0, time.UTC)
+	address := Address{Street: "Street", Number: "1", ZIP: "01000-000", City: "City", State: "SP", Country: "BR"}
+
+	tests := []struct {
+		name          string
+		inputParam    User
+		expectedSet   map[string]interface{}
+		expectedUnset []string
+	}{
+		{
+			name:       "all fields informed",
+			inputParam: User{Name: "Test", Age: "33", BirthDate: "1990-05-20", Email: "test@test.com", Password: "Secret#123", Address: address, EmailVerified: true, VerifiedAt: &verifiedAt},
+			expectedSet: map[string]interface{}{
+				"name": "Test", "age": "33", "birthDate": "1990-05-20", "email": "test@test.com", "password": "Secret#123",
+				"address.street": "Street", "address.number": "1", "address.zip": "01000-000",
+				"address.city": "City", "address.state": "SP", "address.country": "BR",
+				"emailVerified": true, "verifiedAt": &verifiedAt,
+			},
+			expectedUnset: nil,
+		},
+		{
+			name:       "empty fields are unset and password is kept",
+			inputParam: User{Name: "Test", Email: "test@test.com", Address: address},
+			expectedSet: map[string]interface{}{
+				"name": "Test", "email": "test@test.com",
+				"address.street": "Street", "address.number": "1", "address.zip": "01000-000",
+				"address.city": "City", "address.state": "SP", "address.country": "BR",
+				"emailVerified": false,
+			},
+			expectedUnset: []string{"age", "birthDate", "verifiedAt"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			changes := tc.inputParam.replacement()
+
+			set := map[string]interface{}(changes.Set.Map())
+			if !reflect.DeepEqual(set, tc.expectedSet) {
+				t.Errorf("Expecting set %v , but returns %v", tc.expectedSet, set)
+			}
+			if !reflect.DeepEqual(changes.Unset, tc.expectedUnset) {
+				t.Errorf("Expecting unset %v , but returns %v", tc.expectedUnset, changes.Unset)
+			}
+		})
+	}
+}
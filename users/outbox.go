@@ -0,0 +1,108 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"userapi/config"
+)
+
+// Time a relay has to publish an event claimed before other relays claim it again,
+// failed events are published again once it is over
+const relayLease time.Duration = 30 * time.Second
+
+// Event of a user change written to the outbox along with the change, published by the relay
+type OutboxEvent struct {
+	ID            string     `json:"id" bson:"_id,omitempty"`
+	Event         UserEvent  `json:"event" bson:"event"`
+	CreatedAt     time.Time  `json:"createdAt" bson:"createdAt"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	PublishedAt   *time.Time `json:"publishedAt,omitempty" bson:"publishedAt"`
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	LastError     string     `json:"lastError,omitempty" bson:"lastError,omitempty"`
+}
+
+type EventRelay interface {
+	/*
+		Method to publish the events waiting on the outbox, oldest first, and purge
+		events published longer than OUTBOX_RETENTION
+
+		Returns the events published.
+	*/
+	RelayEvents() (int, error)
+}
+
+type eventRelay struct {
+	outbox    OutboxRepository
+	publisher Publisher
+	config    config.Config
+}
+
+func NewEventRelay(outbox OutboxRepository, publisher Publisher, config config.Config) EventRelay {
+	return &eventRelay{
+		outbox:    outbox,
+		publisher: publisher,
+		config:    config,
+	}
+}
+
+/*
+Events are marked published only once the publisher took them, an event claimed by
+a relay stopping before is published again when its lease is over. Events are
+published at least once, and in order only while a single relay publishes them.
+*/
+func (r *eventRelay) RelayEvents() (int, error) {
+	published := 0
+	for {
+		event, err := r.outbox.ClaimEvent(time.Now(), relayLease)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on ClaimEvent : %v", err))
+			return published, err
+		}
+		if event == nil {
+			break
+		}
+
+		if err := r.publisher.Publish(event.Event); err != nil {
+			fmt.Println(fmt.Errorf("Error on Publish : %v", err))
+			if err := r.outbox.FailEvent(event.ID, err.Error()); err != nil {
+				fmt.Println(fmt.Errorf("Error on FailEvent : %v", err))
+			}
+			continue
+		}
+
+		if err := r.outbox.MarkPublished(event.ID, time.Now()); err != nil {
+			fmt.Println(fmt.Errorf("Error on MarkPublished : %v", err))
+			continue
+		}
+		published++
+	}
+
+	if _, err := r.outbox.PurgePublished(time.Now().Add(-r.config.OutboxRetention)); err != nil {
+		fmt.Println(fmt.Errorf("Error on PurgePublished : %v", err))
+		return published, err
+	}
+	return published, nil
+}
+
+/*
+Publishes the events written to the outbox right away and then on every interval,
+until ctx is done.
+
+Every instance of the API runs a relay, relays claim events apart so an event is
+published by one of them at a time.
+*/
+func RunRelay(ctx context.Context, relay EventRelay, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		relay.RelayEvents()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
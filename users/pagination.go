@@ -0,0 +1,64 @@
+package users
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var errInvalidCursor = errors.New("invalid page token")
+
+// Parameters to list a page of users
+type ListQuery struct {
+	Next    string
+	Limit   int
+	Filters []FilterCondition
+	Sort    []SortField
+	// Lists soft deleted users as well
+	IncludeDeleted bool
+}
+
+// Page of users returned by user listing
+type UserPage struct {
+	Users []User `json:"users"`
+	Next  string `json:"next,omitempty"`
+}
+
+// Content of the opaque next page token, values missing on the user are null
+type pageCursor struct {
+	ID     string    `json:"id"`
+	Sort   string    `json:"sort,omitempty"`
+	Values []*string `json:"values,omitempty"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	bytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func decodeCursor(token string) (*pageCursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(bytes, &cursor); err != nil || cursor.ID == "" {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// Returns the cursor pointing after user on the given sort order
+func newCursor(user User, sortFields []SortField) pageCursor {
+	cursor := pageCursor{ID: user.ID, Sort: sortSpec(sortFields)}
+	for _, s := range sortFields {
+		// Sorted fields are either required or left out when empty
+		var value *string
+		if v := UserAccess[s.Field](&user); v != "" {
+			value = &v
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+	return cursor
+}
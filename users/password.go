@@ -0,0 +1,92 @@
+package users
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"userapi/config"
+)
+
+// Password rules enforced on create, update and reset
+type PasswordPolicy struct {
+	// Minimum length in characters
+	MinLength int
+	// Maximum length in bytes, never above the bcrypt limit
+	MaxLength int
+	// Character classes required among lower case, upper case, digits and symbols
+	Classes int
+	banned  map[string]bool
+}
+
+// Returns the PasswordPolicy of config, with the banned passwords of PASSWORD_BANNED_FILE.
+// When the file can not be read, the policy is returned without banned passwords along with the error.
+func NewPasswordPolicy(config config.Config) (PasswordPolicy, error) {
+	policy := PasswordPolicy{
+		MinLength: config.PasswordMinLength,
+		MaxLength: config.PasswordMaxLength,
+		Classes:   config.PasswordClasses,
+		banned:    make(map[string]bool),
+	}
+
+	passwords, err := config.LoadBannedPasswords()
+	if err != nil {
+		return policy, err
+	}
+	for _, password := range passwords {
+		policy.banned[password] = true
+	}
+	return policy, nil
+}
+
+// Returns the first rule the password of the user breaks, nil when it follows the policy
+func (p PasswordPolicy) Validate(password string, user User) *FieldError {
+	if password == "" {
+		return PASSWORD_REQUIRED
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return PASSWORD_TOO_SHORT
+	}
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > config.BCRYPT_MAX_PASSWORD_BYTES {
+		maxLength = config.BCRYPT_MAX_PASSWORD_BYTES
+	}
+	if len(password) > maxLength {
+		return PASSWORD_TOO_LONG
+	}
+
+	if passwordClasses(password) < p.Classes {
+		return PASSWORD_TOO_WEAK
+	}
+
+	lower := strings.ToLower(password)
+	email := strings.ToLower(user.Email)
+	local, _, _ := strings.Cut(email, "@")
+	if lower == email || lower == local || lower == strings.ToLower(strings.TrimSpace(user.Name)) {
+		return PASSWORD_MATCHES_USER
+	}
+
+	if p.banned[lower] {
+		return PASSWORD_BANNED
+	}
+	return nil
+}
+
+// Returns how many of lower case, upper case, digits and symbols the password has
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
@@ -0,0 +1,67 @@
+package users
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"userapi/config"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+
+	bannedFile := filepath.Join(t.TempDir(), "banned.txt")
+	if err := os.WriteFile(bannedFile, []byte("# common passwords\nPassw0rd!\n\nletmein\n"), 0600); err != nil {
+		t.Fatalf("Error writing banned file : %v", err)
+	}
+
+	policy, err := NewPasswordPolicy(config.Config{
+		PasswordMinLength:  8,
+		PasswordMaxLength:  config.BCRYPT_MAX_PASSWORD_BYTES,
+		PasswordClasses:    3,
+		PasswordBannedFile: bannedFile,
+	})
+	if err != nil {
+		t.Fatalf("Error creating password policy : %v", err)
+	}
+
+	user := User{Name: "Maria Silva", Email: "Maria.Silva1@test.com"}
+
+	tests := []struct {
+		name          string
+		inputParam    string
+		expectedError *FieldError
+	}{
+		{name: "valid password", inputParam: "Correct-Horse-9", expectedError: nil},
+		{name: "unicode password", inputParam: "Çorreção-cavalo-9", expectedError: nil},
+		{name: "empty password", inputParam: "", expectedError: PASSWORD_REQUIRED},
+		{name: "too short", inputParam: "Ab1!", expectedError: PASSWORD_TOO_SHORT},
+		{name: "too long", inputParam: "Ab1!" + strings.Repeat("a", 69), expectedError: PASSWORD_TOO_LONG},
+		{name: "too long in bytes", inputParam: "Ab1!" + strings.Repeat("é", 35), expectedError: PASSWORD_TOO_LONG},
+		{name: "too few classes", inputParam: "correcthorse9", expectedError: PASSWORD_TOO_WEAK},
+		{name: "equals email", inputParam: "maria.silva1@TEST.com", expectedError: PASSWORD_MATCHES_USER},
+		{name: "equals email user", inputParam: "Maria.Silva1", expectedError: PASSWORD_MATCHES_USER},
+		{name: "equals name", inputParam: "Maria Silva", expectedError: PASSWORD_MATCHES_USER},
+		{name: "differs from name", inputParam: "Maria Silva1", expectedError: nil},
+		{name: "banned password", inputParam: "PASSW0RD!", expectedError: PASSWORD_BANNED},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			validation := policy.Validate(tc.inputParam, user)
+			if validation != tc.expectedError {
+				t.Errorf("Expecting validation %v , but returns %v", tc.expectedError, validation)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyBannedFileMissing(t *testing.T) {
+	policy, err := NewPasswordPolicy(config.Config{PasswordMinLength: 8, PasswordBannedFile: filepath.Join(t.TempDir(), "missing.txt")})
+	if err == nil {
+		t.Errorf("Expecting error for missing banned file")
+	}
+	if policy.MinLength != 8 {
+		t.Errorf("Expecting policy rules kept , but returns %v", policy)
+	}
+}
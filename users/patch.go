@@ -0,0 +1,197 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const MERGE_PATCH_CONTENT_TYPE string = "application/merge-patch+json"
+const JSON_PATCH_CONTENT_TYPE string = "application/json-patch+json"
+
+var errInvalidPatch = errors.New("invalid patch")
+
+// Patch document sent to partially update a user
+type UserPatch struct {
+	ContentType string
+	Document    []byte
+}
+
+// Changes to apply on a stored user, fields in Unset are removed from the document
+type UserChanges struct {
+	Set   Projection
+	Unset []string
+}
+
+func (c UserChanges) empty() bool {
+	return len(c.Set) == 0 && len(c.Unset) == 0
+}
+
+// Returns the update applying the changes, with $set and $unset when there are fields to set or unset
+func (c UserChanges) toBSON() bson.M {
+	fields := bson.M{}
+	if len(c.Set) > 0 {
+		fields["$set"] = c.Set.toBSON()
+	}
+	if len(c.Unset) > 0 {
+		unset := bson.M{}
+		for _, field := range c.Unset {
+			unset[field] = ""
+		}
+		fields["$unset"] = unset
+	}
+	return fields
+}
+
+// Returns the fields set or unset, along with the fields validated against them
+func (c UserChanges) fields() map[string]bool {
+	fields := map[string]bool{}
+	for _, field := range c.Set {
+		fields[field.Key] = true
+	}
+	for _, field := range c.Unset {
+		fields[field] = true
+	}
+	// State and ZIP are validated against the country
+	if fields["address.country"] {
+		fields["address.state"] = true
+		fields["address.zip"] = true
+	}
+	return fields
+}
+
+// Applies the patch document on the JSON representation of the user,
+// as a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+func (p UserPatch) apply(document []byte) ([]byte, error) {
+	switch p.ContentType {
+	case MERGE_PATCH_CONTENT_TYPE:
+		return jsonpatch.MergePatch(document, p.Document)
+	case JSON_PATCH_CONTENT_TYPE:
+		patch, err := jsonpatch.DecodePatch(p.Document)
+		if err != nil {
+			return nil, err
+		}
+		return patch.Apply(document)
+	}
+	return nil, errInvalidPatch
+}
+
+/*
+Returns the user on the patched document.
+
+Only fields of UserAccess can be changed; a field missing or null on the
+patched document is unset and the user id can not be changed.
+*/
+func userFromPatch(user User, patched []byte) (*User, error) {
+	var document map[string]interface{}
+	if err := json.Unmarshal(patched, &document); err != nil {
+		return nil, errInvalidPatch
+	}
+
+	if err := checkPatchedFields(document, ""); err != nil {
+		return nil, err
+	}
+	if id, _ := document["id"].(string); id != user.ID {
+		return nil, errInvalidPatch
+	}
+	if !readOnlyKept(user, document) {
+		return nil, errInvalidPatch
+	}
+
+	result := User{ID: user.ID}
+	for _, field := range sortedUserFields() {
+		value, exists := lookupField(document, field)
+		if !exists {
+			continue
+		}
+
+		str, ok := value.(string)
+		if !ok {
+			return nil, errInvalidPatch
+		}
+		userSetters[field](&result, str)
+	}
+	return &result, nil
+}
+
+// Returns the changes needed to turn user into the patched user, emptied fields are unset
+func diffUser(user User, patched User) *UserChanges {
+	changes := &UserChanges{Set: Projection{}}
+	for _, field := range sortedUserFields() {
+		current := UserAccess[field](&user)
+		value := UserAccess[field](&patched)
+		if value == "" {
+			if current != "" {
+				changes.Unset = append(changes.Unset, field)
+			}
+			continue
+		}
+		if value != current {
+			changes.Set = append(changes.Set, ProjectionsFields{Key: field, Value: value})
+		}
+	}
+	return changes
+}
+
+// Rejects fields out of UserAccess on the patched document
+func checkPatchedFields(document map[string]interface{}, prefix string) error {
+	for key, value := range document {
+		field := prefix + key
+		if field == "id" || isReadOnly(field) {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := checkPatchedFields(nested, field+"."); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := UserAccess[field]; !ok && value != nil {
+			return errInvalidPatch
+		}
+	}
+	return nil
+}
+
+func isReadOnly(field string) bool {
+	for _, f := range readOnlyFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Tells if the patched document keeps the read only fields of the user
+func readOnlyKept(user User, document map[string]interface{}) bool {
+	original, _ := json.Marshal(user)
+	var current map[string]interface{}
+	if err := json.Unmarshal(original, &current); err != nil {
+		return false
+	}
+	for _, field := range readOnlyFields {
+		if !reflect.DeepEqual(current[field], document[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the value of a dotted field, null values are taken as missing
+func lookupField(document map[string]interface{}, field string) (interface{}, bool) {
+	var value interface{} = document
+	for _, key := range strings.Split(field, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok || value == nil {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,125 @@
+package users
+
+import (
+	"userapi/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ROLE_ADMIN string = "admin"
+const ROLE_SUPPORT string = "support"
+const ROLE_SELF string = "self"
+
+const PERMISSION_READ string = "users:read"
+const PERMISSION_WRITE string = "users:write"
+const PERMISSION_DELETE string = "users:delete"
+const PERMISSION_ROLES string = "users:roles"
+const PERMISSION_API_KEYS string = "apikeys:manage"
+const PERMISSION_MFA_RESET string = "users:mfa"
+const PERMISSION_UNLOCK string = "users:unlock"
+const PERMISSION_AUDIT string = "users:audit"
+const PERMISSION_WEBHOOKS string = "webhooks:manage"
+
+// Permissions granted by each role over any user. Support only reads, writes would
+// let it set the password or email of an admin and take the account over.
+var RolePermissions = map[string][]string{
+	ROLE_ADMIN:   {PERMISSION_READ, PERMISSION_WRITE, PERMISSION_DELETE, PERMISSION_ROLES, PERMISSION_API_KEYS, PERMISSION_MFA_RESET, PERMISSION_UNLOCK, PERMISSION_AUDIT, PERMISSION_WEBHOOKS},
+	ROLE_SUPPORT: {PERMISSION_READ},
+	ROLE_SELF:    {},
+}
+
+// Permissions granted by self role over the user's own record
+var SelfPermissions = []string{PERMISSION_READ, PERMISSION_WRITE}
+
+func hasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Tells if the principal holds the permission over any user, from its roles or API key scopes
+func IsGranted(principal identity.Principal, permission string) bool {
+	if hasPermission(principal.Scopes, permission) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if hasPermission(RolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// Tells if the principal holds the permission over the user with the given ID
+func IsGrantedOn(principal identity.Principal, permission string, userID string) bool {
+	if IsGranted(principal, permission) {
+		return true
+	}
+	for _, role := range principal.Roles {
+		if role == ROLE_SELF && principal.ID == userID && hasPermission(SelfPermissions, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func validRoles(roles []string) bool {
+	for _, role := range roles {
+		if _, ok := RolePermissions[role]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware denying the request unless the authenticated principal holds the permission.
+// When self is true, the permission may also be granted over the user of the `id` path parameter.
+func Authorize(permission string, self bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := identity.GetPrincipal(c)
+		if ok && IsGranted(principal, permission) {
+			return
+		}
+		if ok && self && IsGrantedOn(principal, permission, c.Param("id")) {
+			return
+		}
+		c.AbortWithStatusJSON(403, ACCESS_DENIED)
+	}
+}
+
+// Middleware denying the request unless the user of the `id` path parameter is the one logged in
+// with a bearer token, or the authenticated principal holds one of the permissions over any user.
+func AuthorizeSelf(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := identity.GetPrincipal(c)
+		if ok && principal.Method == identity.BEARER_METHOD && principal.ID == c.Param("id") {
+			return
+		}
+		for _, permission := range permissions {
+			if ok && IsGranted(principal, permission) {
+				return
+			}
+		}
+		c.AbortWithStatusJSON(403, ACCESS_DENIED)
+	}
+}
+
+// Middleware denying a permission listed on REQUIRE_VERIFIED_EMAIL to users with unverified email.
+// It only applies to users granted the permission over their own user, not to roles granted over any user.
+func RequireVerifiedEmail(permission string, required []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !RequiresVerifiedEmail(required, permission) {
+			return
+		}
+		principal, ok := identity.GetPrincipal(c)
+		if !ok || principal.Method != identity.BEARER_METHOD || IsGranted(principal, permission) {
+			return
+		}
+		if !principal.EmailVerified {
+			c.AbortWithStatusJSON(403, EMAIL_NOT_VERIFIED)
+		}
+	}
+}
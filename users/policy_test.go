@@ -0,0 +1,272 @@
+package users
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"userapi/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthorize(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	const otherID string = "64260e1da4c0c814bda5734b"
+
+	tests := []struct {
+		name           string
+		principal      *identity.Principal
+		permission     string
+		self           bool
+		inputParam     string
+		expectedStatus int
+	}{
+		{
+			name:           "admin deletes any user",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_ADMIN}},
+			permission:     PERMISSION_DELETE,
+			inputParam:     otherID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "support reads any user",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SUPPORT}},
+			permission:     PERMISSION_READ,
+			self:           true,
+			inputParam:     otherID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "support can not delete",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SUPPORT}},
+			permission:     PERMISSION_DELETE,
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "support can not change an admin",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SUPPORT}},
+			permission:     PERMISSION_WRITE,
+			self:           true,
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "self reads own user",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SELF}},
+			permission:     PERMISSION_READ,
+			self:           true,
+			inputParam:     userID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "self can not read other user",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SELF}},
+			permission:     PERMISSION_READ,
+			self:           true,
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "self can not delete own user",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SELF}},
+			permission:     PERMISSION_DELETE,
+			self:           true,
+			inputParam:     userID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "self only on self routes",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SELF}},
+			permission:     PERMISSION_WRITE,
+			self:           false,
+			inputParam:     userID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "api key scope granted",
+			principal:      &identity.Principal{ID: "key-1", Method: identity.API_KEY_METHOD, Scopes: []string{PERMISSION_READ}},
+			permission:     PERMISSION_READ,
+			inputParam:     otherID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "api key scope missing",
+			principal:      &identity.Principal{ID: "key-1", Method: identity.API_KEY_METHOD, Scopes: []string{PERMISSION_READ}},
+			permission:     PERMISSION_DELETE,
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "only admin manages api keys",
+			principal:      &identity.Principal{ID: userID, Roles: []string{ROLE_SUPPORT}},
+			permission:     PERMISSION_API_KEYS,
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "no principal",
+			principal:      nil,
+			permission:     PERMISSION_READ,
+			inputParam:     userID,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			r := gin.Default()
+			r.GET("/api/v1/users/:id", func(c *gin.Context) {
+				if tc.principal != nil {
+					identity.SetPrincipal(c, *tc.principal)
+				}
+			}, Authorize(tc.permission, tc.self), func(c *gin.Context) {
+				c.JSON(200, USER_UPDATED)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/users/%s", tc.inputParam), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if tc.expectedStatus == http.StatusForbidden && w.Body.String() != `{"message":"Access Denied","code":"ACCESS_DENIED"}` {
+				t.Errorf("Expecting access denied , but returns %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireVerifiedEmail(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name           string
+		principal      identity.Principal
+		required       []string
+		expectedStatus int
+	}{
+		{
+			name:           "verified user",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_SELF}, EmailVerified: true},
+			required:       []string{PERMISSION_WRITE},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unverified user",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_SELF}},
+			required:       []string{PERMISSION_WRITE},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "permission not required verified",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_SELF}},
+			required:       []string{VERIFIED_FOR_LOGIN},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "role granted over any user",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_ADMIN}},
+			required:       []string{PERMISSION_WRITE},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			r := gin.Default()
+			r.PATCH("/api/v1/users/:id", func(c *gin.Context) {
+				identity.SetPrincipal(c, tc.principal)
+			}, RequireVerifiedEmail(PERMISSION_WRITE, tc.required), func(c *gin.Context) {
+				c.JSON(200, USER_UPDATED)
+			})
+
+			req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/api/v1/users/%s", userID), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSelf(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	const otherID string = "64260e1da4c0c814bda5734b"
+
+	tests := []struct {
+		name           string
+		principal      identity.Principal
+		permissions    []string
+		inputParam     string
+		expectedStatus int
+	}{
+		{
+			name:           "user on own user",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_SELF}},
+			inputParam:     userID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "user on other user",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_SELF}},
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin without permission listed",
+			principal:      identity.Principal{ID: userID, Method: identity.BEARER_METHOD, Roles: []string{ROLE_ADMIN}},
+			inputParam:     otherID,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin with permission listed",
+			principal:      identity.Principal{ID: "apiuser", Method: identity.BASIC_METHOD, Roles: []string{ROLE_ADMIN}},
+			permissions:    []string{PERMISSION_MFA_RESET},
+			inputParam:     otherID,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "basic auth user with same id",
+			principal:      identity.Principal{ID: userID, Method: identity.BASIC_METHOD, Roles: []string{ROLE_SELF}},
+			inputParam:     userID,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			r := gin.Default()
+			r.POST("/api/v1/users/:id/mfa", func(c *gin.Context) {
+				identity.SetPrincipal(c, tc.principal)
+			}, AuthorizeSelf(tc.permissions...), func(c *gin.Context) {
+				c.JSON(200, USER_UPDATED)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/users/%s/mfa", tc.inputParam), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Actor recorded on the audit log for the users purged
+const PURGER string = "purger"
+
+/*
+Purges users soft deleted longer than DELETED_RETENTION right away and then on
+every interval, until ctx is done.
+
+Every instance of the API runs a purger, purging the same users twice is harmless.
+*/
+func RunPurger(ctx context.Context, service UserService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := service.PurgeDeletedUsers()
+		if err == nil && purged > 0 {
+			fmt.Printf("Purged %d deleted users \n", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
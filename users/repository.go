@@ -3,6 +3,8 @@ package users
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,19 +13,81 @@ import (
 )
 
 const userCollection string = "users"
+const verificationCollection string = "email_verifications"
+const auditCollection string = "user_audit"
+const outboxCollection string = "user_outbox"
 const INVALID_OBJECT_ID string = "INVALID_OBJECT_ID"
+const DOCUMENT_NOT_FOUND string = "DOCUMENT_NOT_FOUND"
+const VERSION_MISMATCH string = "VERSION_MISMATCH"
+const DUPLICATE_EMAIL string = "DUPLICATE_EMAIL"
+const NOT_DELETED string = "NOT_DELETED"
 
+// Condition on deletedAt leaving soft deleted users out, they are only reached by RestoreUser
+// and PurgeUsers or when listing users including deleted ones
+var notDeleted bson.M = bson.M{"$eq": nil}
+
+// Unique index of user emails, ignoring case
+const EMAIL_INDEX string = "email_unique"
+
+// Collation comparing emails ignoring case, the one of EMAIL_INDEX
+var EmailCollation *options.Collation = &options.Collation{Locale: "en", Strength: 2}
+
+// Writes record when and by which actor the user was changed on updatedAt and updatedBy,
+// along with createdAt and createdBy on insert. Every write appends the change to the audit
+// log and its event to the outbox, in the same transaction when the deployment supports them,
+// and returns that event, telling the user ID and new version.
 type UserRepository interface {
-	InsertUser(user User) (string, error)
+	InsertUser(actor Actor, user User) (*UserEvent, error)
 	FindUserByEmail(email string, projection Projection) (*User, error)
 	FindUserByID(ID string, projection Projection) (*User, error)
-	UpdateUser(userID string, user User) error
-	DeleteUser(userID string) error
+	ListUsers(criteria ListCriteria, limit int, projection Projection) ([]User, error)
+	UpdateUser(actor Actor, userID string, user User, precondition Precondition) (*UserEvent, error)
+	PatchUser(actor Actor, userID string, changes UserChanges, precondition Precondition) (*UserEvent, error)
+	// Soft deletes the user, setting deletedAt
+	DeleteUser(actor Actor, userID string, precondition Precondition) (*UserEvent, error)
+	// Clears deletedAt of a soft deleted user
+	RestoreUser(actor Actor, userID string) (*UserEvent, error)
+	// Permanently removes users soft deleted before the given time, returns the users removed
+	PurgeUsers(actor Actor, deletedBefore time.Time) (int64, error)
+	// Marks the email as verified if it is still the user email
+	VerifyEmail(actor Actor, userID string, email string, verifiedAt time.Time) (*UserEvent, error)
+}
+
+type VerificationRepository interface {
+	InsertVerification(verification EmailVerification) error
+	// Marks the user token as used if it is unused and not expired, returns nil when it is not
+	ConsumeVerification(userID string, tokenHash string) (*EmailVerification, error)
+	EnsureIndexes() error
+}
+
+// Audit log of user changes, entries are appended by UserRepository writes and never changed nor removed
+type AuditRepository interface {
+	// Returns entries matching the criteria, newest first
+	ListEntries(criteria AuditCriteria, limit int) ([]AuditEntry, error)
+	EnsureIndexes() error
+}
+
+// Events of user changes waiting to be published, kept for a while once published
+type OutboxRepository interface {
+	// Returns the oldest event due at now and not published, leaving it to other relays for lease, nil when none
+	ClaimEvent(now time.Time, lease time.Duration) (*OutboxEvent, error)
+	MarkPublished(ID string, publishedAt time.Time) error
+	// Records a failed publication, the event is published again once its lease is over
+	FailEvent(ID string, lastError string) error
+	// Removes events published before the given time, returns the events removed
+	PurgePublished(publishedBefore time.Time) (int64, error)
+	// Publishes the events written from now on, until ctx is done or the change stream fails
+	WatchEvents(ctx context.Context, publisher Publisher) error
+	EnsureIndexes() error
 }
 
 type userRepository struct {
 	client   *mongo.Client
 	database string
+	// Whether the deployment supports transactions, checked until a check succeeds
+	checked       sync.Mutex
+	known         bool
+	transactional bool
 }
 
 func NewUserRepository(client *mongo.Client, database string) UserRepository {
@@ -33,16 +97,33 @@ func NewUserRepository(client *mongo.Client, database string) UserRepository {
 	}
 }
 
-func (repo *userRepository) InsertUser(user User) (string, error) {
+func (repo *userRepository) InsertUser(actor Actor, user User) (*UserEvent, error) {
+	now := time.Now().UTC()
 	user.ID = ""
+	user.Version = 1
+	user.CreatedAt, user.CreatedBy = &now, actor.ID
+	user.UpdatedAt, user.UpdatedBy = &now, actor.ID
 	coll := repo.client.Database(repo.database).Collection(userCollection)
-	result, err := coll.InsertOne(context.Background(), user)
+	var event *UserEvent
+	err := repo.transact(func(ctx context.Context) error {
+		// Transactions retried insert the user again, under a new ID
+		inserted := user
+		result, err := coll.InsertOne(ctx, inserted)
+		if err != nil {
+			return err
+		}
+		inserted.ID = result.InsertedID.(primitive.ObjectID).Hex()
+		event, err = repo.writeChange(ctx, AUDIT_CREATE, actor, nil, &inserted)
+		return err
+	})
 	if err != nil {
-		return "", err
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf(DUPLICATE_EMAIL)
+		}
+		return nil, err
 	}
-	var objID primitive.ObjectID = result.InsertedID.(primitive.ObjectID)
 
-	return objID.Hex(), nil
+	return event, nil
 }
 
 func (repo *userRepository) FindUserByID(ID string, projection Projection) (*User, error) {
@@ -52,7 +133,7 @@ func (repo *userRepository) FindUserByID(ID string, projection Projection) (*Use
 		return nil, fmt.Errorf(INVALID_OBJECT_ID)
 	}
 
-	filter := bson.M{"_id": bson.M{"$eq": objID}}
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "deletedAt": notDeleted}
 	opts := options.FindOne().SetProjection(projection.toBSON())
 
 	var user User
@@ -68,8 +149,9 @@ func (repo *userRepository) FindUserByID(ID string, projection Projection) (*Use
 
 func (repo *userRepository) FindUserByEmail(email string, projection Projection) (*User, error) {
 	coll := repo.client.Database(repo.database).Collection(userCollection)
-	filter := bson.D{{Key: "email", Value: email}}
-	opts := options.FindOne().SetProjection(projection.toBSON())
+	filter := bson.D{{Key: "email", Value: email}, {Key: "deletedAt", Value: notDeleted}}
+	// Matches emails stored before normalization, using EMAIL_INDEX
+	opts := options.FindOne().SetProjection(projection.toBSON()).SetCollation(EmailCollation)
 
 	var user User
 	err := coll.FindOne(context.Background(), filter, opts).Decode(&user)
@@ -82,39 +164,561 @@ func (repo *userRepository) FindUserByEmail(email string, projection Projection)
 	return &user, err
 }
 
-func (repo *userRepository) UpdateUser(userID string, user User) error {
-	user.ID = ""
+func (repo *userRepository) ListUsers(criteria ListCriteria, limit int, projection Projection) ([]User, error) {
 	coll := repo.client.Database(repo.database).Collection(userCollection)
+
+	filter, err := criteria.filterBSON()
+	if err != nil {
+		return nil, err
+	}
+	if !criteria.IncludeDeleted {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"deletedAt": notDeleted}}}
+	}
+
+	opts := options.Find().
+		SetProjection(projection.toBSON()).
+		SetSort(criteria.sortBSON()).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]User, 0, limit)
+	if err := cursor.All(context.Background(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (repo *userRepository) UpdateUser(actor Actor, userID string, user User, precondition Precondition) (*UserEvent, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return fmt.Errorf(INVALID_OBJECT_ID)
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
 	}
 
-	filter := bson.M{"_id": bson.M{"$eq": objID}}
-	fields := bson.M{"$set": user.projection().toBSON()}
+	return repo.updateVersioned(actor, objID, user.replacement().toBSON(), precondition, AUDIT_UPDATE)
+}
 
-	_, err = coll.UpdateOne(context.Background(), filter, fields)
+func (repo *userRepository) PatchUser(actor Actor, userID string, changes UserChanges, precondition Precondition) (*UserEvent, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	return repo.updateVersioned(actor, objID, changes.toBSON(), precondition, AUDIT_UPDATE)
+}
+
+// Applies fields on user incrementing its version, writes the change as action and returns its event
+func (repo *userRepository) updateVersioned(actor Actor, objID primitive.ObjectID, fields bson.M, precondition Precondition, action string) (*UserEvent, error) {
+	stamp(fields, actor)
+	fields["$inc"] = bson.M{"version": 1}
+
+	var event *UserEvent
+	err := repo.transact(func(ctx context.Context) (err error) {
+		event, err = repo.updateChanged(ctx, action, actor, precondition.filter(objID), fields)
 		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repo.unmatched(objID)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf(DUPLICATE_EMAIL)
+		}
+		return nil, err
 	}
-	return nil
+	return event, nil
+}
+
+func (repo *userRepository) DeleteUser(actor Actor, userID string, precondition Precondition) (*UserEvent, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	fields := bson.M{"$set": bson.M{"deletedAt": time.Now().UTC()}}
+	return repo.updateVersioned(actor, objID, fields, precondition, AUDIT_DELETE)
 }
 
-func (repo *userRepository) DeleteUser(userID string) error {
+func (repo *userRepository) RestoreUser(actor Actor, userID string) (*UserEvent, error) {
 	coll := repo.client.Database(repo.database).Collection(userCollection)
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return fmt.Errorf(INVALID_OBJECT_ID)
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "deletedAt": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}, "$inc": bson.M{"version": 1}}
+	stamp(update, actor)
+
+	var event *UserEvent
+	err = repo.transact(func(ctx context.Context) (err error) {
+		event, err = repo.updateChanged(ctx, AUDIT_RESTORE, actor, filter, update)
+		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			count, err := coll.CountDocuments(context.Background(), bson.M{"_id": bson.M{"$eq": objID}})
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				return nil, fmt.Errorf(DOCUMENT_NOT_FOUND)
+			}
+			return nil, fmt.Errorf(NOT_DELETED)
+		}
+		// Another user took the email while the user was deleted
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf(DUPLICATE_EMAIL)
+		}
+		return nil, err
 	}
+	return event, nil
+}
+
+// Users are removed one by one, each along with its purge on the audit log
+func (repo *userRepository) PurgeUsers(actor Actor, deletedBefore time.Time) (int64, error) {
+	coll := repo.client.Database(repo.database).Collection(userCollection)
+	filter := bson.M{"deletedAt": bson.M{"$lt": deletedBefore}}
+	opts := options.Find().SetProjection(bson.M{"_id": 1, "version": 1})
 
-	filter := bson.M{"_id": bson.M{"$eq": objID}}
-	_, err = coll.DeleteOne(context.Background(), filter)
+	cursor, err := coll.Find(context.Background(), filter, opts)
 	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.Background())
+
+	var purged int64
+	for cursor.Next(context.Background()) {
+		var user User
+		if err := cursor.Decode(&user); err != nil {
+			return purged, err
+		}
+		objID, err := primitive.ObjectIDFromHex(user.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		removed := false
+		err = repo.transact(func(ctx context.Context) error {
+			// Users restored or purged by another instance meanwhile are left as they are
+			result, err := coll.DeleteOne(ctx, bson.M{"_id": objID, "version": user.Version, "deletedAt": bson.M{"$lt": deletedBefore}})
+			if err != nil {
+				return err
+			}
+			removed = result.DeletedCount > 0
+			if !removed {
+				return nil
+			}
+			audits := repo.client.Database(repo.database).Collection(auditCollection)
+			_, err = audits.InsertOne(ctx, newAuditEntry(actor, AUDIT_PURGE, &user, nil))
+			return err
+		})
+		if err != nil {
+			return purged, err
+		}
+		if removed {
+			purged++
+		}
+	}
+	return purged, cursor.Err()
+}
+
+func (repo *userRepository) VerifyEmail(actor Actor, userID string, email string, verifiedAt time.Time) (*UserEvent, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "email": bson.M{"$eq": email}, "deletedAt": notDeleted}
+	update := bson.M{
+		"$set": bson.M{"emailVerified": true, "verifiedAt": verifiedAt.UTC()},
+		"$inc": bson.M{"version": 1},
+	}
+	stamp(update, actor)
+
+	var event *UserEvent
+	err = repo.transact(func(ctx context.Context) (err error) {
+		event, err = repo.updateChanged(ctx, AUDIT_UPDATE, actor, filter, update)
 		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf(DOCUMENT_NOT_FOUND)
+		}
+		return nil, err
+	}
+	return event, nil
+}
+
+// Sets updatedAt and updatedBy along with the fields set by an update
+func stamp(update bson.M, actor Actor) {
+	set, ok := update["$set"].(bson.M)
+	if !ok {
+		set = bson.M{}
+		update["$set"] = set
+	}
+	set["updatedAt"] = time.Now().UTC()
+	set["updatedBy"] = actor.ID
+}
+
+/*
+Runs fn in a transaction when the deployment supports them, so a user change, its
+audit entry and its outbox event are written together. The write fails when the
+support can not be checked.
+
+Standalone servers do not support transactions, fn then runs without one and an
+event may be lost if the process stops between the writes. They are only used
+when allowed with ALLOW_STANDALONE_MONGODB, see CheckTransactions.
+*/
+func (repo *userRepository) transact(fn func(ctx context.Context) error) error {
+	transactional, err := repo.supportsTransactions()
+	if err != nil {
+		return err
+	}
+	if !transactional {
+		return fn(context.Background())
+	}
+
+	session, err := repo.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// Failed checks are not kept, the next write checks again
+func (repo *userRepository) supportsTransactions() (bool, error) {
+	repo.checked.Lock()
+	defer repo.checked.Unlock()
+	if repo.known {
+		return repo.transactional, nil
+	}
+
+	transactional, err := supportsTransactions(repo.client)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on isMaster : %v", err))
+		return false, err
+	}
+	if !transactional {
+		fmt.Println("MongoDB does not support transactions, user changes and their events are written apart")
+	}
+	repo.known, repo.transactional = true, transactional
+	return transactional, nil
+}
+
+// Replica set members and mongos routers support transactions, standalone servers do not
+func supportsTransactions(client *mongo.Client) (bool, error) {
+	var hello bson.M
+	err := client.Database("admin").RunCommand(context.Background(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false, err
+	}
+	_, replicaSet := hello["setName"]
+	return replicaSet || hello["msg"] == "isdbgrid", nil
+}
+
+/*
+Returns an error when the deployment of client does not support transactions, unless
+allowStandalone, since user changes, their audit entries and their events could then
+be written apart.
+*/
+func CheckTransactions(client *mongo.Client, allowStandalone bool) error {
+	transactional, err := supportsTransactions(client)
+	if err != nil {
+		return fmt.Errorf("Error on isMaster : %v", err)
+	}
+	if !transactional && !allowStandalone {
+		return fmt.Errorf("MongoDB does not support transactions, run it as a replica set or set ALLOW_STANDALONE_MONGODB")
 	}
 	return nil
 }
 
+/*
+Applies update on the user matching filter and writes the change as action, returning
+its event. The user is read whole before and after the update, so the audit log tells
+whether the password changed.
+*/
+func (repo *userRepository) updateChanged(ctx context.Context, action string, actor Actor, filter bson.M, update bson.M) (*UserEvent, error) {
+	coll := repo.client.Database(repo.database).Collection(userCollection)
+
+	var before, after User
+	if err := coll.FindOneAndUpdate(ctx, filter, update).Decode(&before); err != nil {
+		return nil, err
+	}
+	objID, err := primitive.ObjectIDFromHex(before.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := coll.FindOne(ctx, bson.M{"_id": objID}).Decode(&after); err != nil {
+		return nil, err
+	}
+	return repo.writeChange(ctx, action, actor, &before, &after)
+}
+
+// Events told on the outbox for each change on the audit log
+var auditEvents = map[string]string{
+	AUDIT_CREATE: EVENT_USER_CREATED,
+	AUDIT_UPDATE: EVENT_USER_UPDATED,
+	AUDIT_DELETE: EVENT_USER_DELETED,
+	// Restored users are told created, since they were deleted for the systems told
+	AUDIT_RESTORE: EVENT_USER_CREATED,
+}
+
+// Appends the change made as action on a user to the audit log and its event to the outbox
+func (repo *userRepository) writeChange(ctx context.Context, action string, actor Actor, before *User, after *User) (*UserEvent, error) {
+	coll := repo.client.Database(repo.database).Collection(auditCollection)
+	if _, err := coll.InsertOne(ctx, newAuditEntry(actor, action, before, after)); err != nil {
+		return nil, err
+	}
+	return repo.writeEvent(ctx, auditEvents[action], actor, after)
+}
+
+// Appends the eventType event of a change on user to the outbox, read by the relay, and returns it
+func (repo *userRepository) writeEvent(ctx context.Context, eventType string, actor Actor, user *User) (*UserEvent, error) {
+	now := time.Now().UTC()
+	event := UserEvent{
+		ID:        primitive.NewObjectID().Hex(),
+		Type:      eventType,
+		UserID:    user.ID,
+		Version:   user.Version,
+		Actor:     actor.ID,
+		RequestID: actor.RequestID,
+		Timestamp: now,
+	}
+	if eventType != EVENT_USER_DELETED {
+		told := *user
+		told.Password = ""
+		event.User = &told
+	}
+
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	if _, err := coll.InsertOne(ctx, OutboxEvent{Event: event, CreatedAt: now, NextAttemptAt: now}); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Tells why a write matched no user: the user does not exist or its version moved on
+func (repo *userRepository) unmatched(objID primitive.ObjectID) error {
+	coll := repo.client.Database(repo.database).Collection(userCollection)
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "deletedAt": notDeleted}
+	count, err := coll.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return fmt.Errorf(VERSION_MISMATCH)
+}
+
+type verificationRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewVerificationRepository(client *mongo.Client, database string) VerificationRepository {
+	return &verificationRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *verificationRepository) InsertVerification(verification EmailVerification) error {
+	verification.ID = ""
+	coll := repo.client.Database(repo.database).Collection(verificationCollection)
+	_, err := coll.InsertOne(context.Background(), verification)
+	return err
+}
+
+func (repo *verificationRepository) ConsumeVerification(userID string, tokenHash string) (*EmailVerification, error) {
+	coll := repo.client.Database(repo.database).Collection(verificationCollection)
+	now := time.Now().UTC()
+	filter := bson.M{
+		"tokenHash": bson.M{"$eq": tokenHash},
+		"userId":    bson.M{"$eq": userID},
+		"usedAt":    bson.M{"$exists": false},
+		"expiresAt": bson.M{"$gt": now},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": now}}
+
+	var verification EmailVerification
+	err := coll.FindOneAndUpdate(context.Background(), filter, update).Decode(&verification)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// Creates the unique token hash index and the TTL index removing expired tokens
+func (repo *verificationRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(verificationCollection)
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	})
+	return err
+}
+
+type auditRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewAuditRepository(client *mongo.Client, database string) AuditRepository {
+	return &auditRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *auditRepository) ListEntries(criteria AuditCriteria, limit int) ([]AuditEntry, error) {
+	coll := repo.client.Database(repo.database).Collection(auditCollection)
+
+	filter, err := criteria.filterBSON()
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := coll.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AuditEntry, 0, limit)
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Creates the indexes listing the history of a user and the changes of an actor, newest first
+func (repo *auditRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(auditCollection)
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+type outboxRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewOutboxRepository(client *mongo.Client, database string) OutboxRepository {
+	return &outboxRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *outboxRepository) ClaimEvent(now time.Time, lease time.Duration) (*OutboxEvent, error) {
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	filter := bson.M{"publishedAt": bson.M{"$eq": nil}, "nextAttemptAt": bson.M{"$lte": now.UTC()}}
+	update := bson.M{"$set": bson.M{"nextAttemptAt": now.Add(lease).UTC()}, "$inc": bson.M{"attempts": 1}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var event OutboxEvent
+	err := coll.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&event)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (repo *outboxRepository) MarkPublished(ID string, publishedAt time.Time) error {
+	return repo.set(ID, bson.M{"publishedAt": publishedAt.UTC()})
+}
+
+func (repo *outboxRepository) FailEvent(ID string, lastError string) error {
+	return repo.set(ID, bson.M{"lastError": lastError})
+}
+
+func (repo *outboxRepository) set(ID string, fields bson.M) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	_, err = coll.UpdateOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}}, bson.M{"$set": fields})
+	return err
+}
+
+func (repo *outboxRepository) PurgePublished(publishedBefore time.Time) (int64, error) {
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	filter := bson.M{"publishedAt": bson.M{"$lt": publishedBefore.UTC()}}
+
+	result, err := coll.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (repo *outboxRepository) WatchEvents(ctx context.Context, publisher Publisher) error {
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}}
+
+	stream, err := coll.Watch(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change struct {
+			FullDocument OutboxEvent `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		if err := publisher.Publish(change.FullDocument.Event); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// Creates the indexes claiming events due and purging events published
+func (repo *outboxRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(outboxCollection)
+	_, err := coll.Indexes().CreateMany(context.Background(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "publishedAt", Value: 1}, {Key: "nextAttemptAt", Value: 1}}},
+	})
+	return err
+}
+
 type Projection []ProjectionsFields
 
 func (d Projection) Map() ProjectionMap {
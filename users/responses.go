@@ -59,3 +59,113 @@ var USER_DELETE_FAILED UserResponse = UserResponse{
 	Message: "User Delete Failed",
 	Code:    "USER_DELETE_FAILED",
 }
+
+var USER_RESTORED UserResponse = UserResponse{
+	Message: "User Restored",
+	Code:    "USER_RESTORED",
+}
+
+var USER_RESTORE_FAILED UserResponse = UserResponse{
+	Message: "User Restore Failed",
+	Code:    "USER_RESTORE_FAILED",
+}
+
+var USER_NOT_RESTORABLE UserResponse = UserResponse{
+	Message: "User Is Not Deleted",
+	Code:    "USER_NOT_RESTORABLE",
+}
+
+var USER_EMAIL_TAKEN UserResponse = UserResponse{
+	Message: "User Email Is Taken By Another User",
+	Code:    "USER_EMAIL_TAKEN",
+}
+
+var INVALID_PAGE_TOKEN UserResponse = UserResponse{
+	Message: "Invalid Page Token",
+	Code:    "INVALID_PAGE_TOKEN",
+}
+
+var INVALID_PAGE_SIZE UserResponse = UserResponse{
+	Message: "Invalid Page Size",
+	Code:    "INVALID_PAGE_SIZE",
+}
+
+var USER_LIST_FAILED UserResponse = UserResponse{
+	Message: "User List Failed",
+	Code:    "USER_LIST_FAILED",
+}
+
+var INVALID_PATCH UserResponse = UserResponse{
+	Message: "Invalid Patch Document",
+	Code:    "INVALID_PATCH",
+}
+
+var UNSUPPORTED_PATCH_TYPE UserResponse = UserResponse{
+	Message: "Unsupported Patch Content Type",
+	Code:    "UNSUPPORTED_PATCH_TYPE",
+}
+
+var USER_VERSION_MISMATCH UserResponse = UserResponse{
+	Message: "User Version Does Not Match",
+	Code:    "USER_VERSION_MISMATCH",
+}
+
+var ACCESS_DENIED UserResponse = UserResponse{
+	Message: "Access Denied",
+	Code:    "ACCESS_DENIED",
+}
+
+var INVALID_ROLES UserResponse = UserResponse{
+	Message: "Invalid Roles",
+	Code:    "INVALID_ROLES",
+}
+
+var INVALID_VERIFICATION_TOKEN UserResponse = UserResponse{
+	Message: "Invalid Verification Token",
+	Code:    "INVALID_VERIFICATION_TOKEN",
+}
+
+var EMAIL_VERIFIED UserResponse = UserResponse{
+	Message: "Email Verified",
+	Code:    "EMAIL_VERIFIED",
+}
+
+var EMAIL_VERIFY_FAILED UserResponse = UserResponse{
+	Message: "Email Verify Failed",
+	Code:    "EMAIL_VERIFY_FAILED",
+}
+
+var EMAIL_NOT_VERIFIED UserResponse = UserResponse{
+	Message: "Email Not Verified",
+	Code:    "EMAIL_NOT_VERIFIED",
+}
+
+var AUDIT_LIST_FAILED UserResponse = UserResponse{
+	Message: "Audit List Failed",
+	Code:    "AUDIT_LIST_FAILED",
+}
+
+var INVALID_AS_OF UserResponse = UserResponse{
+	Message: "Invalid asOf Time",
+	Code:    "INVALID_AS_OF",
+}
+
+var INVALID_REVISION UserResponse = UserResponse{
+	Message: "Invalid Revision",
+	Code:    "INVALID_REVISION",
+}
+
+var USER_REVISION_NOT_FOUND UserResponse = UserResponse{
+	Message: "User Revision Not Found",
+	Code:    "USER_REVISION_NOT_FOUND",
+}
+
+var USER_HISTORY_INCOMPLETE UserResponse = UserResponse{
+	Message: "User History Is Incomplete",
+	Code:    "USER_HISTORY_INCOMPLETE",
+}
+
+var USER_REVERTED UserResponse = UserResponse{
+	Message: "User Reverted",
+	Code:    "USER_REVERTED",
+}
@@ -3,21 +3,97 @@
 package users
 
 import (
+	"context"
+	"fmt"
 	"userapi/config"
+	"userapi/mailer"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Method to add routes in api (gin.RouterGroup), using config (config.Config)
-// and client (mongo.Client)
-func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client) {
+// Method to add routes in api (gin.RouterGroup), using config (config.Config),
+// service (UserService) and bus (EventBus) to stream user changes
+func AddRoutes(api *gin.RouterGroup, config config.Config, service UserService, bus *EventBus) {
+	var userController UserController = NewUserController(service)
+	var eventController EventController = NewEventController(bus)
+
+	verified := config.RequireVerifiedEmail
+	api.GET("/users", Authorize(PERMISSION_READ, false), userController.ListUsers)
+	api.GET("/users/events", Authorize(PERMISSION_READ, false), eventController.StreamEvents)
+	api.GET("/users/:id", Authorize(PERMISSION_READ, true), RequireVerifiedEmail(PERMISSION_READ, verified), userController.GetUser)
+	api.POST("/users", Authorize(PERMISSION_WRITE, false), userController.CreateUser)
+	api.PUT("/users/:id", Authorize(PERMISSION_WRITE, false), userController.UpdateUser)
+	api.POST("/users/:id/revisions/:rev/revert", Authorize(PERMISSION_WRITE, false), userController.RevertUser)
+	api.PATCH("/users/:id", Authorize(PERMISSION_WRITE, true), RequireVerifiedEmail(PERMISSION_WRITE, verified), userController.PatchUser)
+	api.DELETE("/users/:id", Authorize(PERMISSION_DELETE, false), userController.DeleteUser)
+	api.POST("/users/:id/restore", Authorize(PERMISSION_DELETE, false), userController.RestoreUser)
+	api.PUT("/users/:id/roles", Authorize(PERMISSION_ROLES, false), userController.SetRoles)
+	api.GET("/users/:id/history", Authorize(PERMISSION_READ, false), userController.GetUserHistory)
+	api.GET("/audit", Authorize(PERMISSION_AUDIT, false), userController.ListAuditEntries)
+}
+
+// Method to add routes not requiring authentication in api (gin.RouterGroup),
+// using service (UserService)
+func AddPublicRoutes(api *gin.RouterGroup, service UserService) {
+	var userController UserController = NewUserController(service)
+
+	api.POST("/users/:id/verify-email", userController.VerifyEmail)
+}
+
+// Method to start purging soft deleted users in background, using config (config.Config)
+// and service (UserService), until ctx is done
+func StartPurger(ctx context.Context, config config.Config, service UserService) {
+	go RunPurger(ctx, service, config.PurgeInterval)
+}
+
+// Method to start publishing user events written to the outbox in background, using
+// config (config.Config), client (mongo.Client) and publisher (Publisher), until ctx is done
+func StartRelay(ctx context.Context, config config.Config, client *mongo.Client, publisher Publisher) {
+	var outboxRepository OutboxRepository = NewOutboxRepository(client, config.Database)
+	if err := outboxRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	go RunRelay(ctx, NewEventRelay(outboxRepository, publisher, config), config.OutboxPollInterval)
+}
+
+// Method to start feeding bus (EventBus) from MongoDB change streams in background, using
+// config (config.Config) and client (mongo.Client), until ctx is done. Without CHANGE_STREAMS
+// the user service of this instance feeds bus.
+func StartEventStream(ctx context.Context, config config.Config, client *mongo.Client, bus *EventBus) {
+	if config.ChangeStreams {
+		go RunChangeStream(ctx, NewOutboxRepository(client, config.Database), bus, config.OutboxPollInterval)
+	}
+}
+
+// Method returning the publisher of the user changes made in process to bus (EventBus), using
+// config (config.Config). Change streams feed bus with the changes of every instance, changes
+// are then not published in process and nil is returned.
+func StreamPublisher(config config.Config, bus *EventBus) Publisher {
+	if config.ChangeStreams {
+		return nil
+	}
+	return bus
+}
+
+// Method returning the user service shared by the routes and the purger, using config (config.Config),
+// client (mongo.Client), passwords (PasswordPolicy) and bus (EventBus) to publish user changes,
+// creating the indexes it needs. Fails when MongoDB does not support transactions, unless ALLOW_STANDALONE_MONGODB.
+func NewService(config config.Config, client *mongo.Client, passwords PasswordPolicy, bus *EventBus) (UserService, error) {
+	if err := CheckTransactions(client, config.AllowStandalone); err != nil {
+		return nil, err
+	}
+
 	var userRepository UserRepository = NewUserRepository(client, config.Database)
-	var userService UserService = NewUserService(userRepository)
-	var userController UserController = NewUserController(userService)
+	var verificationRepository VerificationRepository = NewVerificationRepository(client, config.Database)
+	var auditRepository AuditRepository = NewAuditRepository(client, config.Database)
+	var userService UserService = NewUserService(userRepository, verificationRepository, auditRepository, StreamPublisher(config, bus), mailer.NewMailer(config.MailFile), passwords, config)
 
-	api.GET("/users/:id", userController.GetUser)
-	api.POST("/users", userController.CreateUser)
-	api.PUT("/users/:id", userController.UpdateUser)
-	api.DELETE("/users/:id", userController.DeleteUser)
+	if err := verificationRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	if err := auditRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	return userService, nil
 }
@@ -1,7 +1,11 @@
 package users
 
 import (
+	"encoding/json"
 	"fmt"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -12,9 +16,10 @@ type UserService interface {
 
 		Parameters
 
+		actor: Author of the change, recorded as createdBy.
 		user: The user data to create new user.
 	*/
-	CreateUser(user User) (string, error)
+	CreateUser(actor Actor, user User) (string, error)
 	/*
 		Method to get user
 
@@ -24,22 +29,124 @@ type UserService interface {
 	*/
 	GetUser(userID string) (*User, error)
 	/*
-		Method to update user
+		Method to get user as it was at a given time, from its audit log
 
 		Parameters
 
+		userID: User ID to find user data.
+		asOf: Time the user is returned as of.
+	*/
+	GetUserAsOf(userID string, asOf time.Time) (*User, error)
+	/*
+		Method to list users page by page
+
+		Parameters
+
+		query: Page token, page size, filters and sort to list users.
+	*/
+	ListUsers(query ListQuery) (*UserPage, error)
+	/*
+		Method to fully replace user data
+
+		Parameters
+
+		actor: Author of the change, recorded as updatedBy.
 		userID: User ID to find user data.
 		user: User data to update user.
+		precondition: User versions accepted, any version when empty.
+
+		Returns the new user version.
+	*/
+	UpdateUser(actor Actor, userID string, user User, precondition Precondition) (int64, error)
+	/*
+		Method to partially update user
+
+		Parameters
+
+		actor: Author of the change, recorded as updatedBy.
+		userID: User ID to find user data.
+		patch: JSON Merge Patch or JSON Patch document to apply on user data.
+		precondition: User versions accepted, any version when empty.
+
+		Returns the new user version.
 	*/
-	UpdateUser(userID string, user User) error
+	PatchUser(actor Actor, userID string, patch UserPatch, precondition Precondition) (int64, error)
 	/*
-		Method to delete user
+		Method to replace user data with the data of one of its revisions, as an update
 
 		Parameters
 
+		actor: Author of the change, recorded as updatedBy.
 		userID: User ID to find user data.
+		revision: User version to revert to, found on the user history.
+		precondition: User versions accepted, any version when empty.
+
+		Returns the new user version.
 	*/
-	DeleteUser(userID string) error
+	RevertUser(actor Actor, userID string, revision int64, precondition Precondition) (int64, error)
+	/*
+		Method to soft delete user, which can be restored until purged
+
+		Parameters
+
+		actor: Author of the change, recorded as updatedBy.
+		userID: User ID to find user data.
+		precondition: User versions accepted, any version when empty.
+	*/
+	DeleteUser(actor Actor, userID string, precondition Precondition) error
+	/*
+		Method to restore a soft deleted user
+
+		Parameters
+
+		actor: Author of the change, recorded as updatedBy.
+		userID: User ID to find user data.
+
+		Returns the new user version.
+	*/
+	RestoreUser(actor Actor, userID string) (int64, error)
+	/*
+		Method to permanently remove users soft deleted longer than DELETED_RETENTION
+
+		Returns the number of users removed.
+	*/
+	PurgeDeletedUsers() (int64, error)
+	/*
+		Method to replace user roles
+
+		Parameters
+
+		actor: Author of the change, recorded as updatedBy.
+		userID: User ID to find user data.
+		roles: Roles granted to user.
+	*/
+	SetRoles(actor Actor, userID string, roles []string) error
+	/*
+		Method to list the changes made on a user, newest first
+
+		Parameters
+
+		userID: User ID to find user changes, also of users deleted.
+		query: Page token, page size and filters to list changes.
+	*/
+	GetUserHistory(userID string, query AuditQuery) (*AuditPage, error)
+	/*
+		Method to list the changes made on any user, newest first
+
+		Parameters
+
+		query: Page token, page size and filters to list changes.
+	*/
+	ListAuditEntries(query AuditQuery) (*AuditPage, error)
+	/*
+		Method to confirm user email with the token sent to it
+
+		Parameters
+
+		userID: User ID to find user data.
+		token: Verification token sent to the user email.
+	*/
+	VerifyEmail(userID string, token string) error
 }
 
 type userServiceError struct {
@@ -54,21 +161,52 @@ const CREATE_USER_FAILED string = "CREATE_USER_FAILED"
 const GET_USER_FAILED string = "GET_USER_FAILED"
 const USER_ID_INVALID string = "USER_ID_INVALID"
 const USER_EXISTS string = "USER_EXISTS"
-const USER_NOT_EXISTS string = "USER_EXISTS"
+const USER_NOT_EXISTS string = "USER_NOT_EXISTS"
+const LIST_USERS_FAILED string = "LIST_USERS_FAILED"
+const PAGE_TOKEN_INVALID string = "PAGE_TOKEN_INVALID"
 const UPDATE_USER_FAILED string = "UPDATE_USER_FAILED"
+const PATCH_INVALID string = "PATCH_INVALID"
+const PRECONDITION_FAILED string = "PRECONDITION_FAILED"
+const ROLES_INVALID string = "ROLES_INVALID"
 const DELETE_USER_FAILED string = "DELETE_USER_FAILED"
+const RESTORE_USER_FAILED string = "RESTORE_USER_FAILED"
+const USER_NOT_DELETED string = "USER_NOT_DELETED"
+const PURGE_USERS_FAILED string = "PURGE_USERS_FAILED"
+const VERIFICATION_TOKEN_INVALID string = "VERIFICATION_TOKEN_INVALID"
+const VERIFY_EMAIL_FAILED string = "VERIFY_EMAIL_FAILED"
+const LIST_AUDIT_FAILED string = "LIST_AUDIT_FAILED"
+const REVISION_NOT_FOUND string = "REVISION_NOT_FOUND"
+const HISTORY_INCOMPLETE string = "HISTORY_INCOMPLETE"
 
 type userService struct {
-	repo UserRepository
+	repo          UserRepository
+	verifications VerificationRepository
+	audits        AuditRepository
+	events        Publisher
+	mailer        mailer.Mailer
+	passwords     PasswordPolicy
+	config        config.Config
 }
 
-func NewUserService(repo UserRepository) UserService {
+func NewUserService(repo UserRepository, verifications VerificationRepository, audits AuditRepository, events Publisher, mailer mailer.Mailer, passwords PasswordPolicy, config config.Config) UserService {
 	return &userService{
-		repo: repo,
+		repo:          repo,
+		verifications: verifications,
+		audits:        audits,
+		events:        events,
+		mailer:        mailer,
+		passwords:     passwords,
+		config:        config,
 	}
 }
 
-func (svc *userService) CreateUser(user User) (string, error) {
+func (svc *userService) CreateUser(actor Actor, user User) (string, error) {
+	user.Email = NormalizeEmail(user.Email)
+	user.normalizeAge()
+	if validation := ValidateNewUser(user, svc.passwords, svc.inputRules()...); validation != nil {
+		return "", validation
+	}
+
 	projection := Projection{{Key: "_id", Value: 1}}
 	existingUser, err := svc.repo.FindUserByEmail(user.Email, projection)
 	if err != nil {
@@ -82,16 +220,38 @@ func (svc *userService) CreateUser(user User) (string, error) {
 	}
 
 	user.Password = svc.hashPassword(user.Password)
+	// Roles are only granted through SetRoles
+	user.Roles = []string{ROLE_SELF}
+	user.EmailVerified = false
+	user.VerifiedAt = nil
 
-	insertID, err := svc.repo.InsertUser(user)
-	if existingUser != nil {
-		fmt.Println(fmt.Errorf("User already exists"))
+	// The unique email index settles concurrent signups passing the check above
+	event, err := svc.repo.InsertUser(actor, user)
+	if err != nil {
+		if err.Error() == DUPLICATE_EMAIL {
+			fmt.Println(fmt.Errorf("User already exists"))
+			return "", &userServiceError{code: USER_EXISTS}
+		}
+		fmt.Println(fmt.Errorf("Error on InsertUser : %v", err))
 		return "", &userServiceError{code: CREATE_USER_FAILED}
 	}
-	return insertID, nil
+	svc.publish(event)
+
+	svc.sendVerification(event.UserID, user.Email)
+	return event.UserID, nil
 }
 
 func (svc *userService) GetUser(userID string) (*User, error) {
+	user, err := svc.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	user.computeAge(time.Now())
+	return user, nil
+}
+
+// Returns the user as stored, without the computed age
+func (svc *userService) findUser(userID string) (*User, error) {
 	projection := Projection{{Key: "password", Value: 0}}
 	user, err := svc.repo.FindUserByID(userID, projection)
 	if err != nil {
@@ -111,31 +271,252 @@ func (svc *userService) GetUser(userID string) (*User, error) {
 	return user, nil
 }
 
-func (svc *userService) UpdateUser(userID string, user User) error {
-	if err := svc.repo.UpdateUser(userID, user); err != nil {
+func (svc *userService) ListUsers(query ListQuery) (*UserPage, error) {
+	criteria := ListCriteria{Filters: query.Filters, Sort: query.Sort, IncludeDeleted: query.IncludeDeleted}
+	if query.Next != "" {
+		cursor, err := decodeCursor(query.Next)
+		if err != nil || cursor.Sort != sortSpec(query.Sort) {
+			fmt.Println(fmt.Errorf("Invalid page token : %v", err))
+			return nil, &userServiceError{code: PAGE_TOKEN_INVALID}
+		}
+		criteria.AfterID = cursor.ID
+		criteria.AfterValues = cursor.Values
+	}
+
+	limit := svc.pageSize(query.Limit)
+
+	// Fetch one more user than requested to know if there is a next page
+	projection := Projection{{Key: "password", Value: 0}}
+	users, err := svc.repo.ListUsers(criteria, limit+1, projection)
+	if err != nil {
 		if err.Error() == INVALID_OBJECT_ID {
-			fmt.Println(fmt.Errorf("Invalid user id : %v", err))
-			return &userServiceError{code: USER_ID_INVALID}
+			fmt.Println(fmt.Errorf("Invalid page token : %v", err))
+			return nil, &userServiceError{code: PAGE_TOKEN_INVALID}
+		}
+		fmt.Println(fmt.Errorf("Error on ListUsers : %v", err))
+		return nil, &userServiceError{code: LIST_USERS_FAILED}
+	}
+
+	now := time.Now()
+	for i := range users {
+		users[i].computeAge(now)
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.Next = encodeCursor(newCursor(page.Users[limit-1], query.Sort))
+	}
+	return page, nil
+}
+
+// Extra rules on users received as input, legacy ages are rejected once their deprecation is over
+func (svc *userService) inputRules() []FieldRule {
+	if svc.config.RejectLegacyAge {
+		return []FieldRule{rejectLegacyAge}
+	}
+	return nil
+}
+
+func (svc *userService) pageSize(limit int) int {
+	if limit <= 0 {
+		limit = svc.config.PageSize
+	}
+	if svc.config.MaxPageSize > 0 && limit > svc.config.MaxPageSize {
+		limit = svc.config.MaxPageSize
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	return limit
+}
+
+func (svc *userService) UpdateUser(actor Actor, userID string, user User, precondition Precondition) (int64, error) {
+	user.Email = NormalizeEmail(user.Email)
+	user.normalizeAge()
+	if validation := ValidateUser(user, svc.passwords, svc.inputRules()...); validation != nil {
+		return 0, validation
+	}
+
+	current, err := svc.findUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !precondition.matches(current.Version) {
+		fmt.Println(fmt.Errorf("User version %d does not match precondition", current.Version))
+		return 0, &userServiceError{code: PRECONDITION_FAILED}
+	}
+
+	// The verification is kept unless the email changes
+	emailChanged := user.Email != current.Email
+	user.EmailVerified = current.EmailVerified && !emailChanged
+	user.VerifiedAt = nil
+	if user.EmailVerified {
+		user.VerifiedAt = current.VerifiedAt
+	}
+
+	if user.Password != "" {
+		user.Password = svc.hashPassword(user.Password)
+	}
+
+	// The user read is the one recorded as replaced, so it is only replaced on that version
+	event, err := svc.repo.UpdateUser(actor, userID, user, Precondition{current.Version})
+	if err != nil {
+		return 0, svc.writeError("UpdateUser", err, UPDATE_USER_FAILED)
+	}
+	svc.publish(event)
+
+	if emailChanged {
+		svc.sendVerification(userID, user.Email)
+	}
+	return event.Version, nil
+}
+
+func (svc *userService) PatchUser(actor Actor, userID string, patch UserPatch, precondition Precondition) (int64, error) {
+	user, err := svc.findUser(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !precondition.matches(user.Version) {
+		fmt.Println(fmt.Errorf("User version %d does not match precondition", user.Version))
+		return 0, &userServiceError{code: PRECONDITION_FAILED}
+	}
+
+	document, _ := json.Marshal(user)
+	patched, err := patch.apply(document)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Invalid patch : %v", err))
+		return 0, &userServiceError{code: PATCH_INVALID}
+	}
+
+	patchedUser, err := userFromPatch(*user, patched)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Invalid patch : %v", err))
+		return 0, &userServiceError{code: PATCH_INVALID}
+	}
+	patchedUser.Email = NormalizeEmail(patchedUser.Email)
+	patchedUser.normalizeAge()
+	changes := diffUser(*user, *patchedUser)
+
+	// Only changed fields are validated, so stored data does not block unrelated patches
+	if validation := ValidateUser(*patchedUser, svc.passwords, svc.inputRules()...).forFields(changes.fields()); validation != nil {
+		return 0, validation
+	}
+
+	if changes.empty() {
+		return user.Version, nil
+	}
+
+	for i := range changes.Set {
+		if changes.Set[i].Key == "password" {
+			changes.Set[i].Value = svc.hashPassword(patchedUser.Password)
 		}
-		fmt.Println(fmt.Errorf("Error on UpdateUser : %v", err))
-		return &userServiceError{code: UPDATE_USER_FAILED}
 	}
+
+	emailChanged := patchedUser.Email != user.Email
+	if emailChanged {
+		changes.Set = append(changes.Set, ProjectionsFields{Key: "emailVerified", Value: false})
+		changes.Unset = append(changes.Unset, "verifiedAt")
+	}
+
+	// The patch was computed from the version read, so it is only applied on that version
+	event, err := svc.repo.PatchUser(actor, userID, *changes, Precondition{user.Version})
+	if err != nil {
+		return 0, svc.writeError("PatchUser", err, UPDATE_USER_FAILED)
+	}
+	svc.publish(event)
+
+	if emailChanged {
+		svc.sendVerification(userID, patchedUser.Email)
+	}
+	return event.Version, nil
+}
+
+func (svc *userService) DeleteUser(actor Actor, userID string, precondition Precondition) error {
+	user, err := svc.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !precondition.matches(user.Version) {
+		fmt.Println(fmt.Errorf("User version %d does not match precondition", user.Version))
+		return &userServiceError{code: PRECONDITION_FAILED}
+	}
+
+	// The user read is the one recorded as deleted, so it is only deleted on that version
+	event, err := svc.repo.DeleteUser(actor, userID, Precondition{user.Version})
+	if err != nil {
+		return svc.writeError("DeleteUser", err, DELETE_USER_FAILED)
+	}
+	svc.publish(event)
 	return nil
 }
 
-func (svc *userService) DeleteUser(userID string) error {
-	if err := svc.repo.DeleteUser(userID); err != nil {
-		if err.Error() == INVALID_OBJECT_ID {
-			fmt.Println(fmt.Errorf("Invalid user id : %v", err))
-			return &userServiceError{code: USER_ID_INVALID}
+func (svc *userService) RestoreUser(actor Actor, userID string) (int64, error) {
+	event, err := svc.repo.RestoreUser(actor, userID)
+	if err != nil {
+		if err.Error() == NOT_DELETED {
+			fmt.Println(fmt.Errorf("User is not deleted"))
+			return 0, &userServiceError{code: USER_NOT_DELETED}
 		}
-		fmt.Println(fmt.Errorf("Error on DeleteUser : %v", err))
-		return &userServiceError{code: DELETE_USER_FAILED}
+		return 0, svc.writeError("RestoreUser", err, RESTORE_USER_FAILED)
+	}
+	svc.publish(event)
+	return event.Version, nil
+}
+
+func (svc *userService) PurgeDeletedUsers() (int64, error) {
+	purged, err := svc.repo.PurgeUsers(Actor{ID: PURGER}, time.Now().Add(-svc.config.DeletedRetention))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on PurgeUsers : %v", err))
+		return 0, &userServiceError{code: PURGE_USERS_FAILED}
+	}
+	return purged, nil
+}
+
+func (svc *userService) SetRoles(actor Actor, userID string, roles []string) error {
+	if len(roles) == 0 || !validRoles(roles) {
+		fmt.Println(fmt.Errorf("Invalid roles : %v", roles))
+		return &userServiceError{code: ROLES_INVALID}
 	}
+
+	changes := UserChanges{Set: Projection{{Key: "roles", Value: roles}}}
+	event, err := svc.repo.PatchUser(actor, userID, changes, nil)
+	if err != nil {
+		return svc.writeError("PatchUser", err, UPDATE_USER_FAILED)
+	}
+	svc.publish(event)
 	return nil
 }
 
+// Maps errors from repository writes to service errors
+func (svc *userService) writeError(method string, err error, code string) error {
+	switch err.Error() {
+	case INVALID_OBJECT_ID:
+		fmt.Println(fmt.Errorf("Invalid user id : %v", err))
+		return &userServiceError{code: USER_ID_INVALID}
+	case DOCUMENT_NOT_FOUND:
+		fmt.Println(fmt.Errorf("User not exists"))
+		return &userServiceError{code: USER_NOT_EXISTS}
+	case VERSION_MISMATCH:
+		fmt.Println(fmt.Errorf("User version does not match precondition"))
+		return &userServiceError{code: PRECONDITION_FAILED}
+	case DUPLICATE_EMAIL:
+		fmt.Println(fmt.Errorf("User already exists"))
+		return &userServiceError{code: USER_EXISTS}
+	}
+	fmt.Println(fmt.Errorf("Error on %s : %v", method, err))
+	return &userServiceError{code: code}
+}
+
 func (svc *userService) hashPassword(password string) string {
+	return HashPassword(password)
+}
+
+// Returns the bcrypt hash stored in place of a user password
+func HashPassword(password string) string {
 	bytes, _ := bcrypt.GenerateFromPassword([]byte(password), 8)
 	return string(bytes)
 }
@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
 
 	"github.com/golang/mock/gomock"
 )
 
+// Author of the changes made by service tests
+var testActor Actor = Actor{ID: "admin", RequestID: "request-1"}
+
 func TestServiceCreateUser(t *testing.T) {
 
 	const userID string = "64260e1da4c0c814bda5734a"
@@ -29,8 +35,13 @@ func TestServiceCreateUser(t *testing.T) {
 					Return(nil, nil)
 				repository.
 					EXPECT().
-					InsertUser(gomock.Any()).
-					Return(userID, nil)
+					InsertUser(testActor, gomock.Any()).
+					DoAndReturn(func(actor Actor, user User) (*UserEvent, error) {
+						if !reflect.DeepEqual(user.Roles, []string{ROLE_SELF}) {
+							t.Errorf("Expecting roles %v , but returns %v", []string{ROLE_SELF}, user.Roles)
+						}
+						return &UserEvent{UserID: userID, Version: 1}, nil
+					})
 			},
 			inputParam: User{
 				Address: Address{
@@ -108,8 +119,8 @@ func TestServiceCreateUser(t *testing.T) {
 					Return(nil, nil)
 				repository.
 					EXPECT().
-					InsertUser(gomock.Any()).
-					Return("", errors.New("Any Error"))
+					InsertUser(gomock.Any(), gomock.Any()).
+					Return(nil, errors.New("Any Error"))
 			},
 			inputParam: User{
 				Address: Address{
@@ -128,6 +139,57 @@ func TestServiceCreateUser(t *testing.T) {
 			expectedResponse: "",
 			expectedError:    &userServiceError{code: CREATE_USER_FAILED},
 		},
+		{
+			name: "email normalized",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByEmail("test@test.com", gomock.Any()).
+					Return(nil, nil)
+				repository.
+					EXPECT().
+					InsertUser(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(actor Actor, user User) (*UserEvent, error) {
+						if user.Email != "test@test.com" {
+							t.Errorf("Expecting email %s , but returns %s", "test@test.com", user.Email)
+						}
+						return &UserEvent{UserID: userID, Version: 1}, nil
+					})
+			},
+			inputParam:       User{Email: " Test@TEST.com ", Name: "Test", Password: "12345"},
+			expectedResponse: userID,
+			expectedError:    nil,
+		},
+		{
+			name: "concurrent signup with same email",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByEmail(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+				repository.
+					EXPECT().
+					InsertUser(gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(DUPLICATE_EMAIL))
+			},
+			inputParam:       User{Email: "test@test.com", Name: "Test", Password: "12345"},
+			expectedResponse: "",
+			expectedError:    &userServiceError{code: USER_EXISTS},
+		},
+		{
+			name:             "password required",
+			setupMock:        func(repository *MockUserRepository) {},
+			inputParam:       User{Email: "test@test.com", Name: "Test"},
+			expectedResponse: "",
+			expectedError:    NewValidationErrors([]FieldError{*PASSWORD_REQUIRED}),
+		},
+		{
+			name:             "password too short",
+			setupMock:        func(repository *MockUserRepository) {},
+			inputParam:       User{Email: "test@test.com", Name: "Test", Password: "1234"},
+			expectedResponse: "",
+			expectedError:    NewValidationErrors([]FieldError{*PASSWORD_TOO_SHORT}),
+		},
 	}
 
 	for _, tc := range tests {
@@ -136,9 +198,14 @@ func TestServiceCreateUser(t *testing.T) {
 			repo := NewMockUserRepository(ctrl)
 			tc.setupMock(repo)
 
-			service := NewUserService(repo)
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{MinLength: 5}, config.Config{})
 
-			result, err := service.CreateUser(tc.inputParam)
+			result, err := service.CreateUser(testActor, tc.inputParam)
 
 			if err != nil && err.Error() != tc.expectedError.Error() {
 				t.Errorf("Expecting error %d , but returns %d", tc.expectedError, err)
@@ -233,7 +300,12 @@ func TestServiceGetUser(t *testing.T) {
 			repo := NewMockUserRepository(ctrl)
 			tc.setupMock(repo)
 
-			service := NewUserService(repo)
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{})
 
 			result, err := service.GetUser(tc.inputParam)
 
@@ -242,7 +314,7 @@ func TestServiceGetUser(t *testing.T) {
 			}
 
 			if !reflect.DeepEqual(result, tc.expectedResponse) {
-				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, result)
+				t.Errorf("Expecting body %v , but returns %v", tc.expectedResponse, result)
 			}
 		})
 	}
@@ -267,6 +339,8 @@ func TestServiceUpdateUser(t *testing.T) {
 		Password: "12345",
 	}
 
+	verifiedAt := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
 	type updateParams struct {
 		UserID string
 		User   User
@@ -283,8 +357,52 @@ func TestServiceUpdateUser(t *testing.T) {
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(nil)
+					FindUserByID(userID, gomock.Any()).
+					Return(&User{Email: "test@test.com", EmailVerified: true, VerifiedAt: &verifiedAt}, nil)
+				repository.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(actor Actor, ID string, user User, precondition Precondition) (*UserEvent, error) {
+						if !user.EmailVerified || user.VerifiedAt != &verifiedAt {
+							t.Errorf("Expecting email verification kept , but returns %v %v", user.EmailVerified, user.VerifiedAt)
+						}
+						return &UserEvent{Version: 2}, nil
+					})
+			},
+			inputParam:    updateParams{UserID: userID, User: user},
+			expectedError: nil,
+		},
+		{
+			name: "email taken by another user",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(userID, gomock.Any()).
+					Return(&User{Email: "old@test.com"}, nil)
+				repository.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(DUPLICATE_EMAIL))
+			},
+			inputParam:    updateParams{UserID: userID, User: user},
+			expectedError: &userServiceError{code: USER_EXISTS},
+		},
+		{
+			name: "email changed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(userID, gomock.Any()).
+					Return(&User{Email: "old@test.com", EmailVerified: true, VerifiedAt: &verifiedAt}, nil)
+				repository.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(actor Actor, ID string, user User, precondition Precondition) (*UserEvent, error) {
+						if user.EmailVerified || user.VerifiedAt != nil {
+							t.Errorf("Expecting email verification reset , but returns %v %v", user.EmailVerified, user.VerifiedAt)
+						}
+						return &UserEvent{Version: 2}, nil
+					})
 			},
 			inputParam:    updateParams{UserID: userID, User: user},
 			expectedError: nil,
@@ -294,19 +412,34 @@ func TestServiceUpdateUser(t *testing.T) {
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(fmt.Errorf(INVALID_OBJECT_ID))
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(INVALID_OBJECT_ID))
 			},
 			inputParam:    updateParams{UserID: "any id invalid", User: user},
 			expectedError: &userServiceError{code: USER_ID_INVALID},
 		},
+		{
+			name: "user not exists",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			inputParam:    updateParams{UserID: userID, User: user},
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
 		{
 			name: "update user fail",
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(fmt.Errorf("Any error"))
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&User{Email: "test@test.com"}, nil)
+				repository.
+					EXPECT().
+					UpdateUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any error"))
 			},
 			inputParam:    updateParams{UserID: userID, User: user},
 			expectedError: &userServiceError{code: UPDATE_USER_FAILED},
@@ -319,9 +452,14 @@ func TestServiceUpdateUser(t *testing.T) {
 			repo := NewMockUserRepository(ctrl)
 			tc.setupMock(repo)
 
-			service := NewUserService(repo)
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{})
 
-			err := service.UpdateUser(tc.inputParam.UserID, tc.inputParam.User)
+			_, err := service.UpdateUser(testActor, tc.inputParam.UserID, tc.inputParam.User, nil)
 
 			if err != nil && err.Error() != tc.expectedError.Error() {
 				t.Errorf("Expecting error %d , but returns %d", tc.expectedError, err)
@@ -330,6 +468,325 @@ func TestServiceUpdateUser(t *testing.T) {
 	}
 }
 
+func TestServicePatchUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	var user User = User{
+		ID:      userID,
+		Version: 3,
+		Address: Address{
+			City:    "SP",
+			Country: "BR",
+			Number:  "111",
+			State:   "SP",
+			Street:  "Rua hum",
+			ZIP:     "12345-678",
+		},
+		Age:   "33",
+		Email: "test@test.com",
+		Name:  "Test",
+	}
+
+	tests := []struct {
+		name          string
+		setupMock     func(service *MockUserRepository)
+		inputParam    UserPatch
+		expectedError error
+	}{
+		{
+			name: "user with roles",
+			setupMock: func(repository *MockUserRepository) {
+				withRoles := user
+				withRoles.Roles = []string{ROLE_SELF}
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&withRoles, nil)
+				repository.
+					EXPECT().
+					PatchUser(testActor, userID, UserChanges{Set: Projection{{Key: "name", Value: "New Name"}}}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"name": "New Name"}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "legacy age converted into birth date",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserChanges{
+						Set:   Projection{{Key: "birthDate", Value: "1990-05-20"}},
+						Unset: []string{"age"},
+					}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"age": "20/05/1990"}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "read only field changed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"roles": ["admin"], "emailVerified": true}`),
+			},
+			expectedError: &userServiceError{code: PATCH_INVALID},
+		},
+		{
+			name: "email change resets verification",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserChanges{
+						Set:   Projection{{Key: "email", Value: "new@test.com"}, {Key: "emailVerified", Value: false}},
+						Unset: []string{"verifiedAt"},
+					}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"email": "new@test.com"}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "merge patch success",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserChanges{
+						Set:   Projection{{Key: "name", Value: "New Name"}},
+						Unset: []string{"address.number", "address.street"},
+					}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"name": "New Name", "address": {"street": null, "number": null}}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "json patch success",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserChanges{
+						Set:   Projection{{Key: "address.city", Value: "RJ"}},
+						Unset: []string{"age"},
+					}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: JSON_PATCH_CONTENT_TYPE,
+				Document: []byte(`[
+					{"op": "test", "path": "/address/city", "value": "SP"},
+					{"op": "replace", "path": "/address/city", "value": "RJ"},
+					{"op": "remove", "path": "/age"}
+				]`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "password is hashed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(actor Actor, userID string, changes UserChanges, precondition Precondition) (*UserEvent, error) {
+						if len(changes.Set) != 1 || changes.Set[0].Key != "password" || changes.Set[0].Value == "12345" {
+							t.Errorf("Expecting hashed password , but returns %v", changes.Set)
+						}
+						return &UserEvent{Version: 4}, nil
+					})
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"password": "12345"}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "json patch test failed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: JSON_PATCH_CONTENT_TYPE,
+				Document:    []byte(`[{"op": "test", "path": "/name", "value": "Other"}]`),
+			},
+			expectedError: &userServiceError{code: PATCH_INVALID},
+		},
+		{
+			name: "unknown field",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"role": "admin"}`),
+			},
+			expectedError: &userServiceError{code: PATCH_INVALID},
+		},
+		{
+			name: "id changed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"id": "64260e1da4c0c814bda5734b"}`),
+			},
+			expectedError: &userServiceError{code: PATCH_INVALID},
+		},
+		{
+			name: "user with timestamps",
+			setupMock: func(repository *MockUserRepository) {
+				createdAt := time.Date(2023, 3, 30, 10, 0, 0, 0, time.UTC)
+				withTimestamps := user
+				withTimestamps.CreatedAt, withTimestamps.CreatedBy = &createdAt, "admin"
+				withTimestamps.UpdatedAt, withTimestamps.UpdatedBy = &createdAt, "admin"
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&withTimestamps, nil)
+				repository.
+					EXPECT().
+					PatchUser(testActor, userID, UserChanges{Set: Projection{{Key: "name", Value: "New Name"}}}, Precondition{3}).
+					Return(&UserEvent{Version: 4}, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"name": "New Name"}`),
+			},
+			expectedError: nil,
+		},
+		{
+			name: "updatedBy changed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"updatedBy": "someone"}`),
+			},
+			expectedError: &userServiceError{code: PATCH_INVALID},
+		},
+		{
+			name: "email removed",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"email": null}`),
+			},
+			expectedError: NewValidationErrors([]FieldError{*EMAIL_REQUIRED}),
+		},
+		{
+			name: "user not exists",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"name": "New Name"}`),
+			},
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
+		{
+			name: "patch user fail",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&user, nil)
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any error"))
+			},
+			inputParam: UserPatch{
+				ContentType: MERGE_PATCH_CONTENT_TYPE,
+				Document:    []byte(`{"name": "New Name"}`),
+			},
+			expectedError: &userServiceError{code: UPDATE_USER_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			tc.setupMock(repo)
+
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{})
+
+			_, err := service.PatchUser(testActor, userID, tc.inputParam, nil)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
 func TestServiceDeleteUser(t *testing.T) {
 
 	const userID string = "64260e1da4c0c814bda5734a"
@@ -345,8 +802,12 @@ func TestServiceDeleteUser(t *testing.T) {
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					DeleteUser(gomock.Any()).
-					Return(nil)
+					FindUserByID(userID, gomock.Any()).
+					Return(&User{ID: userID, Version: 2}, nil)
+				repository.
+					EXPECT().
+					DeleteUser(testActor, userID, Precondition{2}).
+					Return(&UserEvent{Version: 3}, nil)
 			},
 			inputParam:    userID,
 			expectedError: nil,
@@ -356,19 +817,34 @@ func TestServiceDeleteUser(t *testing.T) {
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					DeleteUser(gomock.Any()).
-					Return(fmt.Errorf(INVALID_OBJECT_ID))
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(INVALID_OBJECT_ID))
 			},
 			inputParam:    "any id invalid",
 			expectedError: &userServiceError{code: USER_ID_INVALID},
 		},
+		{
+			name: "user not exists",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			inputParam:    userID,
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
 		{
 			name: "delete user fail",
 			setupMock: func(repository *MockUserRepository) {
 				repository.
 					EXPECT().
-					DeleteUser(gomock.Any()).
-					Return(fmt.Errorf("Any error"))
+					FindUserByID(gomock.Any(), gomock.Any()).
+					Return(&User{ID: userID, Version: 2}, nil)
+				repository.
+					EXPECT().
+					DeleteUser(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any error"))
 			},
 			inputParam:    userID,
 			expectedError: &userServiceError{code: DELETE_USER_FAILED},
@@ -381,9 +857,14 @@ func TestServiceDeleteUser(t *testing.T) {
 			repo := NewMockUserRepository(ctrl)
 			tc.setupMock(repo)
 
-			service := NewUserService(repo)
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
 
-			err := service.DeleteUser(tc.inputParam)
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{})
+
+			err := service.DeleteUser(testActor, tc.inputParam, nil)
 
 			if err != nil && err.Error() != tc.expectedError.Error() {
 				t.Errorf("Expecting error %d , but returns %d", tc.expectedError, err)
@@ -391,3 +872,302 @@ func TestServiceDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceListUsers(t *testing.T) {
+
+	var users []User = []User{
+		{ID: "64260e1da4c0c814bda5734a", Email: "one@test.com"},
+		{ID: "64260e1da4c0c814bda5734b", Email: "two@test.com"},
+		{ID: "64260e1da4c0c814bda5734c", Email: "three@test.com"},
+	}
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockUserRepository)
+		inputParam       ListQuery
+		expectedResponse *UserPage
+		expectedError    error
+	}{
+		{
+			name: "list last page",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(ListCriteria{}, 4, gomock.Any()).
+					Return(users, nil)
+			},
+			inputParam:       ListQuery{Limit: 3},
+			expectedResponse: &UserPage{Users: users},
+			expectedError:    nil,
+		},
+		{
+			name: "list page with next token",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(ListCriteria{}, 3, gomock.Any()).
+					Return(users, nil)
+			},
+			inputParam:       ListQuery{Limit: 2},
+			expectedResponse: &UserPage{Users: users[:2], Next: encodeCursor(pageCursor{ID: users[1].ID})},
+			expectedError:    nil,
+		},
+		{
+			name: "list page after token",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(ListCriteria{AfterID: users[1].ID}, 3, gomock.Any()).
+					Return(users[2:], nil)
+			},
+			inputParam:       ListQuery{Limit: 2, Next: encodeCursor(pageCursor{ID: users[1].ID})},
+			expectedResponse: &UserPage{Users: users[2:]},
+			expectedError:    nil,
+		},
+		{
+			name: "page size capped",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(ListCriteria{}, 11, gomock.Any()).
+					Return(users, nil)
+			},
+			inputParam:       ListQuery{Limit: 1000},
+			expectedResponse: &UserPage{Users: users},
+			expectedError:    nil,
+		},
+		{
+			name: "list page sorted and filtered",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(ListCriteria{
+						Filters: []FilterCondition{{Field: "address.city", Operator: FILTER_EQUALS, Value: "SP"}},
+						Sort:    []SortField{{Field: "email", Descending: true}},
+					}, 3, gomock.Any()).
+					Return(users, nil)
+			},
+			inputParam: ListQuery{
+				Limit:   2,
+				Filters: []FilterCondition{{Field: "address.city", Operator: FILTER_EQUALS, Value: "SP"}},
+				Sort:    []SortField{{Field: "email", Descending: true}},
+			},
+			expectedResponse: &UserPage{
+				Users: users[:2],
+				Next:  encodeCursor(pageCursor{ID: users[1].ID, Sort: "-email", Values: []*string{&users[1].Email}}),
+			},
+			expectedError: nil,
+		},
+		{
+			name:             "page token from another sort",
+			setupMock:        func(repository *MockUserRepository) {},
+			inputParam:       ListQuery{Next: encodeCursor(pageCursor{ID: users[1].ID, Sort: "-email"})},
+			expectedResponse: nil,
+			expectedError:    &userServiceError{code: PAGE_TOKEN_INVALID},
+		},
+		{
+			name:             "invalid page token",
+			setupMock:        func(repository *MockUserRepository) {},
+			inputParam:       ListQuery{Next: "not a token"},
+			expectedResponse: nil,
+			expectedError:    &userServiceError{code: PAGE_TOKEN_INVALID},
+		},
+		{
+			name: "list users fail",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					ListUsers(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any Error"))
+			},
+			inputParam:       ListQuery{},
+			expectedResponse: nil,
+			expectedError:    &userServiceError{code: LIST_USERS_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			tc.setupMock(repo)
+
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{PageSize: 5, MaxPageSize: 10})
+
+			result, err := service.ListUsers(tc.inputParam)
+
+			if err != nil && err.Error() != tc.expectedError.Error() {
+				t.Errorf("Expecting error %d , but returns %d", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(result, tc.expectedResponse) {
+				t.Errorf("Expecting body %v , but returns %v", tc.expectedResponse, result)
+			}
+		})
+	}
+}
+
+func TestServiceSetRoles(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name          string
+		setupMock     func(service *MockUserRepository)
+		inputParam    []string
+		expectedError error
+	}{
+		{
+			name: "set roles success",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), userID, UserChanges{Set: Projection{{Key: "roles", Value: []string{ROLE_SUPPORT}}}}, nil).
+					Return(&UserEvent{Version: 2}, nil)
+			},
+			inputParam:    []string{ROLE_SUPPORT},
+			expectedError: nil,
+		},
+		{
+			name:          "unknown role",
+			setupMock:     func(repository *MockUserRepository) {},
+			inputParam:    []string{"root"},
+			expectedError: &userServiceError{code: ROLES_INVALID},
+		},
+		{
+			name:          "no roles",
+			setupMock:     func(repository *MockUserRepository) {},
+			inputParam:    []string{},
+			expectedError: &userServiceError{code: ROLES_INVALID},
+		},
+		{
+			name: "user not exists",
+			setupMock: func(repository *MockUserRepository) {
+				repository.
+					EXPECT().
+					PatchUser(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			inputParam:    []string{ROLE_ADMIN},
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			tc.setupMock(repo)
+
+			verifications := NewMockVerificationRepository(ctrl)
+			m := mailer.NewMockMailer(ctrl)
+			verifications.EXPECT().InsertVerification(gomock.Any()).Return(nil).AnyTimes()
+			m.EXPECT().Send(gomock.Any()).Return(nil).AnyTimes()
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{})
+
+			err := service.SetRoles(testActor, userID, tc.inputParam)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceRestoreUser(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	tests := []struct {
+		name            string
+		setupMock       func(repository *MockUserRepository)
+		expectedVersion int64
+		expectedError   error
+	}{
+		{
+			name: "restore user success",
+			setupMock: func(repository *MockUserRepository) {
+				repository.EXPECT().RestoreUser(testActor, userID).Return(&UserEvent{Version: 3}, nil)
+			},
+			expectedVersion: 3,
+			expectedError:   nil,
+		},
+		{
+			name: "user not deleted",
+			setupMock: func(repository *MockUserRepository) {
+				repository.EXPECT().RestoreUser(testActor, userID).Return(nil, fmt.Errorf(NOT_DELETED))
+			},
+			expectedError: &userServiceError{code: USER_NOT_DELETED},
+		},
+		{
+			name: "user purged",
+			setupMock: func(repository *MockUserRepository) {
+				repository.EXPECT().RestoreUser(testActor, userID).Return(nil, fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			expectedError: &userServiceError{code: USER_NOT_EXISTS},
+		},
+		{
+			name: "email taken while deleted",
+			setupMock: func(repository *MockUserRepository) {
+				repository.EXPECT().RestoreUser(testActor, userID).Return(nil, fmt.Errorf(DUPLICATE_EMAIL))
+			},
+			expectedError: &userServiceError{code: USER_EXISTS},
+		},
+		{
+			name: "restore user fail",
+			setupMock: func(repository *MockUserRepository) {
+				repository.EXPECT().RestoreUser(testActor, userID).Return(nil, fmt.Errorf("Any error"))
+			},
+			expectedError: &userServiceError{code: RESTORE_USER_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			tc.setupMock(repo)
+
+			service := NewUserService(repo, NewMockVerificationRepository(ctrl), NewMockAuditRepository(ctrl), nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{})
+
+			version, err := service.RestoreUser(testActor, userID)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if version != tc.expectedVersion {
+				t.Errorf("Expecting version %d , but returns %d", tc.expectedVersion, version)
+			}
+		})
+	}
+}
+
+func TestServicePurgeDeletedUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := NewMockUserRepository(ctrl)
+
+	retention := 24 * time.Hour
+	repo.
+		EXPECT().
+		PurgeUsers(Actor{ID: PURGER}, gomock.Any()).
+		DoAndReturn(func(actor Actor, deletedBefore time.Time) (int64, error) {
+			if age := time.Since(deletedBefore); age < retention || age > retention+time.Minute {
+				t.Errorf("Expecting users deleted before %v , but purges users deleted before %v", retention, age)
+			}
+			return 2, nil
+		})
+
+	service := NewUserService(repo, NewMockVerificationRepository(ctrl), NewMockAuditRepository(ctrl), nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{DeletedRetention: retention})
+
+	purged, err := service.PurgeDeletedUsers()
+	if err != nil || purged != 2 {
+		t.Errorf("Expecting 2 users purged , but returns %d with error %v", purged, err)
+	}
+}
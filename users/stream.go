@@ -0,0 +1,201 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Events a stream may fall behind before it is closed
+const streamBuffer int = 64
+
+// Comment sent on idle streams so proxies keep them open
+const streamKeepAlive time.Duration = 15 * time.Second
+
+/*
+Fans user events out to the event streams of this instance, keeping the last
+events published so streams can resume after the last event they received.
+*/
+type EventBus struct {
+	mutex   sync.Mutex
+	size    int
+	history []UserEvent
+	streams map[chan UserEvent]struct{}
+}
+
+// Returns an EventBus keeping the last size events
+func NewEventBus(size int) *EventBus {
+	return &EventBus{
+		size:    size,
+		history: make([]UserEvent, 0, size),
+		streams: map[chan UserEvent]struct{}{},
+	}
+}
+
+func (b *EventBus) Publish(event UserEvent) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	for stream := range b.streams {
+		select {
+		case stream <- event:
+		default:
+			// Streams falling behind are closed, their clients resume from the last event received
+			delete(b.streams, stream)
+			close(stream)
+		}
+	}
+	return nil
+}
+
+/*
+Returns the events held published after lastEventID, the channel receiving the
+events published from now on and the function ending the subscription.
+
+No event is returned for an empty lastEventID, and every event held when it is
+no longer held. The channel is closed when the stream falls behind.
+*/
+func (b *EventBus) Subscribe(lastEventID string) ([]UserEvent, <-chan UserEvent, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	missed := []UserEvent{}
+	if lastEventID != "" {
+		start := 0
+		for i, event := range b.history {
+			if event.ID == lastEventID {
+				start = i + 1
+			}
+		}
+		missed = append(missed, b.history[start:]...)
+	}
+
+	stream := make(chan UserEvent, streamBuffer)
+	b.streams[stream] = struct{}{}
+	cancel := func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if _, ok := b.streams[stream]; ok {
+			delete(b.streams, stream)
+			close(stream)
+		}
+	}
+	return missed, stream, cancel
+}
+
+/*
+Feeds publisher with the events written to the outbox by every instance, read from
+a MongoDB change stream, until ctx is done. Change streams require a replica set,
+the stream is opened again after interval when it fails.
+*/
+func RunChangeStream(ctx context.Context, outbox OutboxRepository, publisher Publisher, interval time.Duration) {
+	for {
+		if err := outbox.WatchEvents(ctx, publisher); err != nil {
+			fmt.Println(fmt.Errorf("Error on WatchEvents : %v", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Controller streaming user events as server-sent events
+type EventController struct {
+	bus *EventBus
+}
+
+// Returns new EventController instance
+func NewEventController(bus *EventBus) EventController {
+	return EventController{
+		bus: bus,
+	}
+}
+
+// StreamEvents godoc
+//
+//	@Summary		Stream user changes
+//	@Description	This endpoint streams the users created, updated and deleted as server-sent events, named by the event type with the event id.
+//	@Description	Streams resume after the Last-Event-ID header while the event is held, every event held is sent again otherwise.
+//	@Tags			users
+//	@Produce		text/event-stream
+//	@Param			userId			query		string	false	"user ID"
+//	@Param			Last-Event-ID	header		string	false	"last event received"
+//	@Success		200				{object}	UserEvent
+//	@Failure		401
+//	@Failure		403				{object}	UserResponse
+//	@Failure		400				{object}	UserResponse
+//	@Router			/users/events [get]
+func (ctr EventController) StreamEvents(c *gin.Context) {
+	userID := c.Query("userId")
+	if userID != "" && !primitive.IsValidObjectID(userID) {
+		c.JSON(400, INVALID_USER_ID)
+		return
+	}
+
+	missed, events, cancel := ctr.bus.Subscribe(c.GetHeader("Last-Event-ID"))
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Proxies buffering responses would hold the events back
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+
+	for _, event := range missed {
+		if err := sendEvent(c.Writer, event, userID); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			if err := sendEvent(c.Writer, event, userID); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// Writes the event as a server-sent event, unless it is not about the user of a stream filtered by userID
+func sendEvent(writer io.Writer, event UserEvent, userID string) error {
+	if userID != "" && event.UserID != userID {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,168 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const streamUserID string = "64260e1da4c0c814bda5734a"
+const otherUserID string = "64260e1da4c0c814bda5734b"
+
+var streamEvents []UserEvent = []UserEvent{
+	{ID: "event-1", Type: EVENT_USER_CREATED, UserID: streamUserID, Version: 1},
+	{ID: "event-2", Type: EVENT_USER_CREATED, UserID: otherUserID, Version: 1},
+	{ID: "event-3", Type: EVENT_USER_DELETED, UserID: streamUserID, Version: 2},
+}
+
+func TestEventBusSubscribe(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		inputLastEventID string
+		expectedMissed   []UserEvent
+	}{
+		{
+			name:             "new stream",
+			inputLastEventID: "",
+			expectedMissed:   []UserEvent{},
+		},
+		{
+			name:             "resumed stream",
+			inputLastEventID: "event-2",
+			expectedMissed:   streamEvents[2:],
+		},
+		{
+			name:             "resumed after the last event",
+			inputLastEventID: "event-3",
+			expectedMissed:   []UserEvent{},
+		},
+		{
+			name:             "last event no longer held",
+			inputLastEventID: "event-1",
+			expectedMissed:   streamEvents[1:],
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			bus := NewEventBus(2)
+			for _, event := range streamEvents {
+				bus.Publish(event)
+			}
+
+			missed, events, cancel := bus.Subscribe(tc.inputLastEventID)
+			defer cancel()
+
+			if !reflect.DeepEqual(missed, tc.expectedMissed) {
+				t.Errorf("Expecting missed events %v , but returns %v", tc.expectedMissed, missed)
+			}
+
+			bus.Publish(streamEvents[0])
+			if event := <-events; !reflect.DeepEqual(event, streamEvents[0]) {
+				t.Errorf("Expecting event %v , but returns %v", streamEvents[0], event)
+			}
+		})
+	}
+}
+
+func TestEventBusClosesStreamsBehind(t *testing.T) {
+	bus := NewEventBus(1)
+	_, events, cancel := bus.Subscribe("")
+	defer cancel()
+
+	for i := 0; i <= streamBuffer; i++ {
+		bus.Publish(UserEvent{ID: fmt.Sprintf("event-%d", i)})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	if received != streamBuffer {
+		t.Errorf("Expecting events %v before the stream is closed , but returns %v", streamBuffer, received)
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		inputQuery       string
+		inputLastEventID string
+		expectedStatus   int
+		expectedResponse string
+	}{
+		{
+			name:             "new stream",
+			inputQuery:       "",
+			inputLastEventID: "",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "",
+		},
+		{
+			name:             "resumed stream",
+			inputQuery:       "",
+			inputLastEventID: "event-1",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "id: event-2\nevent: user.created\n" +
+				`data: {"id":"event-2","type":"user.created","userId":"64260e1da4c0c814bda5734b","version":1,"actor":"","timestamp":"0001-01-01T00:00:00Z"}` + "\n\n" +
+				"id: event-3\nevent: user.deleted\n" +
+				`data: {"id":"event-3","type":"user.deleted","userId":"64260e1da4c0c814bda5734a","version":2,"actor":"","timestamp":"0001-01-01T00:00:00Z"}` + "\n\n",
+		},
+		{
+			name:             "stream filtered by user",
+			inputQuery:       "?userId=" + otherUserID,
+			inputLastEventID: "any event",
+			expectedStatus:   http.StatusOK,
+			expectedResponse: "id: event-2\nevent: user.created\n" +
+				`data: {"id":"event-2","type":"user.created","userId":"64260e1da4c0c814bda5734b","version":1,"actor":"","timestamp":"0001-01-01T00:00:00Z"}` + "\n\n",
+		},
+		{
+			name:             "invalid user id",
+			inputQuery:       "?userId=any",
+			inputLastEventID: "",
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid User ID","code":"INVALID_USER_ID"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			bus := NewEventBus(10)
+			for _, event := range streamEvents {
+				bus.Publish(event)
+			}
+
+			controller := NewEventController(bus)
+			r := gin.Default()
+			r.GET("/api/v1/users/events", controller.StreamEvents)
+
+			// The client is gone once the missed events are sent, ending the stream
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/users/events"+tc.inputQuery, nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			if tc.inputLastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.inputLastEventID)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
@@ -1,5 +1,14 @@
 package users
 
+import (
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
 type ValidationResponse struct {
 	Message string `json:"message"`
 	Code    string `json:"code"`
@@ -9,14 +18,261 @@ func (r ValidationResponse) Error() string {
 	return r.Code
 }
 
-var EMAIL_REQUIRED *ValidationResponse = &ValidationResponse{
+// Validation failure of a single field, fields are named as in UserAccess
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// All validation failures of a user, returned with status 422
+type ValidationErrors struct {
+	Message string       `json:"message"`
+	Code    string       `json:"code"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func (v *ValidationErrors) Error() string {
+	return v.Code
+}
+
+// Returns the errors of the given fields only, nil when none remains
+func (v *ValidationErrors) forFields(fields map[string]bool) *ValidationErrors {
+	if v == nil {
+		return nil
+	}
+	errors := make([]FieldError, 0)
+	for _, err := range v.Errors {
+		if fields[err.Field] {
+			errors = append(errors, err)
+		}
+	}
+	return NewValidationErrors(errors)
+}
+
+// Returns the failures as ValidationErrors, nil when there is none
+func NewValidationErrors(errors []FieldError) *ValidationErrors {
+	if len(errors) == 0 {
+		return nil
+	}
+	return &ValidationErrors{
+		Message: "Invalid User Data",
+		Code:    "VALIDATION_FAILED",
+		Errors:  errors,
+	}
+}
+
+var EMAIL_REQUIRED *FieldError = &FieldError{
+	Field:   "email",
 	Code:    "EMAIL_REQUIRED",
 	Message: "Email Required",
 }
 
-func ValidateUser(user User) *ValidationResponse {
+var EMAIL_INVALID *FieldError = &FieldError{
+	Field:   "email",
+	Code:    "EMAIL_INVALID",
+	Message: "Email Must Be A Valid Address",
+}
+
+var NAME_INVALID *FieldError = &FieldError{
+	Field:   "name",
+	Code:    "NAME_INVALID",
+	Message: "Name Must Have 2 To 100 Characters",
+}
+
+var AGE_INVALID *FieldError = &FieldError{
+	Field:   "age",
+	Code:    "AGE_INVALID",
+	Message: "Age Must Be A Number From 0 To 150",
+}
+
+var AGE_DEPRECATED *FieldError = &FieldError{
+	Field:   "age",
+	Code:    "AGE_DEPRECATED",
+	Message: "Age Is No Longer Accepted, Inform The Birth Date",
+}
+
+var BIRTH_DATE_INVALID *FieldError = &FieldError{
+	Field:   "birthDate",
+	Code:    "BIRTH_DATE_INVALID",
+	Message: "Birth Date Must Be A Past Date In The Format YYYY-MM-DD",
+}
+
+var COUNTRY_INVALID *FieldError = &FieldError{
+	Field:   "address.country",
+	Code:    "COUNTRY_INVALID",
+	Message: "Country Must Be An ISO 3166-1 Alpha-2 Code",
+}
+
+var STATE_INVALID *FieldError = &FieldError{
+	Field:   "address.state",
+	Code:    "STATE_INVALID",
+	Message: "State Must Be An ISO 3166-2 Subdivision Code Of The Country",
+}
+
+var ZIP_INVALID *FieldError = &FieldError{
+	Field:   "address.zip",
+	Code:    "ZIP_INVALID",
+	Message: "ZIP Must Follow The Postal Code Format Of The Country",
+}
+
+var PASSWORD_REQUIRED *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_REQUIRED",
+	Message: "Password Required",
+}
+
+var PASSWORD_TOO_SHORT *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_TOO_SHORT",
+	Message: "Password Too Short",
+}
+
+var PASSWORD_TOO_LONG *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_TOO_LONG",
+	Message: "Password Too Long",
+}
+
+var PASSWORD_TOO_WEAK *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_TOO_WEAK",
+	Message: "Password Must Mix Lower Case, Upper Case, Digits And Symbols",
+}
+
+var PASSWORD_MATCHES_USER *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_MATCHES_USER",
+	Message: "Password Must Differ From Email And Name",
+}
+
+var PASSWORD_BANNED *FieldError = &FieldError{
+	Field:   "password",
+	Code:    "PASSWORD_BANNED",
+	Message: "Password Too Common",
+}
+
+// Checks a field of the user, returning its failure or nil
+type FieldRule func(user User) *FieldError
+
+// Rules checked on every user, in the order errors are returned
+var userRules = []FieldRule{
+	validateEmail,
+	validateName,
+	validateAge,
+	validateBirthDate,
+	validateCountry,
+	validateState,
+	validateZIP,
+}
+
+// Validates all user fields and the given extra rules, along with its password when informed
+func ValidateUser(user User, passwords PasswordPolicy, rules ...FieldRule) *ValidationErrors {
+	return validateUser(user, passwords, false, rules)
+}
+
+// Validates all fields of a user being created, whose password is required
+func ValidateNewUser(user User, passwords PasswordPolicy, rules ...FieldRule) *ValidationErrors {
+	return validateUser(user, passwords, true, rules)
+}
+
+func validateUser(user User, passwords PasswordPolicy, passwordRequired bool, rules []FieldRule) *ValidationErrors {
+	errors := make([]FieldError, 0)
+	for _, rule := range append(append([]FieldRule{}, userRules...), rules...) {
+		if err := rule(user); err != nil {
+			errors = append(errors, *err)
+		}
+	}
+
+	if user.Password != "" || passwordRequired {
+		if err := passwords.Validate(user.Password, user); err != nil {
+			errors = append(errors, *err)
+		}
+	}
+	return NewValidationErrors(errors)
+}
+
+// Email must be an RFC 5322 address, without display name
+func validateEmail(user User) *FieldError {
 	if user.Email == "" {
 		return EMAIL_REQUIRED
 	}
+	address, err := mail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email || address.Name != "" || len(user.Email) > 254 {
+		return EMAIL_INVALID
+	}
+	return nil
+}
+
+// Name is optional, when informed it has 2 to 100 printable characters
+func validateName(user User) *FieldError {
+	if user.Name == "" {
+		return nil
+	}
+	length := utf8.RuneCountInString(strings.TrimSpace(user.Name))
+	if length < 2 || length > 100 || strings.IndexFunc(user.Name, unicode.IsControl) >= 0 {
+		return NAME_INVALID
+	}
+	return nil
+}
+
+func validateAge(user User) *FieldError {
+	if user.Age == "" {
+		return nil
+	}
+	age, err := strconv.Atoi(user.Age)
+	if err != nil || age < 0 || age > MAX_AGE {
+		return AGE_INVALID
+	}
+	return nil
+}
+
+func validateBirthDate(user User) *FieldError {
+	if user.BirthDate == "" {
+		return nil
+	}
+	birthDate, ok := parseBirthDate(user.BirthDate)
+	now := time.Now().UTC()
+	if !ok || birthDate.After(now) || ageAt(birthDate, now) > MAX_AGE {
+		return BIRTH_DATE_INVALID
+	}
+	return nil
+}
+
+// Rejects a legacy age once its deprecation is over, ages are then computed from the birth date only
+func rejectLegacyAge(user User) *FieldError {
+	if user.Age != "" {
+		return AGE_DEPRECATED
+	}
+	return nil
+}
+
+func validateCountry(user User) *FieldError {
+	if user.Address.Country != "" && !countryCodes[user.Address.Country] {
+		return COUNTRY_INVALID
+	}
+	return nil
+}
+
+// State is checked against the country subdivisions, only when the country is valid
+func validateState(user User) *FieldError {
+	country := user.Address.Country
+	if user.Address.State == "" || !countryCodes[country] {
+		return nil
+	}
+	if !validSubdivision(country, user.Address.State) {
+		return STATE_INVALID
+	}
+	return nil
+}
+
+// ZIP is checked against the country postal code format, or a generic format without country
+func validateZIP(user User) *FieldError {
+	if user.Address.ZIP == "" {
+		return nil
+	}
+	if !validZIP(user.Address.Country, user.Address.ZIP) {
+		return ZIP_INVALID
+	}
 	return nil
 }
@@ -0,0 +1,183 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateUser(t *testing.T) {
+
+	passwords := PasswordPolicy{MinLength: 8, Classes: 2}
+
+	var user User = User{
+		Address: Address{
+			City:    "SP",
+			Country: "BR",
+			Number:  "111",
+			State:   "SP",
+			Street:  "Rua hum",
+			ZIP:     "12345-678",
+		},
+		Age:   "33",
+		Email: "test@test.com",
+		Name:  "Test",
+	}
+
+	tests := []struct {
+		name           string
+		inputParam     func(user User) User
+		expectedErrors []FieldError
+	}{
+		{
+			name:           "valid user",
+			inputParam:     func(user User) User { return user },
+			expectedErrors: nil,
+		},
+		{
+			name: "valid user without optional fields",
+			inputParam: func(user User) User {
+				return User{Email: user.Email}
+			},
+			expectedErrors: nil,
+		},
+		{
+			name: "state with country prefix",
+			inputParam: func(user User) User {
+				user.Address.State = "BR-RJ"
+				return user
+			},
+			expectedErrors: nil,
+		},
+		{
+			name: "email required",
+			inputParam: func(user User) User {
+				user.Email = ""
+				return user
+			},
+			expectedErrors: []FieldError{*EMAIL_REQUIRED},
+		},
+		{
+			name: "email without domain",
+			inputParam: func(user User) User {
+				user.Email = "test@"
+				return user
+			},
+			expectedErrors: []FieldError{*EMAIL_INVALID},
+		},
+		{
+			name: "email with display name",
+			inputParam: func(user User) User {
+				user.Email = "Test <test@test.com>"
+				return user
+			},
+			expectedErrors: []FieldError{*EMAIL_INVALID},
+		},
+		{
+			name: "age out of range",
+			inputParam: func(user User) User {
+				user.Age = "151"
+				return user
+			},
+			expectedErrors: []FieldError{*AGE_INVALID},
+		},
+		{
+			name: "birth date in the future",
+			inputParam: func(user User) User {
+				user.BirthDate = time.Now().AddDate(1, 0, 0).Format(BIRTH_DATE_LAYOUT)
+				return user
+			},
+			expectedErrors: []FieldError{*BIRTH_DATE_INVALID},
+		},
+		{
+			name: "birth date out of ISO format",
+			inputParam: func(user User) User {
+				user.BirthDate = "20/05/1990"
+				return user
+			},
+			expectedErrors: []FieldError{*BIRTH_DATE_INVALID},
+		},
+		{
+			name: "zip out of country format",
+			inputParam: func(user User) User {
+				user.Address.ZIP = "1234"
+				return user
+			},
+			expectedErrors: []FieldError{*ZIP_INVALID},
+		},
+		{
+			name: "state of another country",
+			inputParam: func(user User) User {
+				user.Address.State = "CA"
+				return user
+			},
+			expectedErrors: []FieldError{*STATE_INVALID},
+		},
+		{
+			name: "unknown country",
+			inputParam: func(user User) User {
+				user.Address.Country = "XX"
+				return user
+			},
+			expectedErrors: []FieldError{*COUNTRY_INVALID},
+		},
+		{
+			name: "all errors at once",
+			inputParam: func(user User) User {
+				user.Email = "test.com"
+				user.Name = "T"
+				user.Age = "thirty"
+				user.Address.State = "ZZ"
+				user.Address.ZIP = "ABC"
+				user.Password = "short"
+				return user
+			},
+			expectedErrors: []FieldError{*EMAIL_INVALID, *NAME_INVALID, *AGE_INVALID, *STATE_INVALID, *ZIP_INVALID, *PASSWORD_TOO_SHORT},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			validation := ValidateUser(tc.inputParam(user), passwords)
+
+			if !reflect.DeepEqual(validation, NewValidationErrors(tc.expectedErrors)) {
+				t.Errorf("Expecting errors %v , but returns %v", tc.expectedErrors, validation)
+			}
+		})
+	}
+}
+
+func TestValidateNewUser(t *testing.T) {
+	validation := ValidateNewUser(User{Email: "test@test.com"}, PasswordPolicy{MinLength: 8})
+
+	expected := NewValidationErrors([]FieldError{*PASSWORD_REQUIRED})
+	if !reflect.DeepEqual(validation, expected) {
+		t.Errorf("Expecting errors %v , but returns %v", expected, validation)
+	}
+}
+
+func TestValidateUserRules(t *testing.T) {
+	validation := ValidateUser(User{Email: "test@test.com", Age: "33"}, PasswordPolicy{}, rejectLegacyAge)
+
+	expected := NewValidationErrors([]FieldError{*AGE_DEPRECATED})
+	if !reflect.DeepEqual(validation, expected) {
+		t.Errorf("Expecting errors %v , but returns %v", expected, validation)
+	}
+}
+
+func TestValidationErrorsForFields(t *testing.T) {
+	validation := NewValidationErrors([]FieldError{*NAME_INVALID, *ZIP_INVALID})
+
+	changes := UserChanges{Set: Projection{{Key: "address.country", Value: "US"}}}
+	filtered := validation.forFields(changes.fields())
+
+	expected := NewValidationErrors([]FieldError{*ZIP_INVALID})
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expecting errors %v , but returns %v", expected, filtered)
+	}
+
+	changes = UserChanges{Set: Projection{{Key: "age", Value: "40"}}}
+	if filtered := validation.forFields(changes.fields()); filtered != nil {
+		t.Errorf("Expecting no errors , but returns %v", filtered)
+	}
+}
@@ -0,0 +1,99 @@
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"userapi/mailer"
+)
+
+// Value of REQUIRE_VERIFIED_EMAIL refusing login to users with unverified email
+const VERIFIED_FOR_LOGIN string = "login"
+
+// Email verification token as stored, only the token hash is persisted.
+// It verifies the email it was sent to, not a later email of the user.
+type EmailVerification struct {
+	ID        string     `bson:"_id,omitempty"`
+	TokenHash string     `bson:"tokenHash"`
+	UserID    string     `bson:"userId"`
+	Email     string     `bson:"email"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	UsedAt    *time.Time `bson:"usedAt,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// Tells if REQUIRE_VERIFIED_EMAIL lists the value, either login or a permission over the own user
+func RequiresVerifiedEmail(required []string, value string) bool {
+	return hasPermission(required, value)
+}
+
+func (svc *userService) VerifyEmail(userID string, token string) error {
+	sum := sha256.Sum256([]byte(token))
+	verification, err := svc.verifications.ConsumeVerification(userID, hex.EncodeToString(sum[:]))
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ConsumeVerification : %v", err))
+		return &userServiceError{code: VERIFY_EMAIL_FAILED}
+	}
+
+	if verification == nil {
+		fmt.Println(fmt.Errorf("Invalid verification token"))
+		return &userServiceError{code: VERIFICATION_TOKEN_INVALID}
+	}
+
+	// The token proves the user is the one verifying
+	actor := Actor{ID: userID}
+	verifiedAt := time.Now().UTC()
+	event, err := svc.repo.VerifyEmail(actor, userID, verification.Email, verifiedAt)
+	if err != nil {
+		if err.Error() == DOCUMENT_NOT_FOUND {
+			// The user was deleted or changed the email after the token was sent
+			fmt.Println(fmt.Errorf("Verification token email is not the user email"))
+			return &userServiceError{code: VERIFICATION_TOKEN_INVALID}
+		}
+		return svc.writeError("VerifyEmail", err, VERIFY_EMAIL_FAILED)
+	}
+	svc.publish(event)
+	return nil
+}
+
+// Emails a verification token for the user email.
+// Failures are only logged, the user change was already stored.
+func (svc *userService) sendVerification(userID string, email string) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		fmt.Println(fmt.Errorf("Error on rand.Read : %v", err))
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(bytes)
+	sum := sha256.Sum256([]byte(token))
+
+	now := time.Now().UTC()
+	err := svc.verifications.InsertVerification(EmailVerification{
+		TokenHash: hex.EncodeToString(sum[:]),
+		UserID:    userID,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(svc.config.VerificationTokenTTL),
+	})
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on InsertVerification : %v", err))
+		return
+	}
+
+	body := fmt.Sprintf("Use this token to verify your email: %s\n", token)
+	if svc.config.VerificationURL != "" {
+		body = fmt.Sprintf("Open this link to verify your email: %s?user=%s&token=%s\n", svc.config.VerificationURL, userID, token)
+	}
+	body += fmt.Sprintf("\nIt expires in %s.", svc.config.VerificationTokenTTL)
+
+	if err := svc.mailer.Send(mailer.Message{To: email, Subject: "Verify your email", Body: body}); err != nil {
+		fmt.Println(fmt.Errorf("Error on Send : %v", err))
+	}
+}
@@ -0,0 +1,148 @@
+package users
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/mailer"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestServiceVerifyEmail(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+	const token string = "verification-token"
+	sum := sha256.Sum256([]byte(token))
+
+	tests := []struct {
+		name          string
+		setupMock     func(repository *MockUserRepository, verifications *MockVerificationRepository)
+		expectedError error
+	}{
+		{
+			name: "email verified",
+			setupMock: func(repository *MockUserRepository, verifications *MockVerificationRepository) {
+				verifications.
+					EXPECT().
+					ConsumeVerification(userID, hex.EncodeToString(sum[:])).
+					Return(&EmailVerification{UserID: userID, Email: "test@test.com"}, nil)
+				repository.
+					EXPECT().
+					VerifyEmail(Actor{ID: userID}, userID, "test@test.com", gomock.Any()).
+					Return(&UserEvent{Version: 2}, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name: "used or expired token",
+			setupMock: func(repository *MockUserRepository, verifications *MockVerificationRepository) {
+				verifications.
+					EXPECT().
+					ConsumeVerification(gomock.Any(), gomock.Any()).
+					Return(nil, nil)
+			},
+			expectedError: &userServiceError{code: VERIFICATION_TOKEN_INVALID},
+		},
+		{
+			name: "email changed after token sent",
+			setupMock: func(repository *MockUserRepository, verifications *MockVerificationRepository) {
+				verifications.
+					EXPECT().
+					ConsumeVerification(gomock.Any(), gomock.Any()).
+					Return(&EmailVerification{UserID: userID, Email: "old@test.com"}, nil)
+				repository.
+					EXPECT().
+					VerifyEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			expectedError: &userServiceError{code: VERIFICATION_TOKEN_INVALID},
+		},
+		{
+			name: "user deleted after token sent",
+			setupMock: func(repository *MockUserRepository, verifications *MockVerificationRepository) {
+				verifications.
+					EXPECT().
+					ConsumeVerification(gomock.Any(), gomock.Any()).
+					Return(&EmailVerification{UserID: userID, Email: "test@test.com"}, nil)
+				repository.
+					EXPECT().
+					VerifyEmail(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf(DOCUMENT_NOT_FOUND))
+			},
+			expectedError: &userServiceError{code: VERIFICATION_TOKEN_INVALID},
+		},
+		{
+			name: "consume failed",
+			setupMock: func(repository *MockUserRepository, verifications *MockVerificationRepository) {
+				verifications.
+					EXPECT().
+					ConsumeVerification(gomock.Any(), gomock.Any()).
+					Return(nil, fmt.Errorf("Any Error"))
+			},
+			expectedError: &userServiceError{code: VERIFY_EMAIL_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			repo := NewMockUserRepository(ctrl)
+			verifications := NewMockVerificationRepository(ctrl)
+			tc.setupMock(repo, verifications)
+
+			service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, mailer.NewMockMailer(ctrl), PasswordPolicy{}, config.Config{})
+
+			err := service.VerifyEmail(userID, token)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestServiceCreateUserSendsVerification(t *testing.T) {
+
+	const userID string = "64260e1da4c0c814bda5734a"
+
+	ctrl := gomock.NewController(t)
+	repo := NewMockUserRepository(ctrl)
+	verifications := NewMockVerificationRepository(ctrl)
+	m := mailer.NewMockMailer(ctrl)
+
+	var tokenHash string
+	repo.EXPECT().FindUserByEmail(gomock.Any(), gomock.Any()).Return(nil, nil)
+	repo.EXPECT().InsertUser(gomock.Any(), gomock.Any()).Return(&UserEvent{UserID: userID, Version: 1}, nil)
+	verifications.
+		EXPECT().
+		InsertVerification(gomock.Any()).
+		DoAndReturn(func(verification EmailVerification) error {
+			tokenHash = verification.TokenHash
+			if verification.UserID != userID || verification.Email != "test@test.com" || !verification.ExpiresAt.After(verification.CreatedAt) {
+				t.Errorf("Expecting expiring verification of %s , but returns %v", userID, verification)
+			}
+			return nil
+		})
+	m.
+		EXPECT().
+		Send(gomock.Any()).
+		DoAndReturn(func(message mailer.Message) error {
+			token := strings.TrimSpace(strings.Split(strings.SplitN(message.Body, ": ", 2)[1], "\n")[0])
+			sum := sha256.Sum256([]byte(token))
+			if message.To != "test@test.com" || hex.EncodeToString(sum[:]) != tokenHash {
+				t.Errorf("Expecting mail with the stored token , but returns %v", message)
+			}
+			return nil
+		})
+
+	service := NewUserService(repo, verifications, NewMockAuditRepository(ctrl), nil, m, PasswordPolicy{}, config.Config{VerificationTokenTTL: time.Hour})
+
+	if _, err := service.CreateUser(testActor, User{Email: "test@test.com", Password: "12345"}); err != nil {
+		t.Errorf("Expecting no error , but returns %v", err)
+	}
+}
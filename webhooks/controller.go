@@ -0,0 +1,246 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"userapi/identity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Controller containing all webhook request handlers
+type WebhookController struct {
+	service WebhookService
+}
+
+// Returns new WebhookController instance
+func NewWebhookController(service WebhookService) WebhookController {
+	return WebhookController{
+		service: service,
+	}
+}
+
+// CreateSubscription godoc
+//
+//	@Summary		Subscribe to user events
+//	@Description	This endpoint subscribes an URL to user events (user.created, user.updated, user.deleted).
+//	@Description	Events are posted signed with the secret, which must have at least 16 characters and is never returned.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		SubscriptionRequest	true	"body"
+//	@Success		201		{object}	Subscription
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		400		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks [post]
+func (ctr WebhookController) CreateSubscription(c *gin.Context) {
+	var request SubscriptionRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil {
+		c.JSON(400, INVALID_SUBSCRIPTION_DATA)
+		return
+	}
+
+	principal, _ := identity.GetPrincipal(c)
+	subscription, err := ctr.service.CreateSubscription(request, principal.ID)
+	if err != nil {
+		if err.Error() == SUBSCRIPTION_DATA_INVALID {
+			c.JSON(400, INVALID_SUBSCRIPTION_DATA)
+			return
+		}
+		c.JSON(502, SUBSCRIPTION_CREATE_FAILED)
+		return
+	}
+
+	c.JSON(201, subscription)
+}
+
+// ListSubscriptions godoc
+//
+//	@Summary		List webhook subscriptions
+//	@Description	This endpoint returns all webhook subscriptions, without their secrets.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200		{object}	SubscriptionList
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks [get]
+func (ctr WebhookController) ListSubscriptions(c *gin.Context) {
+	list, err := ctr.service.ListSubscriptions()
+	if err != nil {
+		c.JSON(502, SUBSCRIPTION_LIST_FAILED)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// GetSubscription godoc
+//
+//	@Summary		Return webhook subscription data
+//	@Description	This endpoint returns a webhook subscription by id, without its secret.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"subscription ID"
+//	@Success		200		{object}	Subscription
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		400		{object}	WebhookResponse
+//	@Failure		404		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks/{id} [get]
+func (ctr WebhookController) GetSubscription(c *gin.Context) {
+	subscription, err := ctr.service.GetSubscription(c.Param("id"))
+	if err != nil {
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, SUBSCRIPTION_GET_FAILED)
+		return
+	}
+
+	c.JSON(200, subscription)
+}
+
+// UpdateSubscription godoc
+//
+//	@Summary		Update webhook subscription
+//	@Description	This endpoint replaces the URL and events of a webhook subscription by id.
+//	@Description	The secret is replaced when set and kept otherwise.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string				true	"subscription ID"
+//	@Param			request	body		SubscriptionRequest	true	"body"
+//	@Success		200		{object}	WebhookResponse
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		400		{object}	WebhookResponse
+//	@Failure		404		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks/{id} [put]
+func (ctr WebhookController) UpdateSubscription(c *gin.Context) {
+	var request SubscriptionRequest
+
+	err := json.NewDecoder(c.Request.Body).Decode(&request)
+	if err != nil {
+		c.JSON(400, INVALID_SUBSCRIPTION_DATA)
+		return
+	}
+
+	err = ctr.service.UpdateSubscription(c.Param("id"), request)
+	if err != nil {
+		if err.Error() == SUBSCRIPTION_DATA_INVALID {
+			c.JSON(400, INVALID_SUBSCRIPTION_DATA)
+			return
+		}
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, SUBSCRIPTION_UPDATE_FAILED)
+		return
+	}
+
+	c.JSON(200, SUBSCRIPTION_UPDATED)
+}
+
+// DeleteSubscription godoc
+//
+//	@Summary		Delete webhook subscription
+//	@Description	This endpoint deletes a webhook subscription by id, its deliveries not made are dropped.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"subscription ID"
+//	@Success		200		{object}	WebhookResponse
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		400		{object}	WebhookResponse
+//	@Failure		404		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks/{id} [delete]
+func (ctr WebhookController) DeleteSubscription(c *gin.Context) {
+	err := ctr.service.DeleteSubscription(c.Param("id"))
+	if err != nil {
+		if ctr.notFound(c, err) {
+			return
+		}
+		c.JSON(502, SUBSCRIPTION_DELETE_FAILED)
+		return
+	}
+
+	c.JSON(200, SUBSCRIPTION_DELETED)
+}
+
+// ListDeadLetters godoc
+//
+//	@Summary		List dead webhook deliveries
+//	@Description	This endpoint returns the deliveries that failed every attempt, newest first, with their last error.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200		{object}	DeliveryList
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks/dead-letters [get]
+func (ctr WebhookController) ListDeadLetters(c *gin.Context) {
+	list, err := ctr.service.ListDeadLetters()
+	if err != nil {
+		c.JSON(502, DEAD_LETTER_LIST_FAILED)
+		return
+	}
+
+	c.JSON(200, list)
+}
+
+// Redeliver godoc
+//
+//	@Summary		Redeliver dead webhook delivery
+//	@Description	This endpoint queues a dead delivery again by id, it is attempted right away as a new delivery.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string	true	"delivery ID"
+//	@Success		200		{object}	WebhookResponse
+//	@Failure		401
+//	@Failure		403		{object}	WebhookResponse
+//	@Failure		400		{object}	WebhookResponse
+//	@Failure		404		{object}	WebhookResponse
+//	@Failure		502		{object}	WebhookResponse
+//	@Router			/webhooks/dead-letters/{id}/redeliver [post]
+func (ctr WebhookController) Redeliver(c *gin.Context) {
+	err := ctr.service.Redeliver(c.Param("id"))
+	if err != nil {
+		if err.Error() == DELIVERY_ID_INVALID {
+			c.JSON(400, INVALID_DELIVERY_ID)
+			return
+		}
+		if err.Error() == DELIVERY_NOT_EXISTS {
+			c.JSON(404, DEAD_LETTER_NOT_FOUND)
+			return
+		}
+		c.JSON(502, REDELIVERY_FAILED)
+		return
+	}
+
+	c.JSON(200, REDELIVERY_QUEUED)
+}
+
+// Responds invalid id and not found errors, telling if the error was handled
+func (ctr WebhookController) notFound(c *gin.Context, err error) bool {
+	switch err.Error() {
+	case SUBSCRIPTION_ID_INVALID:
+		c.JSON(400, INVALID_SUBSCRIPTION_ID)
+		return true
+	case SUBSCRIPTION_NOT_EXISTS:
+		c.JSON(404, SUBSCRIPTION_NOT_FOUND)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package webhooks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+)
+
+func TestCreateSubscription(t *testing.T) {
+
+	createdAt := time.Date(2023, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockWebhookService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "create subscription success",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					CreateSubscription(SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: secret}, "").
+					Return(&Subscription{ID: subscriptionID, URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: secret, CreatedAt: createdAt}, nil)
+			},
+			inputBody:        fmt.Sprintf(`{"url": "https://crm.test/hooks", "events": ["user.created"], "secret": "%s"}`, secret),
+			expectedStatus:   http.StatusCreated,
+			expectedResponse: fmt.Sprintf(`{"id":"%s","url":"https://crm.test/hooks","events":["user.created"],"createdBy":"","createdAt":"2023-04-01T10:00:00Z"}`, subscriptionID),
+		},
+		{
+			name: "invalid subscription data",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					CreateSubscription(gomock.Any(), gomock.Any()).
+					Return(nil, &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID})
+			},
+			inputBody:        `{"url": "https://crm.test/hooks", "events": ["user.created"]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Subscription Data","code":"INVALID_SUBSCRIPTION_DATA"}`,
+		},
+		{
+			name:             "invalid json",
+			setupMock:        func(service *MockWebhookService) {},
+			inputBody:        `{"url": `,
+			expectedStatus:   http.StatusBadRequest,
+			expectedResponse: `{"message":"Invalid Subscription Data","code":"INVALID_SUBSCRIPTION_DATA"}`,
+		},
+		{
+			name: "create subscription failed",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					CreateSubscription(gomock.Any(), gomock.Any()).
+					Return(nil, &webhookServiceError{code: CREATE_SUBSCRIPTION_FAILED})
+			},
+			inputBody:        fmt.Sprintf(`{"url": "https://crm.test/hooks", "events": ["user.created"], "secret": "%s"}`, secret),
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Subscription Create Failed","code":"SUBSCRIPTION_CREATE_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockWebhookService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewWebhookController(svc)
+			r := gin.Default()
+			r.POST("/api/v1/webhooks", controller.CreateSubscription)
+
+			req, err := http.NewRequest(http.MethodPost, "/api/v1/webhooks", strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockWebhookService)
+		inputBody        string
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "update keeping secret",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					UpdateSubscription(subscriptionID, SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_UPDATED}}).
+					Return(nil)
+			},
+			inputBody:        `{"url": "https://crm.test/hooks", "events": ["user.updated"]}`,
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Subscription Updated","code":"SUBSCRIPTION_UPDATED"}`,
+		},
+		{
+			name: "subscription not found",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					UpdateSubscription(gomock.Any(), gomock.Any()).
+					Return(&webhookServiceError{code: SUBSCRIPTION_NOT_EXISTS})
+			},
+			inputBody:        `{"url": "https://crm.test/hooks", "events": ["user.updated"]}`,
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"Subscription Not Found","code":"SUBSCRIPTION_NOT_FOUND"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockWebhookService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewWebhookController(svc)
+			r := gin.Default()
+			r.PUT("/api/v1/webhooks/:id", controller.UpdateSubscription)
+
+			req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/webhooks/%s", subscriptionID), strings.NewReader(tc.inputBody))
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+
+	tests := []struct {
+		name             string
+		setupMock        func(service *MockWebhookService)
+		expectedResponse string
+		expectedStatus   int
+	}{
+		{
+			name: "redelivery queued",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					Redeliver(deliveryID).
+					Return(nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedResponse: `{"message":"Redelivery Queued","code":"REDELIVERY_QUEUED"}`,
+		},
+		{
+			name: "dead letter not found",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					Redeliver(deliveryID).
+					Return(&webhookServiceError{code: DELIVERY_NOT_EXISTS})
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedResponse: `{"message":"Dead Letter Not Found","code":"DEAD_LETTER_NOT_FOUND"}`,
+		},
+		{
+			name: "redelivery failed",
+			setupMock: func(service *MockWebhookService) {
+				service.
+					EXPECT().
+					Redeliver(deliveryID).
+					Return(&webhookServiceError{code: REDELIVER_FAILED})
+			},
+			expectedStatus:   http.StatusBadGateway,
+			expectedResponse: `{"message":"Redelivery Failed","code":"REDELIVERY_FAILED"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			w := httptest.NewRecorder()
+			ctrl := gomock.NewController(tu)
+			svc := NewMockWebhookService(ctrl)
+			tc.setupMock(svc)
+
+			controller := NewWebhookController(svc)
+			r := gin.Default()
+			r.GET("/api/v1/webhooks/:id", controller.GetSubscription)
+			r.POST("/api/v1/webhooks/dead-letters/:id/redeliver", controller.Redeliver)
+
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/webhooks/dead-letters/%s/redeliver", deliveryID), nil)
+			if err != nil {
+				t.Errorf("Error in request : %v", err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Result().StatusCode != tc.expectedStatus {
+				t.Errorf("Expecting statusCode %d , but returns %d", tc.expectedStatus, w.Result().StatusCode)
+			}
+
+			if r := w.Body.String(); r != tc.expectedResponse {
+				t.Errorf("Expecting body %s , but returns %s", tc.expectedResponse, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Longest backoff doubling, later attempts wait as much as this one
+const maxBackoffShift int = 16
+
+func (svc *webhookService) DeliverPending() (int, error) {
+	delivered := 0
+	for {
+		// Claimed deliveries are left to other instances for twice the timeout, in case this one dies meanwhile
+		delivery, err := svc.deliveries.ClaimDelivery(time.Now(), 2*svc.config.WebhookTimeout)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on ClaimDelivery : %v", err))
+			return delivered, &webhookServiceError{code: DELIVER_FAILED}
+		}
+		if delivery == nil {
+			return delivered, nil
+		}
+
+		subscription, err := svc.subscriptions.FindSubscriptionByID(delivery.SubscriptionID)
+		if err != nil {
+			fmt.Println(fmt.Errorf("Error on FindSubscriptionByID : %v", err))
+			return delivered, &webhookServiceError{code: DELIVER_FAILED}
+		}
+
+		// Subscriptions deleted are not delivered anymore
+		if subscription == nil {
+			svc.drop(*delivery)
+			continue
+		}
+
+		if err := svc.send(*subscription, *delivery); err != nil {
+			svc.fail(*delivery, err)
+			continue
+		}
+		svc.drop(*delivery)
+		delivered++
+	}
+}
+
+// Posts the payload to the subscription URL, any status other than 2xx is a failure
+func (svc *webhookService) send(subscription Subscription, delivery Delivery) error {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EVENT_HEADER, delivery.EventType)
+	req.Header.Set(DELIVERY_HEADER, delivery.ID)
+	req.Header.Set(TIMESTAMP_HEADER, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SIGNATURE_HEADER, Sign(subscription.Secret, timestamp, body))
+
+	resp, err := svc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Response status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (svc *webhookService) drop(delivery Delivery) {
+	if err := svc.deliveries.DeleteDelivery(delivery.ID); err != nil {
+		fmt.Println(fmt.Errorf("Error on DeleteDelivery : %v", err))
+	}
+}
+
+// Schedules the next attempt after WEBHOOK_BACKOFF doubled on every attempt, or keeps the
+// delivery as dead after WEBHOOK_MAX_ATTEMPTS
+func (svc *webhookService) fail(delivery Delivery, cause error) {
+	attempts := delivery.Attempts + 1
+
+	var nextAttemptAt *time.Time
+	if attempts < svc.config.WebhookMaxAttempts {
+		shift := attempts - 1
+		if shift > maxBackoffShift {
+			shift = maxBackoffShift
+		}
+		next := time.Now().Add(svc.config.WebhookBackoff * time.Duration(1<<shift))
+		nextAttemptAt = &next
+	} else {
+		fmt.Println(fmt.Errorf("Delivery %s failed %d attempts : %v", delivery.ID, attempts, cause))
+	}
+
+	if err := svc.deliveries.FailDelivery(delivery.ID, attempts, cause.Error(), nextAttemptAt); err != nil {
+		fmt.Println(fmt.Errorf("Error on FailDelivery : %v", err))
+	}
+}
+
+/*
+Attempts the deliveries due right away and then on every interval, until ctx is done.
+
+Every instance of the API runs a deliverer, deliveries are claimed one at a time
+so each attempt is made by a single instance.
+*/
+func RunDeliverer(ctx context.Context, service WebhookService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		service.DeliverPending()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./webhooks/repository.go
+
+// Package webhooks is a generated GoMock package.
+package webhooks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSubscriptionRepository is a mock of SubscriptionRepository interface.
+type MockSubscriptionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSubscriptionRepositoryMockRecorder
+}
+
+// MockSubscriptionRepositoryMockRecorder is the mock recorder for MockSubscriptionRepository.
+type MockSubscriptionRepositoryMockRecorder struct {
+	mock *MockSubscriptionRepository
+}
+
+// NewMockSubscriptionRepository creates a new mock instance.
+func NewMockSubscriptionRepository(ctrl *gomock.Controller) *MockSubscriptionRepository {
+	mock := &MockSubscriptionRepository{ctrl: ctrl}
+	mock.recorder = &MockSubscriptionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSubscriptionRepository) EXPECT() *MockSubscriptionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteSubscription mocks base method.
+func (m *MockSubscriptionRepository) DeleteSubscription(ID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscription", ID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscription indicates an expected call of DeleteSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) DeleteSubscription(ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).DeleteSubscription), ID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockSubscriptionRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockSubscriptionRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockSubscriptionRepository)(nil).EnsureIndexes))
+}
+
+// FindSubscriptionByID mocks base method.
+func (m *MockSubscriptionRepository) FindSubscriptionByID(ID string) (*Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindSubscriptionByID", ID)
+	ret0, _ := ret[0].(*Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindSubscriptionByID indicates an expected call of FindSubscriptionByID.
+func (mr *MockSubscriptionRepositoryMockRecorder) FindSubscriptionByID(ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSubscriptionByID", reflect.TypeOf((*MockSubscriptionRepository)(nil).FindSubscriptionByID), ID)
+}
+
+// InsertSubscription mocks base method.
+func (m *MockSubscriptionRepository) InsertSubscription(subscription Subscription) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertSubscription", subscription)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertSubscription indicates an expected call of InsertSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) InsertSubscription(subscription interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).InsertSubscription), subscription)
+}
+
+// ListSubscriptions mocks base method.
+func (m *MockSubscriptionRepository) ListSubscriptions() ([]Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptions")
+	ret0, _ := ret[0].([]Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubscriptions indicates an expected call of ListSubscriptions.
+func (mr *MockSubscriptionRepositoryMockRecorder) ListSubscriptions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptions", reflect.TypeOf((*MockSubscriptionRepository)(nil).ListSubscriptions))
+}
+
+// ListSubscriptionsFor mocks base method.
+func (m *MockSubscriptionRepository) ListSubscriptionsFor(eventType string) ([]Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubscriptionsFor", eventType)
+	ret0, _ := ret[0].([]Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubscriptionsFor indicates an expected call of ListSubscriptionsFor.
+func (mr *MockSubscriptionRepositoryMockRecorder) ListSubscriptionsFor(eventType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubscriptionsFor", reflect.TypeOf((*MockSubscriptionRepository)(nil).ListSubscriptionsFor), eventType)
+}
+
+// UpdateSubscription mocks base method.
+func (m *MockSubscriptionRepository) UpdateSubscription(ID string, subscription Subscription) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSubscription", ID, subscription)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSubscription indicates an expected call of UpdateSubscription.
+func (mr *MockSubscriptionRepositoryMockRecorder) UpdateSubscription(ID, subscription interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSubscription", reflect.TypeOf((*MockSubscriptionRepository)(nil).UpdateSubscription), ID, subscription)
+}
+
+// MockDeliveryRepository is a mock of DeliveryRepository interface.
+type MockDeliveryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeliveryRepositoryMockRecorder
+}
+
+// MockDeliveryRepositoryMockRecorder is the mock recorder for MockDeliveryRepository.
+type MockDeliveryRepositoryMockRecorder struct {
+	mock *MockDeliveryRepository
+}
+
+// NewMockDeliveryRepository creates a new mock instance.
+func NewMockDeliveryRepository(ctrl *gomock.Controller) *MockDeliveryRepository {
+	mock := &MockDeliveryRepository{ctrl: ctrl}
+	mock.recorder = &MockDeliveryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDeliveryRepository) EXPECT() *MockDeliveryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// ClaimDelivery mocks base method.
+func (m *MockDeliveryRepository) ClaimDelivery(now time.Time, lease time.Duration) (*Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDelivery", now, lease)
+	ret0, _ := ret[0].(*Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDelivery indicates an expected call of ClaimDelivery.
+func (mr *MockDeliveryRepositoryMockRecorder) ClaimDelivery(now, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDelivery", reflect.TypeOf((*MockDeliveryRepository)(nil).ClaimDelivery), now, lease)
+}
+
+// DeleteDelivery mocks base method.
+func (m *MockDeliveryRepository) DeleteDelivery(ID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDelivery", ID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDelivery indicates an expected call of DeleteDelivery.
+func (mr *MockDeliveryRepositoryMockRecorder) DeleteDelivery(ID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDelivery", reflect.TypeOf((*MockDeliveryRepository)(nil).DeleteDelivery), ID)
+}
+
+// DeleteSubscriptionDeliveries mocks base method.
+func (m *MockDeliveryRepository) DeleteSubscriptionDeliveries(subscriptionID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubscriptionDeliveries", subscriptionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubscriptionDeliveries indicates an expected call of DeleteSubscriptionDeliveries.
+func (mr *MockDeliveryRepositoryMockRecorder) DeleteSubscriptionDeliveries(subscriptionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscriptionDeliveries", reflect.TypeOf((*MockDeliveryRepository)(nil).DeleteSubscriptionDeliveries), subscriptionID)
+}
+
+// EnsureIndexes mocks base method.
+func (m *MockDeliveryRepository) EnsureIndexes() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureIndexes")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureIndexes indicates an expected call of EnsureIndexes.
+func (mr *MockDeliveryRepositoryMockRecorder) EnsureIndexes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureIndexes", reflect.TypeOf((*MockDeliveryRepository)(nil).EnsureIndexes))
+}
+
+// FailDelivery mocks base method.
+func (m *MockDeliveryRepository) FailDelivery(ID string, attempts int, lastError string, nextAttemptAt *time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailDelivery", ID, attempts, lastError, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailDelivery indicates an expected call of FailDelivery.
+func (mr *MockDeliveryRepositoryMockRecorder) FailDelivery(ID, attempts, lastError, nextAttemptAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailDelivery", reflect.TypeOf((*MockDeliveryRepository)(nil).FailDelivery), ID, attempts, lastError, nextAttemptAt)
+}
+
+// InsertDeliveries mocks base method.
+func (m *MockDeliveryRepository) InsertDeliveries(deliveries []Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDeliveries", deliveries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertDeliveries indicates an expected call of InsertDeliveries.
+func (mr *MockDeliveryRepositoryMockRecorder) InsertDeliveries(deliveries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDeliveries", reflect.TypeOf((*MockDeliveryRepository)(nil).InsertDeliveries), deliveries)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockDeliveryRepository) ListDeliveries(status string) ([]Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", status)
+	ret0, _ := ret[0].([]Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockDeliveryRepositoryMockRecorder) ListDeliveries(status interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockDeliveryRepository)(nil).ListDeliveries), status)
+}
+
+// RedeliverDelivery mocks base method.
+func (m *MockDeliveryRepository) RedeliverDelivery(ID string, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeliverDelivery", ID, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RedeliverDelivery indicates an expected call of RedeliverDelivery.
+func (mr *MockDeliveryRepositoryMockRecorder) RedeliverDelivery(ID, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeliverDelivery", reflect.TypeOf((*MockDeliveryRepository)(nil).RedeliverDelivery), ID, now)
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import "time"
+
+// Deliveries waiting for their next attempt
+const DELIVERY_PENDING string = "pending"
+
+// Deliveries that failed every attempt, kept until redelivered
+const DELIVERY_DEAD string = "dead"
+
+// URL subscribed to user events. The secret signs the payloads sent and is never returned.
+type Subscription struct {
+	ID        string     `json:"id" bson:"_id,omitempty"`
+	URL       string     `json:"url" bson:"url"`
+	Events    []string   `json:"events" bson:"events"`
+	Secret    string     `json:"-" bson:"secret"`
+	CreatedBy string     `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+type SubscriptionRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Required on creation, the current secret is kept when missing on update
+	Secret string `json:"secret,omitempty"`
+}
+
+type SubscriptionList struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Event queued for a subscription, removed once delivered
+type Delivery struct {
+	ID             string `json:"id" bson:"_id,omitempty"`
+	SubscriptionID string `json:"subscriptionId" bson:"subscriptionId"`
+	EventID        string `json:"eventId" bson:"eventId"`
+	EventType      string `json:"eventType" bson:"eventType"`
+	// Event as sent, the body signed
+	Payload       string    `json:"payload" bson:"payload"`
+	Status        string    `json:"status" bson:"status"`
+	Attempts      int       `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time `json:"nextAttemptAt" bson:"nextAttemptAt"`
+	LastError     string    `json:"lastError,omitempty" bson:"lastError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type DeliveryList struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
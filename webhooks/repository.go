@@ -0,0 +1,280 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const subscriptionCollection string = "webhooks"
+const deliveryCollection string = "webhook_deliveries"
+const INVALID_OBJECT_ID string = "INVALID_OBJECT_ID"
+const DOCUMENT_NOT_FOUND string = "DOCUMENT_NOT_FOUND"
+
+type SubscriptionRepository interface {
+	InsertSubscription(subscription Subscription) (string, error)
+	FindSubscriptionByID(ID string) (*Subscription, error)
+	ListSubscriptions() ([]Subscription, error)
+	// Lists the subscriptions to the event type
+	ListSubscriptionsFor(eventType string) ([]Subscription, error)
+	// Replaces URL and events, and the secret when set
+	UpdateSubscription(ID string, subscription Subscription) error
+	DeleteSubscription(ID string) error
+	EnsureIndexes() error
+}
+
+type subscriptionRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewSubscriptionRepository(client *mongo.Client, database string) SubscriptionRepository {
+	return &subscriptionRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *subscriptionRepository) InsertSubscription(subscription Subscription) (string, error) {
+	subscription.ID = ""
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	result, err := coll.InsertOne(context.Background(), subscription)
+	if err != nil {
+		return "", err
+	}
+	var objID primitive.ObjectID = result.InsertedID.(primitive.ObjectID)
+
+	return objID.Hex(), nil
+}
+
+func (repo *subscriptionRepository) FindSubscriptionByID(ID string) (*Subscription, error) {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return nil, fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	var subscription Subscription
+	err = coll.FindOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (repo *subscriptionRepository) ListSubscriptions() ([]Subscription, error) {
+	return repo.find(bson.M{})
+}
+
+func (repo *subscriptionRepository) ListSubscriptionsFor(eventType string) ([]Subscription, error) {
+	return repo.find(bson.M{"events": bson.M{"$eq": eventType}})
+}
+
+func (repo *subscriptionRepository) find(filter bson.M) ([]Subscription, error) {
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := coll.Find(context.Background(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []Subscription{}
+	if err := cursor.All(context.Background(), &subscriptions); err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (repo *subscriptionRepository) UpdateSubscription(ID string, subscription Subscription) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	fields := bson.M{"url": subscription.URL, "events": subscription.Events, "updatedAt": time.Now().UTC()}
+	if subscription.Secret != "" {
+		fields["secret"] = subscription.Secret
+	}
+
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	result, err := coll.UpdateOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
+
+func (repo *subscriptionRepository) DeleteSubscription(ID string) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	result, err := coll.DeleteOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
+
+// Creates the index to find the subscriptions to an event
+func (repo *subscriptionRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(subscriptionCollection)
+	_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "events", Value: 1}},
+	})
+	return err
+}
+
+type DeliveryRepository interface {
+	InsertDeliveries(deliveries []Delivery) error
+	// Takes the pending delivery due the longest, postponing it by lease so other instances do not take it meanwhile
+	ClaimDelivery(now time.Time, lease time.Duration) (*Delivery, error)
+	// Records a failed attempt, the delivery is attempted again at nextAttemptAt or kept as dead when nil
+	FailDelivery(ID string, attempts int, lastError string, nextAttemptAt *time.Time) error
+	DeleteDelivery(ID string) error
+	DeleteSubscriptionDeliveries(subscriptionID string) error
+	ListDeliveries(status string) ([]Delivery, error)
+	// Puts a dead delivery back as pending, due at now
+	RedeliverDelivery(ID string, now time.Time) error
+	EnsureIndexes() error
+}
+
+type deliveryRepository struct {
+	client   *mongo.Client
+	database string
+}
+
+func NewDeliveryRepository(client *mongo.Client, database string) DeliveryRepository {
+	return &deliveryRepository{
+		client:   client,
+		database: database,
+	}
+}
+
+func (repo *deliveryRepository) InsertDeliveries(deliveries []Delivery) error {
+	documents := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		delivery.ID = ""
+		documents = append(documents, delivery)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	_, err := coll.InsertMany(context.Background(), documents)
+	return err
+}
+
+func (repo *deliveryRepository) ClaimDelivery(now time.Time, lease time.Duration) (*Delivery, error) {
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	filter := bson.M{"status": DELIVERY_PENDING, "nextAttemptAt": bson.M{"$lte": now.UTC()}}
+	update := bson.M{"$set": bson.M{"nextAttemptAt": now.Add(lease).UTC()}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}})
+
+	var delivery Delivery
+	err := coll.FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (repo *deliveryRepository) FailDelivery(ID string, attempts int, lastError string, nextAttemptAt *time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	fields := bson.M{"attempts": attempts, "lastError": lastError, "status": DELIVERY_DEAD}
+	if nextAttemptAt != nil {
+		fields["status"] = DELIVERY_PENDING
+		fields["nextAttemptAt"] = nextAttemptAt.UTC()
+	}
+
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	_, err = coll.UpdateOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}}, bson.M{"$set": fields})
+	return err
+}
+
+func (repo *deliveryRepository) DeleteDelivery(ID string) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	_, err = coll.DeleteOne(context.Background(), bson.M{"_id": bson.M{"$eq": objID}})
+	return err
+}
+
+func (repo *deliveryRepository) DeleteSubscriptionDeliveries(subscriptionID string) error {
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	_, err := coll.DeleteMany(context.Background(), bson.M{"subscriptionId": bson.M{"$eq": subscriptionID}})
+	return err
+}
+
+func (repo *deliveryRepository) ListDeliveries(status string) ([]Delivery, error) {
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+
+	cursor, err := coll.Find(context.Background(), bson.M{"status": bson.M{"$eq": status}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []Delivery{}
+	if err := cursor.All(context.Background(), &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (repo *deliveryRepository) RedeliverDelivery(ID string, now time.Time) error {
+	objID, err := primitive.ObjectIDFromHex(ID)
+	if err != nil {
+		return fmt.Errorf(INVALID_OBJECT_ID)
+	}
+
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	filter := bson.M{"_id": bson.M{"$eq": objID}, "status": DELIVERY_DEAD}
+	update := bson.M{
+		"$set":   bson.M{"status": DELIVERY_PENDING, "attempts": 0, "nextAttemptAt": now.UTC()},
+		"$unset": bson.M{"lastError": ""},
+	}
+
+	result, err := coll.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf(DOCUMENT_NOT_FOUND)
+	}
+	return nil
+}
+
+// Creates the index to claim due deliveries
+func (repo *deliveryRepository) EnsureIndexes() error {
+	coll := repo.client.Database(repo.database).Collection(deliveryCollection)
+	_, err := coll.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}},
+	})
+	return err
+}
@@ -0,0 +1,81 @@
+package webhooks
+
+type WebhookResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+var INVALID_SUBSCRIPTION_DATA WebhookResponse = WebhookResponse{
+	Message: "Invalid Subscription Data",
+	Code:    "INVALID_SUBSCRIPTION_DATA",
+}
+
+var INVALID_SUBSCRIPTION_ID WebhookResponse = WebhookResponse{
+	Message: "Invalid Subscription ID",
+	Code:    "INVALID_SUBSCRIPTION_ID",
+}
+
+var SUBSCRIPTION_NOT_FOUND WebhookResponse = WebhookResponse{
+	Message: "Subscription Not Found",
+	Code:    "SUBSCRIPTION_NOT_FOUND",
+}
+
+var SUBSCRIPTION_CREATE_FAILED WebhookResponse = WebhookResponse{
+	Message: "Subscription Create Failed",
+	Code:    "SUBSCRIPTION_CREATE_FAILED",
+}
+
+var SUBSCRIPTION_LIST_FAILED WebhookResponse = WebhookResponse{
+	Message: "Subscription List Failed",
+	Code:    "SUBSCRIPTION_LIST_FAILED",
+}
+
+var SUBSCRIPTION_GET_FAILED WebhookResponse = WebhookResponse{
+	Message: "Subscription Get Failed",
+	Code:    "SUBSCRIPTION_GET_FAILED",
+}
+
+var SUBSCRIPTION_UPDATED WebhookResponse = WebhookResponse{
+	Message: "Subscription Updated",
+	Code:    "SUBSCRIPTION_UPDATED",
+}
+
+var SUBSCRIPTION_UPDATE_FAILED WebhookResponse = WebhookResponse{
+	Message: "Subscription Update Failed",
+	Code:    "SUBSCRIPTION_UPDATE_FAILED",
+}
+
+var SUBSCRIPTION_DELETED WebhookResponse = WebhookResponse{
+	Message: "Subscription Deleted",
+	Code:    "SUBSCRIPTION_DELETED",
+}
+
+var SUBSCRIPTION_DELETE_FAILED WebhookResponse = WebhookResponse{
+	Message: "Subscription Delete Failed",
+	Code:    "SUBSCRIPTION_DELETE_FAILED",
+}
+
+var DEAD_LETTER_LIST_FAILED WebhookResponse = WebhookResponse{
+	Message: "Dead Letter List Failed",
+	Code:    "DEAD_LETTER_LIST_FAILED",
+}
+
+var INVALID_DELIVERY_ID WebhookResponse = WebhookResponse{
+	Message: "Invalid Delivery ID",
+	Code:    "INVALID_DELIVERY_ID",
+}
+
+var DEAD_LETTER_NOT_FOUND WebhookResponse = WebhookResponse{
+	Message: "Dead Letter Not Found",
+	Code:    "DEAD_LETTER_NOT_FOUND",
+}
+
+var REDELIVERY_QUEUED WebhookResponse = WebhookResponse{
+	Message: "Redelivery Queued",
+	Code:    "REDELIVERY_QUEUED",
+}
+
+var REDELIVERY_FAILED WebhookResponse = WebhookResponse{
+	Message: "Redelivery Failed",
+	Code:    "REDELIVERY_FAILED",
+}
@@ -0,0 +1,62 @@
+// Webhooks module containing Controllers, Services e Repositories.
+// Module responsable for telling user changes to subscribed URLs
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Method to add routes in api (gin.RouterGroup), using config (config.Config)
+// and client (mongo.Client)
+func AddRoutes(api *gin.RouterGroup, config config.Config, client *mongo.Client) {
+	var webhookController WebhookController = NewWebhookController(newWebhookService(config, client))
+
+	manage := users.Authorize(users.PERMISSION_WEBHOOKS, false)
+	api.POST("/webhooks", manage, webhookController.CreateSubscription)
+	api.GET("/webhooks", manage, webhookController.ListSubscriptions)
+	api.GET("/webhooks/dead-letters", manage, webhookController.ListDeadLetters)
+	api.POST("/webhooks/dead-letters/:id/redeliver", manage, webhookController.Redeliver)
+	api.GET("/webhooks/:id", manage, webhookController.GetSubscription)
+	api.PUT("/webhooks/:id", manage, webhookController.UpdateSubscription)
+	api.DELETE("/webhooks/:id", manage, webhookController.DeleteSubscription)
+}
+
+// Method returning the publisher queuing user events for subscribed URLs, using
+// config (config.Config) and client (mongo.Client)
+func NewPublisher(config config.Config, client *mongo.Client) users.Publisher {
+	return newWebhookService(config, client)
+}
+
+// Method to start delivering queued events in background, using config (config.Config)
+// and client (mongo.Client), until ctx is done
+func StartDeliverer(ctx context.Context, config config.Config, client *mongo.Client) {
+	go RunDeliverer(ctx, newWebhookService(config, client), config.WebhookPollInterval)
+}
+
+func newWebhookService(config config.Config, client *mongo.Client) WebhookService {
+	var subscriptionRepository SubscriptionRepository = NewSubscriptionRepository(client, config.Database)
+	var deliveryRepository DeliveryRepository = NewDeliveryRepository(client, config.Database)
+
+	// Redirects are not followed, a subscription must point to its final URL
+	httpClient := &http.Client{
+		Timeout: config.WebhookTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	if err := subscriptionRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	if err := deliveryRepository.EnsureIndexes(); err != nil {
+		fmt.Println(fmt.Errorf("Error on EnsureIndexes : %v", err))
+	}
+	return NewWebhookService(subscriptionRepository, deliveryRepository, httpClient, config)
+}
@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"userapi/config"
+	"userapi/users"
+)
+
+type WebhookService interface {
+	/*
+		Method to subscribe an URL to user events
+
+		Parameters
+
+		request: URL, event types and the secret signing payloads.
+
+		createdBy: ID of the principal subscribing.
+	*/
+	CreateSubscription(request SubscriptionRequest, createdBy string) (*Subscription, error)
+	/*
+		Method to list all subscriptions
+	*/
+	ListSubscriptions() (*SubscriptionList, error)
+	/*
+		Method to get subscription by ID
+
+		Parameters
+
+		ID: Subscription ID.
+	*/
+	GetSubscription(ID string) (*Subscription, error)
+	/*
+		Method to replace subscription URL and events, and the secret when set
+
+		Parameters
+
+		ID: Subscription ID.
+
+		request: URL, event types and optional new secret.
+	*/
+	UpdateSubscription(ID string, request SubscriptionRequest) error
+	/*
+		Method to delete subscription along with its deliveries not made
+
+		Parameters
+
+		ID: Subscription ID.
+	*/
+	DeleteSubscription(ID string) error
+	/*
+		Method to list deliveries that failed every attempt, newest first
+	*/
+	ListDeadLetters() (*DeliveryList, error)
+	/*
+		Method to queue a dead delivery again, attempted as a new delivery
+
+		Parameters
+
+		ID: Delivery ID.
+	*/
+	Redeliver(ID string) error
+	/*
+		Method to queue a user event for every subscription to its type
+
+		Parameters
+
+		event: User event published by the outbox relay of the users service.
+	*/
+	Publish(event users.UserEvent) error
+	/*
+		Method to attempt the deliveries due, retrying failed ones with exponential backoff
+		until WEBHOOK_MAX_ATTEMPTS
+
+		Returns the number of deliveries made.
+	*/
+	DeliverPending() (int, error)
+}
+
+type webhookServiceError struct {
+	code string
+}
+
+func (e *webhookServiceError) Error() string {
+	return e.code
+}
+
+const CREATE_SUBSCRIPTION_FAILED string = "CREATE_SUBSCRIPTION_FAILED"
+const LIST_SUBSCRIPTIONS_FAILED string = "LIST_SUBSCRIPTIONS_FAILED"
+const GET_SUBSCRIPTION_FAILED string = "GET_SUBSCRIPTION_FAILED"
+const UPDATE_SUBSCRIPTION_FAILED string = "UPDATE_SUBSCRIPTION_FAILED"
+const DELETE_SUBSCRIPTION_FAILED string = "DELETE_SUBSCRIPTION_FAILED"
+const SUBSCRIPTION_DATA_INVALID string = "SUBSCRIPTION_DATA_INVALID"
+const SUBSCRIPTION_ID_INVALID string = "SUBSCRIPTION_ID_INVALID"
+const SUBSCRIPTION_NOT_EXISTS string = "SUBSCRIPTION_NOT_EXISTS"
+const LIST_DELIVERIES_FAILED string = "LIST_DELIVERIES_FAILED"
+const REDELIVER_FAILED string = "REDELIVER_FAILED"
+const DELIVERY_ID_INVALID string = "DELIVERY_ID_INVALID"
+const DELIVERY_NOT_EXISTS string = "DELIVERY_NOT_EXISTS"
+const PUBLISH_FAILED string = "PUBLISH_FAILED"
+const DELIVER_FAILED string = "DELIVER_FAILED"
+
+type webhookService struct {
+	subscriptions SubscriptionRepository
+	deliveries    DeliveryRepository
+	client        *http.Client
+	config        config.Config
+}
+
+// Returns a WebhookService sending deliveries with client
+func NewWebhookService(subscriptions SubscriptionRepository, deliveries DeliveryRepository, client *http.Client, config config.Config) WebhookService {
+	return &webhookService{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		client:        client,
+		config:        config,
+	}
+}
+
+func (svc *webhookService) CreateSubscription(request SubscriptionRequest, createdBy string) (*Subscription, error) {
+	if !validateRequest(request, true) {
+		fmt.Println(fmt.Errorf("Invalid subscription data"))
+		return nil, &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID}
+	}
+
+	subscription := Subscription{
+		URL:       request.URL,
+		Events:    request.Events,
+		Secret:    request.Secret,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	ID, err := svc.subscriptions.InsertSubscription(subscription)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on InsertSubscription : %v", err))
+		return nil, &webhookServiceError{code: CREATE_SUBSCRIPTION_FAILED}
+	}
+	subscription.ID = ID
+
+	return &subscription, nil
+}
+
+func (svc *webhookService) ListSubscriptions() (*SubscriptionList, error) {
+	subscriptions, err := svc.subscriptions.ListSubscriptions()
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListSubscriptions : %v", err))
+		return nil, &webhookServiceError{code: LIST_SUBSCRIPTIONS_FAILED}
+	}
+	return &SubscriptionList{Subscriptions: subscriptions}, nil
+}
+
+func (svc *webhookService) GetSubscription(ID string) (*Subscription, error) {
+	subscription, err := svc.subscriptions.FindSubscriptionByID(ID)
+	if err != nil {
+		return nil, svc.writeError("FindSubscriptionByID", err, GET_SUBSCRIPTION_FAILED)
+	}
+	if subscription == nil {
+		fmt.Println(fmt.Errorf("Subscription not exists"))
+		return nil, &webhookServiceError{code: SUBSCRIPTION_NOT_EXISTS}
+	}
+	return subscription, nil
+}
+
+func (svc *webhookService) UpdateSubscription(ID string, request SubscriptionRequest) error {
+	if !validateRequest(request, false) {
+		fmt.Println(fmt.Errorf("Invalid subscription data"))
+		return &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID}
+	}
+
+	subscription := Subscription{URL: request.URL, Events: request.Events, Secret: request.Secret}
+	if err := svc.subscriptions.UpdateSubscription(ID, subscription); err != nil {
+		return svc.writeError("UpdateSubscription", err, UPDATE_SUBSCRIPTION_FAILED)
+	}
+	return nil
+}
+
+func (svc *webhookService) DeleteSubscription(ID string) error {
+	if err := svc.subscriptions.DeleteSubscription(ID); err != nil {
+		return svc.writeError("DeleteSubscription", err, DELETE_SUBSCRIPTION_FAILED)
+	}
+
+	// Deliveries left behind are dropped when claimed, so a failure here is only logged
+	if err := svc.deliveries.DeleteSubscriptionDeliveries(ID); err != nil {
+		fmt.Println(fmt.Errorf("Error on DeleteSubscriptionDeliveries : %v", err))
+	}
+	return nil
+}
+
+func (svc *webhookService) ListDeadLetters() (*DeliveryList, error) {
+	deliveries, err := svc.deliveries.ListDeliveries(DELIVERY_DEAD)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListDeliveries : %v", err))
+		return nil, &webhookServiceError{code: LIST_DELIVERIES_FAILED}
+	}
+	return &DeliveryList{Deliveries: deliveries}, nil
+}
+
+func (svc *webhookService) Redeliver(ID string) error {
+	if err := svc.deliveries.RedeliverDelivery(ID, time.Now()); err != nil {
+		switch err.Error() {
+		case INVALID_OBJECT_ID:
+			fmt.Println(fmt.Errorf("Invalid delivery id : %v", err))
+			return &webhookServiceError{code: DELIVERY_ID_INVALID}
+		case DOCUMENT_NOT_FOUND:
+			fmt.Println(fmt.Errorf("Dead delivery not exists"))
+			return &webhookServiceError{code: DELIVERY_NOT_EXISTS}
+		}
+		fmt.Println(fmt.Errorf("Error on RedeliverDelivery : %v", err))
+		return &webhookServiceError{code: REDELIVER_FAILED}
+	}
+	return nil
+}
+
+func (svc *webhookService) Publish(event users.UserEvent) error {
+	subscriptions, err := svc.subscriptions.ListSubscriptionsFor(event.Type)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on ListSubscriptionsFor : %v", err))
+		return &webhookServiceError{code: PUBLISH_FAILED}
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	// Every subscription is sent the same payload, so receivers can tell duplicates by the event id
+	payload, err := json.Marshal(event)
+	if err != nil {
+		fmt.Println(fmt.Errorf("Error on json.Marshal : %v", err))
+		return &webhookServiceError{code: PUBLISH_FAILED}
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]Delivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         DELIVERY_PENDING,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+
+	if err := svc.deliveries.InsertDeliveries(deliveries); err != nil {
+		fmt.Println(fmt.Errorf("Error on InsertDeliveries : %v", err))
+		return &webhookServiceError{code: PUBLISH_FAILED}
+	}
+	return nil
+}
+
+func (svc *webhookService) writeError(method string, err error, code string) error {
+	switch err.Error() {
+	case INVALID_OBJECT_ID:
+		fmt.Println(fmt.Errorf("Invalid subscription id : %v", err))
+		return &webhookServiceError{code: SUBSCRIPTION_ID_INVALID}
+	case DOCUMENT_NOT_FOUND:
+		fmt.Println(fmt.Errorf("Subscription not exists"))
+		return &webhookServiceError{code: SUBSCRIPTION_NOT_EXISTS}
+	}
+	fmt.Println(fmt.Errorf("Error on %s : %v", method, err))
+	return &webhookServiceError{code: code}
+}
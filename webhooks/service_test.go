@@ -0,0 +1,289 @@
+package webhooks
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"userapi/config"
+	"userapi/users"
+
+	"github.com/golang/mock/gomock"
+)
+
+const subscriptionID string = "64260e1da4c0c814bda5734a"
+const deliveryID string = "64260e1da4c0c814bda5734b"
+const secret string = "a secret of 16 characters or more"
+
+var webhookConfig config.Config = config.Config{WebhookTimeout: time.Second, WebhookMaxAttempts: 3, WebhookBackoff: time.Minute}
+
+func TestServiceCreateSubscription(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		setupMock     func(subscriptions *MockSubscriptionRepository)
+		inputParam    SubscriptionRequest
+		expectedError error
+	}{
+		{
+			name: "create subscription success",
+			setupMock: func(subscriptions *MockSubscriptionRepository) {
+				subscriptions.
+					EXPECT().
+					InsertSubscription(gomock.Any()).
+					DoAndReturn(func(subscription Subscription) (string, error) {
+						if subscription.Secret != secret || subscription.CreatedBy != "admin" {
+							t.Errorf("Expecting subscription with secret created by admin , but returns %v", subscription)
+						}
+						return subscriptionID, nil
+					})
+			},
+			inputParam:    SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: secret},
+			expectedError: nil,
+		},
+		{
+			name:          "url not http",
+			setupMock:     func(subscriptions *MockSubscriptionRepository) {},
+			inputParam:    SubscriptionRequest{URL: "ftp://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: secret},
+			expectedError: &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID},
+		},
+		{
+			name:          "unknown event",
+			setupMock:     func(subscriptions *MockSubscriptionRepository) {},
+			inputParam:    SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{"user.purged"}, Secret: secret},
+			expectedError: &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID},
+		},
+		{
+			name:          "short secret",
+			setupMock:     func(subscriptions *MockSubscriptionRepository) {},
+			inputParam:    SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: "short"},
+			expectedError: &webhookServiceError{code: SUBSCRIPTION_DATA_INVALID},
+		},
+		{
+			name: "insert failed",
+			setupMock: func(subscriptions *MockSubscriptionRepository) {
+				subscriptions.
+					EXPECT().
+					InsertSubscription(gomock.Any()).
+					Return("", fmt.Errorf("Any Error"))
+			},
+			inputParam:    SubscriptionRequest{URL: "https://crm.test/hooks", Events: []string{users.EVENT_USER_CREATED}, Secret: secret},
+			expectedError: &webhookServiceError{code: CREATE_SUBSCRIPTION_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			subscriptions := NewMockSubscriptionRepository(ctrl)
+			tc.setupMock(subscriptions)
+
+			service := NewWebhookService(subscriptions, NewMockDeliveryRepository(ctrl), http.DefaultClient, webhookConfig)
+
+			subscription, err := service.CreateSubscription(tc.inputParam, "admin")
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+
+			if err == nil && subscription.ID != subscriptionID {
+				t.Errorf("Expecting subscription id %s , but returns %v", subscriptionID, subscription)
+			}
+		})
+	}
+}
+
+func TestServicePublish(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	subscriptions := NewMockSubscriptionRepository(ctrl)
+	deliveries := NewMockDeliveryRepository(ctrl)
+
+	event := users.UserEvent{ID: "event-1", Type: users.EVENT_USER_DELETED, UserID: "64260e1da4c0c814bda57351", Version: 2}
+
+	subscriptions.
+		EXPECT().
+		ListSubscriptionsFor(users.EVENT_USER_DELETED).
+		Return([]Subscription{{ID: subscriptionID}, {ID: "64260e1da4c0c814bda5734c"}}, nil)
+	deliveries.
+		EXPECT().
+		InsertDeliveries(gomock.Any()).
+		DoAndReturn(func(queued []Delivery) error {
+			if len(queued) != 2 || queued[0].SubscriptionID != subscriptionID || queued[1].SubscriptionID != "64260e1da4c0c814bda5734c" {
+				t.Errorf("Expecting a delivery for each subscription , but returns %v", queued)
+			}
+			for _, delivery := range queued {
+				if delivery.Status != DELIVERY_PENDING || delivery.EventID != event.ID || delivery.Payload != queued[0].Payload {
+					t.Errorf("Expecting pending delivery of event %s , but returns %v", event.ID, delivery)
+				}
+			}
+			return nil
+		})
+
+	service := NewWebhookService(subscriptions, deliveries, http.DefaultClient, webhookConfig)
+
+	if err := service.Publish(event); err != nil {
+		t.Errorf("Expecting error %v , but returns %v", nil, err)
+	}
+}
+
+func TestServiceDeliverPending(t *testing.T) {
+
+	payload := `{"id":"event-1","type":"user.created"}`
+
+	tests := []struct {
+		name              string
+		receiverStatus    int
+		inputAttempts     int
+		setupMock         func(deliveries *MockDeliveryRepository)
+		expectedDelivered int
+	}{
+		{
+			name:           "delivered and removed",
+			receiverStatus: http.StatusNoContent,
+			inputAttempts:  0,
+			setupMock: func(deliveries *MockDeliveryRepository) {
+				deliveries.EXPECT().DeleteDelivery(deliveryID).Return(nil)
+			},
+			expectedDelivered: 1,
+		},
+		{
+			name:           "failure retried with backoff",
+			receiverStatus: http.StatusInternalServerError,
+			inputAttempts:  1,
+			setupMock: func(deliveries *MockDeliveryRepository) {
+				deliveries.
+					EXPECT().
+					FailDelivery(deliveryID, 2, "Response status 500", gomock.Not(gomock.Nil())).
+					DoAndReturn(func(ID string, attempts int, lastError string, nextAttemptAt *time.Time) error {
+						// Second attempt failed, the third waits twice WEBHOOK_BACKOFF
+						if wait := time.Until(*nextAttemptAt); wait < time.Minute+50*time.Second || wait > 2*time.Minute {
+							t.Errorf("Expecting next attempt in %v , but returns %v", 2*time.Minute, wait)
+						}
+						return nil
+					})
+			},
+			expectedDelivered: 0,
+		},
+		{
+			name:           "last attempt failed kept as dead",
+			receiverStatus: http.StatusBadRequest,
+			inputAttempts:  2,
+			setupMock: func(deliveries *MockDeliveryRepository) {
+				deliveries.EXPECT().FailDelivery(deliveryID, 3, "Response status 400", nil).Return(nil)
+			},
+			expectedDelivered: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(TIMESTAMP_HEADER), 10, 64)
+				if !Verify(secret, timestamp, body, r.Header.Get(SIGNATURE_HEADER)) {
+					t.Errorf("Expecting payload signed , but returns signature %s", r.Header.Get(SIGNATURE_HEADER))
+				}
+				if string(body) != payload || r.Header.Get(EVENT_HEADER) != users.EVENT_USER_CREATED || r.Header.Get(DELIVERY_HEADER) != deliveryID {
+					t.Errorf("Expecting delivery %s of %s , but returns %s", deliveryID, payload, body)
+				}
+				w.WriteHeader(tc.receiverStatus)
+			}))
+			defer receiver.Close()
+
+			ctrl := gomock.NewController(tu)
+			subscriptions := NewMockSubscriptionRepository(ctrl)
+			deliveries := NewMockDeliveryRepository(ctrl)
+
+			delivery := Delivery{ID: deliveryID, SubscriptionID: subscriptionID, EventType: users.EVENT_USER_CREATED, Payload: payload, Status: DELIVERY_PENDING, Attempts: tc.inputAttempts}
+			gomock.InOrder(
+				deliveries.EXPECT().ClaimDelivery(gomock.Any(), 2*time.Second).Return(&delivery, nil),
+				deliveries.EXPECT().ClaimDelivery(gomock.Any(), 2*time.Second).Return(nil, nil),
+			)
+			subscriptions.
+				EXPECT().
+				FindSubscriptionByID(subscriptionID).
+				Return(&Subscription{ID: subscriptionID, URL: receiver.URL, Secret: secret}, nil)
+			tc.setupMock(deliveries)
+
+			service := NewWebhookService(subscriptions, deliveries, receiver.Client(), webhookConfig)
+
+			delivered, err := service.DeliverPending()
+
+			if err != nil {
+				t.Errorf("Expecting error %v , but returns %v", nil, err)
+			}
+
+			if delivered != tc.expectedDelivered {
+				t.Errorf("Expecting delivered %d , but returns %d", tc.expectedDelivered, delivered)
+			}
+		})
+	}
+}
+
+func TestServiceDeliverPendingSubscriptionDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	subscriptions := NewMockSubscriptionRepository(ctrl)
+	deliveries := NewMockDeliveryRepository(ctrl)
+
+	gomock.InOrder(
+		deliveries.EXPECT().ClaimDelivery(gomock.Any(), gomock.Any()).Return(&Delivery{ID: deliveryID, SubscriptionID: subscriptionID}, nil),
+		deliveries.EXPECT().ClaimDelivery(gomock.Any(), gomock.Any()).Return(nil, nil),
+	)
+	subscriptions.EXPECT().FindSubscriptionByID(subscriptionID).Return(nil, nil)
+	deliveries.EXPECT().DeleteDelivery(deliveryID).Return(nil)
+
+	service := NewWebhookService(subscriptions, deliveries, http.DefaultClient, webhookConfig)
+
+	if delivered, err := service.DeliverPending(); delivered != 0 || err != nil {
+		t.Errorf("Expecting delivery dropped , but returns %d %v", delivered, err)
+	}
+}
+
+func TestServiceRedeliver(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		repoError     error
+		expectedError error
+	}{
+		{
+			name:          "redeliver success",
+			repoError:     nil,
+			expectedError: nil,
+		},
+		{
+			name:          "delivery not dead",
+			repoError:     fmt.Errorf(DOCUMENT_NOT_FOUND),
+			expectedError: &webhookServiceError{code: DELIVERY_NOT_EXISTS},
+		},
+		{
+			name:          "invalid delivery id",
+			repoError:     fmt.Errorf(INVALID_OBJECT_ID),
+			expectedError: &webhookServiceError{code: DELIVERY_ID_INVALID},
+		},
+		{
+			name:          "redeliver failed",
+			repoError:     fmt.Errorf("Any Error"),
+			expectedError: &webhookServiceError{code: REDELIVER_FAILED},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(tu *testing.T) {
+			ctrl := gomock.NewController(tu)
+			deliveries := NewMockDeliveryRepository(ctrl)
+			deliveries.EXPECT().RedeliverDelivery(deliveryID, gomock.Any()).Return(tc.repoError)
+
+			service := NewWebhookService(NewMockSubscriptionRepository(ctrl), deliveries, http.DefaultClient, webhookConfig)
+
+			err := service.Redeliver(deliveryID)
+
+			if (err == nil) != (tc.expectedError == nil) || (err != nil && err.Error() != tc.expectedError.Error()) {
+				t.Errorf("Expecting error %v , but returns %v", tc.expectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Headers sent along every delivery
+const EVENT_HEADER string = "X-Webhook-Event"
+const DELIVERY_HEADER string = "X-Webhook-Delivery"
+const TIMESTAMP_HEADER string = "X-Webhook-Timestamp"
+const SIGNATURE_HEADER string = "X-Webhook-Signature"
+
+const signaturePrefix string = "sha256="
+
+/*
+Returns the signature sent on SIGNATURE_HEADER, the HMAC-SHA256 keyed by the
+subscription secret of the timestamp sent on TIMESTAMP_HEADER, a dot and the body.
+
+Signing the timestamp lets receivers reject old payloads sent again.
+*/
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Tells if the signature was made by Sign with the secret, comparing in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"net/url"
+	"userapi/users"
+)
+
+// Shortest secret accepted to sign payloads
+const minSecretLength int = 16
+
+// Tells if the request has an absolute http(s) URL, at least one known event and, when
+// required or set, a secret long enough
+func validateRequest(request SubscriptionRequest, secretRequired bool) bool {
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return false
+	}
+	if len(request.Events) == 0 {
+		return false
+	}
+	for _, event := range request.Events {
+		if !validEvent(event) {
+			return false
+		}
+	}
+	if request.Secret != "" || secretRequired {
+		return len(request.Secret) >= minSecretLength
+	}
+	return true
+}
+
+func validEvent(event string) bool {
+	for _, e := range users.EventTypes {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}